@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/analyze"
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+	"github.com/spf13/cobra"
+)
+
+var (
+	analyzeSource string
+	analyzeFormat string
+)
+
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <image>",
+	Short: "Infer crop, scale and orientation of a processed image",
+	Long: `Locate the color-coded fiducial markers of a generated image in a processed
+output and report how the pipeline transformed it: the crop rectangle in
+source coordinates, the scale factor, rotation or flip, and letterboxing.
+
+The source dimensions are read from the image's QR code, or given with
+--source when the code did not survive processing.
+
+Examples:
+  futuage-test-image-gen analyze ./processed/IG_FEED_4_5/photo.jpg
+  futuage-test-image-gen analyze photo.jpg --source 1200x628 --format json`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          runAnalyze,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	analyzeCmd.Flags().StringVar(&analyzeSource, "source", "", "Source dimensions as WIDTHxHEIGHT (default: read from the QR code)")
+	analyzeCmd.Flags().StringVar(&analyzeFormat, "format", "text", "Output format (text, json)")
+}
+
+func runAnalyze(cmd *cobra.Command, args []string) error {
+	if analyzeFormat != "text" && analyzeFormat != "json" {
+		return fmt.Errorf("invalid output format: %s (supported: text, json)", analyzeFormat)
+	}
+
+	// 1. Decode the processed image
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	img, _, err := fingerprint.Decode(data)
+	if err != nil {
+		return err
+	}
+
+	// 2. Determine the source dimensions
+	var width, height int
+	if analyzeSource != "" {
+		if _, err := fmt.Sscanf(analyzeSource, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+			return fmt.Errorf("invalid source dimensions: %s (expected WIDTHxHEIGHT)", analyzeSource)
+		}
+	} else {
+		payload, err := generator.DecodeQR(img)
+		if err != nil {
+			return fmt.Errorf("%s: %w (use --source)", args[0], err)
+		}
+		label, err := generator.ParseSpecPayload(payload)
+		if err != nil {
+			return err
+		}
+		width, height = label.Width, label.Height
+	}
+
+	// 3. Locate the fiducials and fit the transformation
+	result, err := analyze.Analyze(img, width, height)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	// 4. Print the result
+	if analyzeFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to write result: %w", err)
+		}
+		return nil
+	}
+
+	fmt.Printf("Source:      %dx%d\n", result.SourceWidth, result.SourceHeight)
+	fmt.Printf("Output:      %dx%d\n", result.OutputWidth, result.OutputHeight)
+	fmt.Printf("Orientation: %s\n", result.Orientation)
+	fmt.Printf("Scale:       %.3f x %.3f\n", result.ScaleX, result.ScaleY)
+	crop := result.Crop
+	fmt.Printf("Crop:        x=%d y=%d %dx%d", crop.X, crop.Y, crop.Width, crop.Height)
+	if !result.Cropped {
+		fmt.Print(" (full source)")
+	}
+	fmt.Println()
+	if result.Letterboxed {
+		l := result.Letterbox
+		fmt.Printf("Letterbox:   left=%d top=%d right=%d bottom=%d\n", l.Left, l.Top, l.Right, l.Bottom)
+	} else {
+		fmt.Println("Letterbox:   none")
+	}
+	fmt.Printf("Markers:     %d found, residual %.2fpx\n", len(result.Markers), result.Residual)
+	if len(result.Missing) > 0 {
+		fmt.Printf("Missing:     %s\n", strings.Join(result.Missing, ", "))
+	}
+	return nil
+}
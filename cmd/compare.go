@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/compare"
+	"github.com/gruz0/futuage-test-image-generator/internal/config"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	compareManifest string
+	compareRules    string
+	compareConfig   string
+	compareFormat   string
+	compareOutput   string
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare <pipeline-output-dir>",
+	Short: "Check pipeline outputs against the generated inputs",
+	Long: `Check the files a processing pipeline produced from the generated images
+against per-target rules: dimensions, aspect ratio tolerance, format, maximum
+size and metadata stripping. Outputs are mapped to inputs with a filename
+pattern, by default {target}/{name}.*, where {name} is the input file name
+without extension and {id} the input's spec ID. Exits with a non-zero status
+if any check fails.
+
+Examples:
+  # Check outputs and print failures
+  futuage-test-image-gen compare ./processed --manifest ./test-images/manifest.json --rules rules.json
+
+  # JUnit report for CI
+  futuage-test-image-gen compare ./processed --manifest ./test-images/manifest.json --rules rules.json --format junit -o compare.xml`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          runCompare,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	compareCmd.Flags().StringVarP(&compareManifest, "manifest", "m", "./test-images/manifest.json", "Manifest of the generated inputs")
+	compareCmd.Flags().StringVarP(&compareRules, "rules", "r", "", "Rules file with per-target checks (required)")
+	compareCmd.Flags().StringVarP(&compareConfig, "config", "c", "", "Configuration with the platform targets (default: embedded)")
+	compareCmd.Flags().StringVar(&compareFormat, "format", compare.FormatText, "Report format (text, json, junit)")
+	compareCmd.Flags().StringVarP(&compareOutput, "output", "o", "", "Write the report to a file instead of stdout")
+	_ = compareCmd.MarkFlagRequired("rules")
+}
+
+func runCompare(cmd *cobra.Command, args []string) (err error) {
+	// 1. Check the report options and open the report
+	if !compare.IsValidFormat(compareFormat) {
+		return fmt.Errorf("invalid report format: %s (supported: text, json, junit)", compareFormat)
+	}
+	var w io.Writer = os.Stdout
+	if compareOutput != "" {
+		file, err := os.Create(compareOutput)
+		if err != nil {
+			return fmt.Errorf("failed to create report file: %w", err)
+		}
+		defer func() {
+			if closeErr := file.Close(); closeErr != nil && err == nil {
+				err = fmt.Errorf("failed to close report file: %w", closeErr)
+			}
+		}()
+		w = file
+	}
+
+	// 2. Load inputs and rules
+	cfg, err := config.LoadConfig(compareConfig)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	mf, err := manifest.Load(compareManifest)
+	if err != nil {
+		return err
+	}
+	rules, err := compare.LoadRules(compareRules, cfg)
+	if err != nil {
+		return err
+	}
+
+	// 3. Check every output
+	result, err := compare.Run(args[0], mf, rules)
+	if err != nil {
+		return err
+	}
+
+	// 4. Write the report
+	if err := result.Write(w, compareFormat); err != nil {
+		return err
+	}
+
+	if !result.OK() {
+		return fmt.Errorf("compare failed: %d of %d cases failed", result.Failed, len(result.Cases))
+	}
+	return nil
+}
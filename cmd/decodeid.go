@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+	"github.com/spf13/cobra"
+)
+
+var (
+	decodeIDManifest string
+	decodeIDFormat   string
+)
+
+var decodeIDCmd = &cobra.Command{
+	Use:   "decode-id <image>",
+	Short: "Read the spec ID QR code from a processed image",
+	Long: `Find the QR code embedded in every generated image and print the spec ID
+and generation parameters it carries. The code sits inside the region kept
+by center crops between 9:16 and 1.91:1, so it survives typical platform
+crops, rescaling, recompression and mirroring. With --manifest the ID is
+resolved to the original file.
+
+Examples:
+  futuage-test-image-gen decode-id ./processed/instagram/photo.jpg
+  futuage-test-image-gen decode-id photo.jpg --manifest ./test-images/manifest.json --format json`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          runDecodeID,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+// decodedID is the output of decode-id
+type decodedID struct {
+	Image    string `json:"image"`
+	ID       string `json:"id"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+	Format   string `json:"format"`
+	Quality  int    `json:"quality"`
+	Variant  string `json:"variant,omitempty"`
+	Original string `json:"original,omitempty"`
+}
+
+func init() {
+	decodeIDCmd.Flags().StringVarP(&decodeIDManifest, "manifest", "m", "", "Manifest to resolve the ID to an original file")
+	decodeIDCmd.Flags().StringVar(&decodeIDFormat, "format", "text", "Output format (text, json)")
+}
+
+func runDecodeID(cmd *cobra.Command, args []string) error {
+	if decodeIDFormat != "text" && decodeIDFormat != "json" {
+		return fmt.Errorf("invalid output format: %s (supported: text, json)", decodeIDFormat)
+	}
+
+	// 1. Decode the image and its QR code
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read image: %w", err)
+	}
+	img, _, err := fingerprint.Decode(data)
+	if err != nil {
+		return err
+	}
+	payload, err := generator.DecodeQR(img)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	label, err := generator.ParseSpecPayload(payload)
+	if err != nil {
+		return err
+	}
+
+	result := decodedID{
+		Image:   args[0],
+		ID:      label.ID,
+		Width:   label.Width,
+		Height:  label.Height,
+		Format:  label.Format,
+		Quality: label.Quality,
+		Variant: label.Variant,
+	}
+
+	// 2. Resolve the original file
+	if decodeIDManifest != "" {
+		mf, err := manifest.Load(decodeIDManifest)
+		if err != nil {
+			return err
+		}
+		for _, img := range mf.Images {
+			if img.SpecID() == label.ID {
+				result.Original = img.Filename
+				break
+			}
+		}
+		if result.Original == "" {
+			return fmt.Errorf("spec ID %s not found in %s", label.ID, decodeIDManifest)
+		}
+	}
+
+	// 3. Print the result
+	if decodeIDFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(result); err != nil {
+			return fmt.Errorf("failed to write result: %w", err)
+		}
+		return nil
+	}
+
+	fmt.Printf("ID:         %s\n", result.ID)
+	fmt.Printf("Dimensions: %dx%d\n", result.Width, result.Height)
+	fmt.Printf("Format:     %s Q%d\n", result.Format, result.Quality)
+	if result.Variant != "" {
+		fmt.Printf("Variant:    %s\n", result.Variant)
+	}
+	if result.Original != "" {
+		fmt.Printf("Original:   %s\n", result.Original)
+	}
+	return nil
+}
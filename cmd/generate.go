@@ -1,14 +1,20 @@
 package cmd
 
 import (
+	"crypto/ed25519"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
+	"github.com/gruz0/futuage-test-image-generator/internal/annotations"
 	"github.com/gruz0/futuage-test-image-generator/internal/config"
 	"github.com/gruz0/futuage-test-image-generator/internal/filesystem"
 	"github.com/gruz0/futuage-test-image-generator/internal/generator"
 	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+	"github.com/gruz0/futuage-test-image-generator/internal/oracle"
+	"github.com/gruz0/futuage-test-image-generator/internal/report"
 	"github.com/spf13/cobra"
 )
 
@@ -18,6 +24,14 @@ var (
 	ratios     []string
 	sizes      []string
 	formats    []string
+
+	manifestFormats []string
+	sqlTable        string
+	sqlColumns      []string
+	htmlReport      bool
+	signKey         string
+	expectations    bool
+	annotate        bool
 )
 
 var generateCmd = &cobra.Command{
@@ -33,7 +47,16 @@ Examples:
   futuage-test-image-gen generate --ratios platform --output ./test-images/
 
   # Generate with custom configuration
-  futuage-test-image-gen generate --config ./custom-config.json --output ./test-images/`,
+  futuage-test-image-gen generate --config ./custom-config.json --output ./test-images/
+
+  # Also write CSV and SQL fixtures next to manifest.json
+  futuage-test-image-gen generate --manifest-format json --manifest-format csv --manifest-format sql
+
+  # Also write an HTML contact sheet (index.html)
+  futuage-test-image-gen generate --report
+
+  # Sign manifest.json (writes manifest.json.sig)
+  futuage-test-image-gen generate --sign-key ./fixtures.key`,
 	RunE: runGenerate,
 }
 
@@ -42,7 +65,14 @@ func init() {
 	generateCmd.Flags().StringVarP(&configFile, "config", "c", "", "Custom configuration file (optional)")
 	generateCmd.Flags().StringSliceVar(&ratios, "ratios", []string{}, "Ratio categories to generate (platform, common, edge)")
 	generateCmd.Flags().StringSliceVar(&sizes, "sizes", []string{}, "Size categories to generate (tiny, small, medium, large, xlarge)")
-	generateCmd.Flags().StringSliceVar(&formats, "formats", []string{}, "Format types to generate (jpeg, png, webp)")
+	generateCmd.Flags().StringSliceVar(&formats, "formats", []string{}, "Format types to generate (jpeg, png, webp, gif, apng, bmp, tiff)")
+	generateCmd.Flags().StringSliceVar(&manifestFormats, "manifest-format", []string{manifest.FormatJSON}, "Manifest formats to write, repeatable (json, csv, ndjson, sql)")
+	generateCmd.Flags().StringVar(&sqlTable, "sql-table", "test_images", "Table name for SQL manifest output")
+	generateCmd.Flags().BoolVar(&htmlReport, "report", false, "Write an offline HTML contact sheet (index.html) next to the manifest")
+	generateCmd.Flags().BoolVar(&expectations, "expectations", true, "Write expectations.json with per-target crop and scale predictions")
+	generateCmd.Flags().BoolVar(&annotate, "annotations", true, "Write COCO and Pascal VOC annotations of scene images")
+	generateCmd.Flags().StringVar(&signKey, "sign-key", "", "Sign manifest.json with this ed25519 private key (PEM, PKCS #8)")
+	generateCmd.Flags().StringSliceVar(&sqlColumns, "sql-columns", []string{}, "SQL column mapping as column=field or field (default: all fields)")
 }
 
 func runGenerate(cmd *cobra.Command, args []string) error {
@@ -70,6 +100,16 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("invalid filters: %w", err)
 	}
 
+	sqlLayout, err := buildSQLLayout()
+	if err != nil {
+		return err
+	}
+
+	signingKey, err := loadSigningKey()
+	if err != nil {
+		return err
+	}
+
 	if !filters.IsEmpty() {
 		fmt.Printf("  ✓ Filters: %s\n", filters.Summary())
 	} else {
@@ -123,17 +163,66 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 	// 6. Generate manifest
 	fmt.Printf("Generating manifest...\n")
 	mf := manifest.NewManifest(version, cfg.Version)
+	provenance, err := buildProvenance(cfg, filters, startTime)
+	if err != nil {
+		return err
+	}
+	mf.Provenance = provenance
 	for _, result := range results {
 		if result.Error == nil {
-			mf.AddImage(result.Spec, result.FileSize)
+			if err := mf.AddResult(result); err != nil {
+				return fmt.Errorf("failed to build manifest: %w", err)
+			}
 		}
 	}
 
-	manifestPath := filepath.Join(outputDir, "manifest.json")
-	if err := mf.Write(manifestPath); err != nil {
-		return fmt.Errorf("failed to write manifest: %w", err)
+	for _, format := range manifestFormats {
+		manifestPath := filepath.Join(outputDir, manifest.FileName(format))
+		if err := mf.WriteFormat(manifestPath, format, sqlLayout); err != nil {
+			return fmt.Errorf("failed to write manifest: %w", err)
+		}
+		fmt.Printf("  ✓ Manifest written to: %s\n", manifestPath)
+	}
+
+	if expectations {
+		expectationsPath := filepath.Join(outputDir, oracle.FileName)
+		if err := oracle.Build(mf, cfg).Write(expectationsPath); err != nil {
+			return err
+		}
+		fmt.Printf("  ✓ Expectations written to: %s\n", expectationsPath)
+	}
+
+	if annotate {
+		count, err := annotations.Write(outputDir, mf)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			fmt.Printf("  ✓ Annotations for %d scene images written to: %s\n",
+				count, filepath.Join(outputDir, annotations.COCOFileName))
+		}
+	}
+
+	if signingKey != nil {
+		manifestPath := filepath.Join(outputDir, manifest.FileName(manifest.FormatJSON))
+		if err := manifest.SignFile(manifestPath, signingKey); err != nil {
+			return fmt.Errorf("failed to sign manifest: %w", err)
+		}
+		fmt.Printf("  ✓ Signature written to: %s\n", manifestPath+manifest.SignatureSuffix)
+	}
+
+	if htmlReport {
+		var failures []report.Failure
+		for _, result := range results {
+			if result.Error != nil {
+				failures = append(failures, reportFailure(result))
+			}
+		}
+		if err := report.Write(outputDir, mf, failures); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+		fmt.Printf("  ✓ Report written to: %s\n", filepath.Join(outputDir, report.FileName))
 	}
-	fmt.Printf("  ✓ Manifest written to: %s\n", manifestPath)
 	fmt.Println()
 
 	// 7. Print summary
@@ -147,3 +236,79 @@ func runGenerate(cmd *cobra.Command, args []string) error {
 
 	return nil
 }
+
+// loadSigningKey loads the --sign-key private key, if set. Only the JSON
+// manifest is signed, so it must be among the manifest formats.
+func loadSigningKey() (ed25519.PrivateKey, error) {
+	if signKey == "" {
+		return nil, nil
+	}
+
+	hasJSON := false
+	for _, format := range manifestFormats {
+		hasJSON = hasJSON || format == manifest.FormatJSON
+	}
+	if !hasJSON {
+		return nil, fmt.Errorf("--sign-key requires the json manifest format")
+	}
+
+	return manifest.LoadPrivateKey(signKey)
+}
+
+// buildProvenance records how this run was invoked, ending now
+func buildProvenance(cfg *config.Config, filters *config.Filters, startTime time.Time) (*manifest.Provenance, error) {
+	configHash, err := cfg.SHA256()
+	if err != nil {
+		return nil, err
+	}
+
+	provenance := manifest.NewProvenance(os.Args[1:], startTime, time.Now())
+	provenance.ConfigSource = "default"
+	if configFile != "" {
+		provenance.ConfigSource = configFile
+	}
+	provenance.ConfigSHA256 = configHash
+	provenance.Filters = manifest.FilterSet{
+		Ratios:  append([]string{}, filters.Ratios...),
+		Sizes:   append([]string{}, filters.Sizes...),
+		Formats: append([]string{}, filters.Formats...),
+	}
+	return provenance, nil
+}
+
+// reportFailure describes a failed generation result for the HTML report
+func reportFailure(result generator.GenerationResult) report.Failure {
+	filename, err := filepath.Rel(outputDir, result.Spec.OutputPath)
+	if err != nil {
+		filename = result.Spec.Filename
+	}
+	return report.Failure{
+		Filename: filepath.ToSlash(filename),
+		Format:   result.Spec.Format,
+		Width:    result.Spec.Width,
+		Height:   result.Spec.Height,
+		Error:    result.Error.Error(),
+	}
+}
+
+// buildSQLLayout validates the manifest format flags and returns the
+// table layout for SQL output
+func buildSQLLayout() (manifest.SQLLayout, error) {
+	for _, format := range manifestFormats {
+		if !manifest.IsValidOutputFormat(format) {
+			return manifest.SQLLayout{}, fmt.Errorf("invalid manifest format: %s (supported: %s)",
+				format, strings.Join(manifest.OutputFormats, ", "))
+		}
+	}
+
+	layout := manifest.DefaultSQLLayout()
+	layout.Table = sqlTable
+	if len(sqlColumns) > 0 {
+		columns, err := manifest.ParseSQLColumns(sqlColumns)
+		if err != nil {
+			return manifest.SQLLayout{}, fmt.Errorf("invalid --sql-columns: %w", err)
+		}
+		layout.Columns = columns
+	}
+	return layout, nil
+}
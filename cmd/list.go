@@ -12,8 +12,9 @@ var listCmd = &cobra.Command{
 	Long: `Display all available presets including:
 - Ratio presets (platform, common, edge)
 - Size categories (tiny, small, medium, large, xlarge)
-- Format specifications (jpeg, png, webp)
-- Platform targets (Pinterest, Instagram, LinkedIn, TikTok)`,
+- Format specifications (jpeg, png, webp, gif, apng, bmp, tiff)
+- Platform targets (Pinterest, Instagram, LinkedIn, TikTok)
+- Focal point images, synthetic scenes and overlay layers`,
 	Run: runList,
 }
 
@@ -36,6 +37,17 @@ func runList(cmd *cobra.Command, args []string) {
 	fmt.Println("  jpeg: Q60, Q82, Q95")
 	fmt.Println("  png:  Q95")
 	fmt.Println("  webp: Q82, Q90")
+	fmt.Println("  gif:  256-color palette, variants only")
+	fmt.Println("  apng: variants only")
+	fmt.Println("  bmp:  variants only")
+	fmt.Println("  tiff: variants only")
+	fmt.Println()
+	fmt.Println("Format Variants:")
+	fmt.Println("  webp: lossless, lossless-alpha, lossy-alpha, near-lossless, extended-metadata, animated")
+	fmt.Println("  gif:  transparent, interlaced, animated, animated-once")
+	fmt.Println("  apng: animated, default-is-first-frame, blend-over-alpha, play-twice")
+	fmt.Println("  bmp:  24-bit, 32-bit-alpha, large")
+	fmt.Println("  tiff: uncompressed, deflate, deflate-alpha, multi-page")
 	fmt.Println()
 	fmt.Println("Platform Targets:")
 	fmt.Println("  PINTEREST_2_3  Pinterest  1000×1500 (2:3)")
@@ -45,4 +57,22 @@ func runList(cmd *cobra.Command, args []string) {
 	fmt.Println("  TIKTOK_9_16    TikTok     1080×1920 (9:16)")
 	fmt.Println("  LI_1_1         LinkedIn   1200×1200 (1:1)")
 	fmt.Println("  LI_1_91_1      LinkedIn   1200×628  (1.91:1)")
+	fmt.Println()
+	fmt.Println("Focal Points (subject position for smart-crop tests):")
+	fmt.Println("  wide-top-left      1600×900  top-left-third")
+	fmt.Println("  wide-bottom-right  1600×900  bottom-right-third")
+	fmt.Println("  tall-top           900×1600  top-third")
+	fmt.Println("  tall-bottom-left   900×1600  bottom-left-third")
+	fmt.Println("  square-corner      1200×1200 0.85,0.2")
+	fmt.Println()
+	fmt.Println("Scenes (seeded objects with COCO and Pascal VOC annotations):")
+	fmt.Println("  scene-wide          1600×900  seed 1, 8 objects")
+	fmt.Println("  scene-square        1200×1200 seed 2, 10 objects")
+	fmt.Println("  scene-tall-shapes   900×1600  seed 3, 6 shapes")
+	fmt.Println("  scene-small-glyphs  640×480   seed 4, 5 glyphs")
+	fmt.Println()
+	fmt.Println("Overlay Layers (off unless listed in the config's \"overlays\"):")
+	fmt.Println("  rulers       Edge rulers with ticks every 10/50/100 px and source coordinates")
+	fmt.Println("  crop-guides  Center-crop outline per target ratio on ratio images")
+	fmt.Println("  safe-zones   Hatched platform UI regions outside each target's safe areas")
 }
@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+	"github.com/gruz0/futuage-test-image-generator/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateOutput  string
+	verifyManifest string
+	verifyFormat   string
+	verifyPubKey   string
+
+	diffFormat         string
+	diffMaxSizeGrowth  string
+	diffMaxTotalGrowth string
+	diffFailOnChange   bool
+)
+
+var manifestCmd = &cobra.Command{
+	Use:   "manifest",
+	Short: "Inspect and maintain manifest.json files",
+}
+
+var manifestSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print the manifest JSON Schema",
+	Long: `Print the JSON Schema describing manifest.json for the current
+schema version, for validating manifests in downstream test harnesses.`,
+	Args: cobra.NoArgs,
+	RunE: runManifestSchema,
+}
+
+var manifestMigrateCmd = &cobra.Command{
+	Use:   "migrate <manifest.json>",
+	Short: "Upgrade a manifest to the current schema version",
+	Long: `Load a manifest written by an older version of the tool, migrate it to
+the current schema version and write it back.
+
+Examples:
+  # Upgrade in place
+  futuage-test-image-gen manifest migrate ./test-images/manifest.json
+
+  # Write the upgraded manifest elsewhere
+  futuage-test-image-gen manifest migrate old.json --output new.json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runManifestMigrate,
+}
+
+var manifestVerifyCmd = &cobra.Command{
+	Use:   "verify [output-dir]",
+	Short: "Check generated images against their manifest",
+	Long: `Check that every image recorded in the manifest exists, has the recorded
+size and checksum, decodes with the recorded format and has the recorded
+dimensions. Files in the image directories that the manifest does not list
+are reported as extra. Exits with a non-zero status if anything is missing,
+extra or mismatched.
+
+Examples:
+  # Verify the default output directory
+  futuage-test-image-gen manifest verify ./test-images
+
+  # Verify against a manifest stored elsewhere, with machine-readable output
+  futuage-test-image-gen manifest verify ./test-images --manifest ./golden.json --format json
+
+  # Also check the manifest signature written by generate --sign-key
+  futuage-test-image-gen manifest verify ./test-images --pubkey ./fixtures.key.pub`,
+	Args:          cobra.MaximumNArgs(1),
+	RunE:          runManifestVerify,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var manifestDiffCmd = &cobra.Command{
+	Use:   "diff <old.json> <new.json>",
+	Short: "Compare the fixture sets of two generation runs",
+	Long: `Compare two manifests and report added and removed images, dimension,
+format and pixel changes, and file size deltas. Exits with a non-zero status
+if a size threshold is exceeded, or on any change with --fail-on-change.
+
+Examples:
+  # Show what changed after a config edit
+  futuage-test-image-gen manifest diff old/manifest.json new/manifest.json
+
+  # Fail CI if any image grew by more than 10%
+  futuage-test-image-gen manifest diff old.json new.json --max-size-growth 10% --format json`,
+	Args:          cobra.ExactArgs(2),
+	RunE:          runManifestDiff,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+var manifestReportCmd = &cobra.Command{
+	Use:   "report [output-dir]",
+	Short: "Write an HTML contact sheet of a generated directory",
+	Long: `Write an offline index.html next to manifest.json with thumbnails grouped
+by category, ratio or format, sortable metadata columns, file size bars and
+a detail page per image. Images missing from the directory are highlighted.
+
+Examples:
+  futuage-test-image-gen manifest report ./test-images`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runManifestReport,
+}
+
+var manifestKeygenCmd = &cobra.Command{
+	Use:   "keygen <private-key-path>",
+	Short: "Create an ed25519 key pair for signing manifests",
+	Long: `Create an ed25519 key pair for generate --sign-key and manifest verify
+--pubkey. The private key is written to the given path and the public key to
+the same path with a .pub suffix, both PEM encoded and compatible with
+openssl genpkey -algorithm ed25519.
+
+Examples:
+  futuage-test-image-gen manifest keygen ./fixtures.key`,
+	Args: cobra.ExactArgs(1),
+	RunE: runManifestKeygen,
+}
+
+func init() {
+	manifestDiffCmd.Flags().StringVar(&diffFormat, "format", "text", "Report format (text, json)")
+	manifestDiffCmd.Flags().StringVar(&diffMaxSizeGrowth, "max-size-growth", "", "Maximum growth of any single image, e.g. 10%")
+	manifestDiffCmd.Flags().StringVar(&diffMaxTotalGrowth, "max-total-growth", "", "Maximum growth of the whole set, e.g. 5%")
+	manifestDiffCmd.Flags().BoolVar(&diffFailOnChange, "fail-on-change", false, "Exit non-zero if any image was added, removed or changed")
+
+	manifestVerifyCmd.Flags().StringVarP(&verifyManifest, "manifest", "m", "", "Manifest path (default: <output-dir>/manifest.json)")
+	manifestVerifyCmd.Flags().StringVar(&verifyFormat, "format", "text", "Report format (text, json)")
+	manifestVerifyCmd.Flags().StringVar(&verifyPubKey, "pubkey", "", "Require a valid manifest signature from this ed25519 public key (PEM)")
+
+	manifestMigrateCmd.Flags().StringVarP(&migrateOutput, "output", "o", "", "Output path (default: overwrite the input)")
+
+	manifestCmd.AddCommand(manifestSchemaCmd)
+	manifestCmd.AddCommand(manifestMigrateCmd)
+	manifestCmd.AddCommand(manifestVerifyCmd)
+	manifestCmd.AddCommand(manifestDiffCmd)
+	manifestCmd.AddCommand(manifestReportCmd)
+	manifestCmd.AddCommand(manifestKeygenCmd)
+}
+
+func runManifestSchema(cmd *cobra.Command, args []string) error {
+	_, err := os.Stdout.Write(manifest.Schema())
+	return err
+}
+
+func runManifestMigrate(cmd *cobra.Command, args []string) error {
+	mf, err := manifest.Load(args[0])
+	if err != nil {
+		return err
+	}
+
+	output := migrateOutput
+	if output == "" {
+		output = args[0]
+	}
+	if err := mf.Write(output); err != nil {
+		return fmt.Errorf("failed to write manifest: %w", err)
+	}
+
+	fmt.Printf("✓ Manifest migrated to schema version %d: %s\n", manifest.SchemaVersion, output)
+	return nil
+}
+
+func runManifestVerify(cmd *cobra.Command, args []string) error {
+	if verifyFormat != "text" && verifyFormat != "json" {
+		return fmt.Errorf("invalid report format: %s (supported: text, json)", verifyFormat)
+	}
+
+	dir := "./test-images"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	path := verifyManifest
+	if path == "" {
+		path = filepath.Join(dir, "manifest.json")
+	}
+
+	// 1. Check the signature before trusting the manifest
+	if verifyPubKey != "" {
+		key, err := manifest.LoadPublicKey(verifyPubKey)
+		if err != nil {
+			return err
+		}
+		if err := manifest.VerifyFileSignature(path, key); err != nil {
+			return fmt.Errorf("signature verification failed: %w", err)
+		}
+	}
+
+	// 2. Load the manifest and check the directory against it
+	mf, err := manifest.Load(path)
+	if err != nil {
+		return err
+	}
+	if missing := mf.MissingChecksums(); verifyPubKey != "" && len(missing) > 0 {
+		return fmt.Errorf("signed manifest has no checksum for %d image(s), e.g. %s", len(missing), missing[0])
+	}
+
+	report, err := mf.Verify(dir)
+	if err != nil {
+		return err
+	}
+
+	// 3. Print the report
+	if verifyPubKey != "" && verifyFormat == "text" {
+		fmt.Println("✓ Manifest signature is valid")
+	}
+	if verifyFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	} else {
+		printVerifyReport(report)
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("verification failed: %d problem(s) in %s", report.Problems(), dir)
+	}
+	return nil
+}
+
+func printVerifyReport(report *manifest.VerifyReport) {
+	for _, name := range report.Missing {
+		fmt.Printf("  ✗ missing:  %s\n", name)
+	}
+	for _, name := range report.Extra {
+		fmt.Printf("  ✗ extra:    %s\n", name)
+	}
+	for _, m := range report.Mismatched {
+		fmt.Printf("  ✗ mismatch: %s: %s expected %s, got %s\n", m.Filename, m.Field, m.Expected, m.Actual)
+	}
+
+	fmt.Printf("Checked %d images: %d missing, %d extra, %d mismatches\n",
+		report.Checked, len(report.Missing), len(report.Extra), len(report.Mismatched))
+	if report.OK() {
+		fmt.Println("✓ All files match the manifest")
+	}
+}
+
+func runManifestKeygen(cmd *cobra.Command, args []string) error {
+	if _, err := os.Stat(args[0]); err == nil {
+		return fmt.Errorf("%s already exists", args[0])
+	}
+
+	if err := manifest.GenerateKey(args[0]); err != nil {
+		return err
+	}
+
+	fmt.Printf("✓ Private key written to: %s (keep it secret)\n", args[0])
+	fmt.Printf("✓ Public key written to: %s\n", args[0]+".pub")
+	return nil
+}
+
+func runManifestReport(cmd *cobra.Command, args []string) error {
+	dir := "./test-images"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	mf, err := manifest.Load(filepath.Join(dir, "manifest.json"))
+	if err != nil {
+		return err
+	}
+
+	if err := report.Write(dir, mf, nil); err != nil {
+		return fmt.Errorf("failed to write report: %w", err)
+	}
+
+	fmt.Printf("✓ Report written to: %s\n", filepath.Join(dir, report.FileName))
+	return nil
+}
+
+func runManifestDiff(cmd *cobra.Command, args []string) error {
+	if diffFormat != "text" && diffFormat != "json" {
+		return fmt.Errorf("invalid report format: %s (supported: text, json)", diffFormat)
+	}
+
+	var thresholds manifest.DiffThresholds
+	var err error
+	if thresholds.MaxSizeGrowth, err = parsePercent(diffMaxSizeGrowth); err != nil {
+		return fmt.Errorf("invalid --max-size-growth: %w", err)
+	}
+	if thresholds.MaxTotalSizeGrowth, err = parsePercent(diffMaxTotalGrowth); err != nil {
+		return fmt.Errorf("invalid --max-total-growth: %w", err)
+	}
+
+	// 1. Load both manifests, migrating older ones
+	oldManifest, err := manifest.Load(args[0])
+	if err != nil {
+		return err
+	}
+	newManifest, err := manifest.Load(args[1])
+	if err != nil {
+		return err
+	}
+
+	// 2. Compare and apply thresholds
+	report := manifest.Diff(oldManifest, newManifest)
+	violations := report.Check(thresholds)
+
+	// 3. Print the report
+	if diffFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	} else {
+		printDiffReport(report)
+	}
+
+	if violations > 0 {
+		return fmt.Errorf("diff failed: %d threshold violation(s)", violations)
+	}
+	if diffFailOnChange && report.HasChanges() {
+		return fmt.Errorf("diff failed: fixture set changed")
+	}
+	return nil
+}
+
+func printDiffReport(report *manifest.DiffReport) {
+	for _, name := range report.Added {
+		fmt.Printf("  + %s\n", name)
+	}
+	for _, name := range report.Removed {
+		fmt.Printf("  - %s\n", name)
+	}
+	for _, c := range report.Changed {
+		fmt.Printf("  ~ %s: %s %s -> %s\n", c.Filename, c.Field, c.Old, c.New)
+	}
+	for _, d := range report.SizeDeltas {
+		fmt.Printf("  ~ %s: size %d -> %d bytes (%+.1f%%)\n", d.Filename, d.OldBytes, d.NewBytes, d.DeltaPercent)
+	}
+	for _, v := range report.Violations {
+		fmt.Printf("  ✗ %s\n", v)
+	}
+
+	fmt.Printf("%d added, %d removed, %d changed, %d resized; total %d -> %d bytes (%+.1f%%)\n",
+		len(report.Added), len(report.Removed), len(report.Changed), len(report.SizeDeltas),
+		report.OldBytes, report.NewBytes, report.TotalDeltaPercent())
+	if !report.HasChanges() {
+		fmt.Println("✓ No changes")
+	}
+}
+
+// parsePercent parses a threshold such as "10%" or "10". An empty value
+// disables the threshold and returns nil; "0%" fails on any growth.
+func parsePercent(value string) (*float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	percent, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(value), "%"), 64)
+	if err != nil || percent < 0 {
+		return nil, fmt.Errorf("expected a non-negative percentage, got %q", value)
+	}
+	return &percent, nil
+}
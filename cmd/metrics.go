@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+	"github.com/gruz0/futuage-test-image-generator/internal/metrics"
+	"github.com/spf13/cobra"
+)
+
+var (
+	metricsManifest   string
+	metricsThresholds string
+	metricsFormat     string
+)
+
+var metricsCmd = &cobra.Command{
+	Use:   "metrics <pipeline-output-dir>",
+	Short: "Measure the quality of pipeline outputs against the originals",
+	Long: `Match pipeline outputs to the generated originals, center-crop and rescale
+each original to the output's dimensions, and compute PSNR, SSIM and the
+perceptual hash distance. Scores are aggregated per target and per output
+format. Exits with a non-zero status if any output violates the thresholds
+in the metrics config.
+
+Examples:
+  futuage-test-image-gen metrics ./processed --manifest ./test-images/manifest.json --thresholds metrics.json`,
+	Args:          cobra.ExactArgs(1),
+	RunE:          runMetrics,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+}
+
+func init() {
+	metricsCmd.Flags().StringVarP(&metricsManifest, "manifest", "m", "./test-images/manifest.json", "Manifest of the generated originals")
+	metricsCmd.Flags().StringVarP(&metricsThresholds, "thresholds", "t", "", "Metrics config with targets and thresholds (required)")
+	metricsCmd.Flags().StringVar(&metricsFormat, "format", "text", "Report format (text, json)")
+	_ = metricsCmd.MarkFlagRequired("thresholds")
+}
+
+func runMetrics(cmd *cobra.Command, args []string) error {
+	if metricsFormat != "text" && metricsFormat != "json" {
+		return fmt.Errorf("invalid report format: %s (supported: text, json)", metricsFormat)
+	}
+
+	// 1. Load originals and thresholds
+	mf, err := manifest.Load(metricsManifest)
+	if err != nil {
+		return err
+	}
+	cfg, err := metrics.LoadConfig(metricsThresholds)
+	if err != nil {
+		return err
+	}
+
+	// 2. Measure every matched pair
+	report, err := metrics.Run(args[0], filepath.Dir(metricsManifest), mf, cfg)
+	if err != nil {
+		return err
+	}
+
+	// 3. Print the report
+	if metricsFormat == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return fmt.Errorf("failed to write report: %w", err)
+		}
+	} else if err := report.WriteText(os.Stdout); err != nil {
+		return err
+	}
+
+	if !report.OK() {
+		return fmt.Errorf("metrics failed: %d output(s) violate thresholds", report.Violations)
+	}
+	return nil
+}
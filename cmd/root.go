@@ -30,4 +30,9 @@ func Execute() {
 func init() {
 	rootCmd.AddCommand(generateCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(manifestCmd)
+	rootCmd.AddCommand(compareCmd)
+	rootCmd.AddCommand(metricsCmd)
+	rootCmd.AddCommand(decodeIDCmd)
+	rootCmd.AddCommand(analyzeCmd)
 }
@@ -0,0 +1,395 @@
+// Package analyze recovers how a pipeline transformed a generated image by
+// locating its fiducial markers in the processed output.
+package analyze
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+	"github.com/gruz0/futuage-test-image-generator/internal/oracle"
+)
+
+// colorTolerance is the largest RGB distance at which an output pixel
+// still counts as a marker color. Markers are at least ~127 apart from each
+// other and from the backgrounds.
+const colorTolerance = 56
+
+// minMarkerPixels is the smallest blob accepted as a marker
+const minMarkerPixels = 4
+
+// letterboxTolerance is the smallest bar in output pixels reported as
+// letterboxing
+const letterboxTolerance = 1.0
+
+// Marker is a fiducial found in the output
+type Marker struct {
+	Name    string  `json:"name"`
+	X       float64 `json:"x"`
+	Y       float64 `json:"y"`
+	Pixels  int     `json:"pixels"`
+	Clipped bool    `json:"clipped,omitempty"` // blob touches the output edge
+	Error   float64 `json:"error"`             // distance from the fitted position
+}
+
+// Letterbox is the padding around the source content in output pixels
+type Letterbox struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Right  int `json:"right"`
+	Bottom int `json:"bottom"`
+}
+
+// Any reports whether any side is padded
+func (l Letterbox) Any() bool {
+	return l.Left > 0 || l.Top > 0 || l.Right > 0 || l.Bottom > 0
+}
+
+// Result describes the transformation from source to output
+type Result struct {
+	SourceWidth  int         `json:"source_width"`
+	SourceHeight int         `json:"source_height"`
+	OutputWidth  int         `json:"output_width"`
+	OutputHeight int         `json:"output_height"`
+	Orientation  Orientation `json:"orientation"`
+	ScaleX       float64     `json:"scale_x"`
+	ScaleY       float64     `json:"scale_y"`
+	Crop         oracle.Rect `json:"crop"`    // visible source region
+	Cropped      bool        `json:"cropped"` // crop is smaller than the source
+	Letterbox    Letterbox   `json:"letterbox"`
+	Letterboxed  bool        `json:"letterboxed"`
+	Residual     float64     `json:"residual"` // RMS marker error in output pixels
+	Markers      []Marker    `json:"markers"`
+	Missing      []string    `json:"missing,omitempty"`
+}
+
+// Analyze locates the fiducials of a width x height source image in a
+// processed output and infers the crop, scale, orientation and letterboxing
+// that produced it
+func Analyze(img image.Image, width, height int) (*Result, error) {
+	fiducials := generator.Fiducials(width, height)
+	if len(fiducials) == 0 {
+		return nil, fmt.Errorf("images smaller than %dpx carry no fiducials", generator.FiducialMinSize)
+	}
+
+	// 1. Find the blob of every marker color
+	bounds := img.Bounds()
+	markers := findMarkers(img, fiducials)
+
+	// 2. Fit the transformation to the markers that are fully visible
+	var points []fitPoint
+	for _, f := range fiducials {
+		m, ok := markers[f.Name]
+		if !ok || m.Clipped {
+			continue
+		}
+		sx, sy := f.Center()
+		points = append(points, fitPoint{sx: sx, sy: sy, ox: m.X, oy: m.Y})
+	}
+	if len(points) < 2 {
+		return nil, fmt.Errorf("found %d usable fiducial(s), need at least 2", len(points))
+	}
+	fit, err := bestFit(points, width, height)
+	if err != nil {
+		return nil, err
+	}
+
+	// 3. Derive crop and letterboxing
+	result := &Result{
+		SourceWidth:  width,
+		SourceHeight: height,
+		OutputWidth:  bounds.Dx(),
+		OutputHeight: bounds.Dy(),
+		Orientation:  fit.orientation,
+		ScaleX:       round3(fit.scaleX),
+		ScaleY:       round3(fit.scaleY),
+		Residual:     round3(fit.residual),
+		Markers:      []Marker{},
+	}
+	tolX, tolY := cropTolerance(img)
+	result.Crop, result.Cropped = fit.crop(width, height, bounds.Dx(), bounds.Dy(), tolX, tolY)
+	result.Letterbox = fit.letterbox(width, height, bounds.Dx(), bounds.Dy())
+	result.Letterboxed = result.Letterbox.Any()
+
+	// 4. Report every marker against its fitted position
+	for _, f := range fiducials {
+		m, ok := markers[f.Name]
+		if !ok {
+			if fit.visible(f, width, height, bounds.Dx(), bounds.Dy()) {
+				result.Missing = append(result.Missing, f.Name)
+			}
+			continue
+		}
+		sx, sy := f.Center()
+		px, py := fit.apply(sx, sy, width, height)
+		m.Error = round3(math.Hypot(m.X-px, m.Y-py))
+		result.Markers = append(result.Markers, m)
+	}
+	return result, nil
+}
+
+// findMarkers returns the largest blob of each marker color, in output
+// coordinates relative to the image bounds
+func findMarkers(img image.Image, fiducials []generator.Fiducial) map[string]Marker {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	rgba := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Over)
+
+	// 1. Classify pixels by marker color
+	classes := make([]int8, w*h)
+	for i := range classes {
+		p := rgba.Pix[i*4 : i*4+3]
+		classes[i] = -1
+		for n, f := range fiducials {
+			dr := int(p[0]) - int(f.Color.R)
+			dg := int(p[1]) - int(f.Color.G)
+			db := int(p[2]) - int(f.Color.B)
+			if dr*dr+dg*dg+db*db <= colorTolerance*colorTolerance {
+				classes[i] = int8(n)
+				break
+			}
+		}
+	}
+
+	// 2. Flood fill connected blobs and keep the largest per color
+	markers := make(map[string]Marker)
+	visited := make([]bool, w*h)
+	var stack []int
+	for start, class := range classes {
+		if class < 0 || visited[start] {
+			continue
+		}
+		count, sumX, sumY := 0, 0.0, 0.0
+		clipped := false
+		stack = append(stack[:0], start)
+		visited[start] = true
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%w, i/w
+			count++
+			sumX += float64(x) + 0.5
+			sumY += float64(y) + 0.5
+			if x == 0 || y == 0 || x == w-1 || y == h-1 {
+				clipped = true
+			}
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[1] < 0 || n[0] >= w || n[1] >= h {
+					continue
+				}
+				j := n[1]*w + n[0]
+				if !visited[j] && classes[j] == class {
+					visited[j] = true
+					stack = append(stack, j)
+				}
+			}
+		}
+
+		name := fiducials[class].Name
+		if count < minMarkerPixels || count <= markers[name].Pixels {
+			continue
+		}
+		markers[name] = Marker{
+			Name:    name,
+			X:       round3(sumX / float64(count)),
+			Y:       round3(sumY / float64(count)),
+			Pixels:  count,
+			Clipped: clipped,
+		}
+	}
+	return markers
+}
+
+// fitPoint pairs a marker's source and output centers
+type fitPoint struct {
+	sx, sy float64
+	ox, oy float64
+}
+
+// fit maps oriented source coordinates to output coordinates:
+// out = scale * orient(src) + offset
+type fit struct {
+	orientation      Orientation
+	scaleX, scaleY   float64
+	offsetX, offsetY float64
+	residual         float64
+}
+
+// bestFit tries every orientation and keeps the one with positive scales
+// and the smallest residual
+func bestFit(points []fitPoint, width, height int) (fit, error) {
+	var fits []fit
+	for _, o := range Orientations {
+		if f, ok := fitOrientation(points, o, width, height); ok {
+			fits = append(fits, f)
+		}
+	}
+	if len(fits) == 0 {
+		return fit{}, fmt.Errorf("fiducials do not match any orientation")
+	}
+	sort.SliceStable(fits, func(i, j int) bool { return fits[i].residual < fits[j].residual })
+	return fits[0], nil
+}
+
+// fitOrientation solves scale and offset per axis by least squares. When
+// the markers span only one axis a uniform scale is assumed.
+func fitOrientation(points []fitPoint, o Orientation, width, height int) (fit, bool) {
+	n := float64(len(points))
+	px := make([]float64, len(points))
+	py := make([]float64, len(points))
+	var mpx, mpy, mox, moy float64
+	for i, p := range points {
+		px[i], py[i] = o.Apply(p.sx, p.sy, width, height)
+		mpx += px[i] / n
+		mpy += py[i] / n
+		mox += p.ox / n
+		moy += p.oy / n
+	}
+
+	var sxx, syy, sxo, syo float64
+	for i, p := range points {
+		dx, dy := px[i]-mpx, py[i]-mpy
+		sxx += dx * dx
+		syy += dy * dy
+		sxo += dx * (p.ox - mox)
+		syo += dy * (p.oy - moy)
+	}
+
+	const minSpread = 1.0
+	f := fit{orientation: o}
+	switch {
+	case sxx > minSpread && syy > minSpread:
+		f.scaleX, f.scaleY = sxo/sxx, syo/syy
+	case sxx+syy > minSpread:
+		f.scaleX = (sxo + syo) / (sxx + syy)
+		f.scaleY = f.scaleX
+	default:
+		return fit{}, false
+	}
+	if f.scaleX <= 0 || f.scaleY <= 0 {
+		return fit{}, false
+	}
+	f.offsetX = mox - f.scaleX*mpx
+	f.offsetY = moy - f.scaleY*mpy
+
+	var sum float64
+	for i, p := range points {
+		ex := f.scaleX*px[i] + f.offsetX - p.ox
+		ey := f.scaleY*py[i] + f.offsetY - p.oy
+		sum += ex*ex + ey*ey
+	}
+	f.residual = math.Sqrt(sum / n)
+	return f, true
+}
+
+// apply maps a source point to the output
+func (f fit) apply(x, y float64, width, height int) (float64, float64) {
+	ox, oy := f.orientation.Apply(x, y, width, height)
+	return f.scaleX*ox + f.offsetX, f.scaleY*oy + f.offsetY
+}
+
+// crop returns the source region visible in the output. Crop edges within
+// tolX, tolY output pixels of the source edges count as uncropped.
+func (f fit) crop(width, height, outWidth, outHeight int, tolX, tolY float64) (oracle.Rect, bool) {
+	ow, oh := f.orientation.Size(width, height)
+
+	// Output bounds in oriented source coordinates, clamped to the source
+	x0 := clamp(-f.offsetX/f.scaleX, 0, float64(ow))
+	y0 := clamp(-f.offsetY/f.scaleY, 0, float64(oh))
+	x1 := clamp((float64(outWidth)-f.offsetX)/f.scaleX, 0, float64(ow))
+	y1 := clamp((float64(outHeight)-f.offsetY)/f.scaleY, 0, float64(oh))
+	x0, x1 = snap(x0, 0, tolX/f.scaleX), snap(x1, float64(ow), tolX/f.scaleX)
+	y0, y1 = snap(y0, 0, tolY/f.scaleY), snap(y1, float64(oh), tolY/f.scaleY)
+
+	// Undo the orientation
+	ax, ay := f.orientation.Invert(x0, y0, width, height)
+	bx, by := f.orientation.Invert(x1, y1, width, height)
+	rect := oracle.Rect{
+		X:      int(math.Round(math.Min(ax, bx))),
+		Y:      int(math.Round(math.Min(ay, by))),
+		Width:  int(math.Round(math.Abs(bx - ax))),
+		Height: int(math.Round(math.Abs(by - ay))),
+	}
+	return rect, rect.Width < width || rect.Height < height
+}
+
+// snap returns edge if v is within tolerance of it
+func snap(v, edge, tolerance float64) float64 {
+	if math.Abs(v-edge) < tolerance {
+		return edge
+	}
+	return v
+}
+
+// cropTolerance returns how far in output pixels, per axis, a crop edge may
+// lie from the source edge and still count as uncropped. Subsampled chroma
+// blurs the marker edges of decoded JPEGs and biases the fit by up to three
+// quarters of a chroma block, so along subsampled axes crops smaller than
+// that cannot be told apart from the bias and are not reported. Along other
+// axes, and for images without subsampled chroma, edges are only rounded.
+func cropTolerance(img image.Image) (x, y float64) {
+	blockX, blockY := 1, 1
+	if ycc, ok := img.(*image.YCbCr); ok {
+		switch ycc.SubsampleRatio {
+		case image.YCbCrSubsampleRatio422:
+			blockX = 2
+		case image.YCbCrSubsampleRatio420:
+			blockX, blockY = 2, 2
+		case image.YCbCrSubsampleRatio440:
+			blockY = 2
+		case image.YCbCrSubsampleRatio411:
+			blockX = 4
+		case image.YCbCrSubsampleRatio410:
+			blockX, blockY = 4, 2
+		}
+	}
+	tolerance := func(block int) float64 {
+		if block == 1 {
+			return 0.5
+		}
+		return 0.75 * float64(block)
+	}
+	return tolerance(blockX), tolerance(blockY)
+}
+
+// letterbox returns the output padding around the source content
+func (f fit) letterbox(width, height, outWidth, outHeight int) Letterbox {
+	ow, oh := f.orientation.Size(width, height)
+	x0, y0 := f.offsetX, f.offsetY
+	x1, y1 := f.offsetX+f.scaleX*float64(ow), f.offsetY+f.scaleY*float64(oh)
+
+	bar := func(v float64) int {
+		if v < letterboxTolerance {
+			return 0
+		}
+		return int(math.Round(v))
+	}
+	return Letterbox{
+		Left:   bar(x0),
+		Top:    bar(y0),
+		Right:  bar(float64(outWidth) - x1),
+		Bottom: bar(float64(outHeight) - y1),
+	}
+}
+
+// visible reports whether a marker should be fully inside the output
+func (f fit) visible(m generator.Fiducial, width, height, outWidth, outHeight int) bool {
+	ax, ay := f.apply(float64(m.Rect.Min.X), float64(m.Rect.Min.Y), width, height)
+	bx, by := f.apply(float64(m.Rect.Max.X), float64(m.Rect.Max.Y), width, height)
+	return math.Min(ax, bx) >= 0 && math.Min(ay, by) >= 0 &&
+		math.Max(ax, bx) <= float64(outWidth) && math.Max(ay, by) <= float64(outHeight)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+func round3(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
@@ -0,0 +1,218 @@
+package analyze
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"testing"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+	"github.com/gruz0/futuage-test-image-generator/internal/oracle"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+func renderSource(t *testing.T, width, height int) *image.RGBA {
+	t.Helper()
+	spec := generator.ImageSpec{
+		Width: width, Height: height, Ratio: "3:2", RatioDecimal: 1.5,
+		Format: "png", Quality: 95, SizeCategory: "medium", Category: "platform",
+	}
+	img, err := generator.Render(spec)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	return img.(*image.RGBA)
+}
+
+func crop(img *image.RGBA, r image.Rectangle) *image.RGBA {
+	return img.SubImage(r).(*image.RGBA)
+}
+
+func scale(img image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+func rotate90(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dy(), b.Dx()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.Set(b.Dy()-1-y, x, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+func mirror(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	for y := 0; y < b.Dy(); y++ {
+		for x := 0; x < b.Dx(); x++ {
+			dst.Set(b.Dx()-1-x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return dst
+}
+
+func pad(img *image.RGBA, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	xdraw.Draw(dst, dst.Bounds(), image.NewUniform(color.Black), image.Point{}, xdraw.Src)
+	b := img.Bounds()
+	at := image.Pt((w-b.Dx())/2, (h-b.Dy())/2)
+	xdraw.Draw(dst, image.Rectangle{Min: at, Max: at.Add(b.Size())}, img, b.Min, xdraw.Src)
+	return dst
+}
+
+func recompress(t *testing.T, img image.Image, quality int) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return decoded
+}
+
+func TestAnalyze(t *testing.T) {
+	src := renderSource(t, 1200, 800)
+	full := oracle.Rect{Width: 1200, Height: 800}
+	portrait := oracle.Rect{X: 280, Width: 640, Height: 800}
+
+	tests := []struct {
+		name          string
+		img           image.Image
+		wantOrient    Orientation
+		wantScale     float64
+		wantCrop      oracle.Rect
+		wantLetterbox Letterbox
+	}{
+		{"untouched", src, OrientationNone, 1, full, Letterbox{}},
+		{"center crop", crop(src, image.Rect(280, 0, 920, 800)), OrientationNone, 1, portrait, Letterbox{}},
+		{"crop and scale", scale(crop(src, image.Rect(280, 0, 920, 800)), 320, 400), OrientationNone, 0.5, portrait, Letterbox{}},
+		{"rotated", rotate90(src), OrientationRotate90, 1, full, Letterbox{}},
+		{"mirrored", mirror(src), OrientationFlipHorizontal, 1, full, Letterbox{}},
+		{"letterboxed", pad(scale(src, 600, 400), 600, 600), OrientationNone, 0.5, full, Letterbox{Top: 100, Bottom: 100}},
+		{"recompressed", recompress(t, scale(src, 600, 400), 70), OrientationNone, 0.5, full, Letterbox{}},
+	}
+
+	near := func(a, b, tolerance int) bool { return math.Abs(float64(a-b)) <= float64(tolerance) }
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Analyze(tt.img, 1200, 800)
+			if err != nil {
+				t.Fatalf("Analyze() error = %v", err)
+			}
+			if result.Orientation != tt.wantOrient {
+				t.Errorf("Orientation = %s, want %s", result.Orientation, tt.wantOrient)
+			}
+			if math.Abs(result.ScaleX-tt.wantScale) > 0.01 || math.Abs(result.ScaleY-tt.wantScale) > 0.01 {
+				t.Errorf("Scale = %.3f x %.3f, want %.3f", result.ScaleX, result.ScaleY, tt.wantScale)
+			}
+			c := result.Crop
+			if !near(c.X, tt.wantCrop.X, 3) || !near(c.Y, tt.wantCrop.Y, 3) ||
+				!near(c.Width, tt.wantCrop.Width, 3) || !near(c.Height, tt.wantCrop.Height, 3) {
+				t.Errorf("Crop = %+v, want %+v", c, tt.wantCrop)
+			}
+			if result.Cropped != (tt.wantCrop != full) {
+				t.Errorf("Cropped = %v", result.Cropped)
+			}
+			l := result.Letterbox
+			if !near(l.Left, tt.wantLetterbox.Left, 2) || !near(l.Top, tt.wantLetterbox.Top, 2) ||
+				!near(l.Right, tt.wantLetterbox.Right, 2) || !near(l.Bottom, tt.wantLetterbox.Bottom, 2) {
+				t.Errorf("Letterbox = %+v, want %+v", l, tt.wantLetterbox)
+			}
+			if len(result.Missing) > 0 {
+				t.Errorf("Missing = %v", result.Missing)
+			}
+		})
+	}
+}
+
+func TestAnalyze_RecompressedPortrait(t *testing.T) {
+	// Chroma subsampling shifts the marker centroids of tall images by up
+	// to a pixel, which must not be reported as a crop
+	src := renderSource(t, 1080, 1920)
+	result, err := Analyze(recompress(t, src, 82), 1080, 1920)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	if want := (oracle.Rect{Width: 1080, Height: 1920}); result.Crop != want || result.Cropped {
+		t.Errorf("Crop = %+v (cropped %v), want %+v", result.Crop, result.Cropped, want)
+	}
+}
+
+func TestAnalyze_SmallCrops(t *testing.T) {
+	src := renderSource(t, 1200, 800)
+	oneLeft := crop(src, image.Rect(1, 0, 1200, 800))
+
+	tests := []struct {
+		name        string
+		img         image.Image
+		wantCrop    oracle.Rect
+		wantCropped bool
+	}{
+		{"lossless 1px crop", oneLeft, oracle.Rect{X: 1, Width: 1199, Height: 800}, true},
+		// Known blind spot: along subsampled chroma axes a 1px crop is
+		// within the chroma bias and reads as uncropped
+		{"subsampled 1px crop", recompress(t, oneLeft, 95), oracle.Rect{Width: 1200, Height: 800}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := Analyze(tt.img, 1200, 800)
+			if err != nil {
+				t.Fatalf("Analyze() error = %v", err)
+			}
+			if result.Crop != tt.wantCrop || result.Cropped != tt.wantCropped {
+				t.Errorf("Crop = %+v (cropped %v), want %+v (cropped %v)", result.Crop, result.Cropped, tt.wantCrop, tt.wantCropped)
+			}
+		})
+	}
+}
+
+func TestCropTolerance(t *testing.T) {
+	tests := []struct {
+		name         string
+		img          image.Image
+		wantX, wantY float64
+	}{
+		{"rgba", image.NewRGBA(image.Rect(0, 0, 8, 8)), 0.5, 0.5},
+		{"ycbcr 444", image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio444), 0.5, 0.5},
+		{"ycbcr 422", image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio422), 1.5, 0.5},
+		{"ycbcr 420", image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio420), 1.5, 1.5},
+		{"ycbcr 410", image.NewYCbCr(image.Rect(0, 0, 8, 8), image.YCbCrSubsampleRatio410), 3, 1.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if x, y := cropTolerance(tt.img); x != tt.wantX || y != tt.wantY {
+				t.Errorf("cropTolerance() = %v, %v, want %v, %v", x, y, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestAnalyze_Errors(t *testing.T) {
+	if _, err := Analyze(image.NewRGBA(image.Rect(0, 0, 100, 100)), 100, 100); err == nil {
+		t.Error("Analyze() accepted a source too small for fiducials")
+	}
+	if _, err := Analyze(image.NewRGBA(image.Rect(0, 0, 400, 400)), 400, 400); err == nil {
+		t.Error("Analyze() succeeded without fiducials")
+	}
+}
+
+func TestOrientation_Invert(t *testing.T) {
+	for _, o := range Orientations {
+		x, y := o.Apply(30, 70, 200, 100)
+		if ix, iy := o.Invert(x, y, 200, 100); ix != 30 || iy != 70 {
+			t.Errorf("%s: Invert(Apply(30, 70)) = (%v, %v)", o, ix, iy)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package analyze
+
+// Orientation is one of the eight rotations and flips of an image, named
+// after the operation that turns the source into the output
+type Orientation string
+
+// Supported orientations; rotations are clockwise
+const (
+	OrientationNone           Orientation = "none"
+	OrientationFlipHorizontal Orientation = "flip-horizontal"
+	OrientationRotate180      Orientation = "rotate-180"
+	OrientationFlipVertical   Orientation = "flip-vertical"
+	OrientationTranspose      Orientation = "transpose"
+	OrientationRotate90       Orientation = "rotate-90"
+	OrientationTransverse     Orientation = "transverse"
+	OrientationRotate270      Orientation = "rotate-270"
+)
+
+// Orientations lists every orientation, starting with the identity
+var Orientations = []Orientation{
+	OrientationNone,
+	OrientationFlipHorizontal,
+	OrientationRotate180,
+	OrientationFlipVertical,
+	OrientationTranspose,
+	OrientationRotate90,
+	OrientationTransverse,
+	OrientationRotate270,
+}
+
+// Transposed reports whether the orientation swaps width and height
+func (o Orientation) Transposed() bool {
+	switch o {
+	case OrientationTranspose, OrientationRotate90, OrientationTransverse, OrientationRotate270:
+		return true
+	default:
+		return false
+	}
+}
+
+// Size returns the dimensions of a width x height image after the
+// orientation
+func (o Orientation) Size(width, height int) (int, int) {
+	if o.Transposed() {
+		return height, width
+	}
+	return width, height
+}
+
+// Apply maps a point of a width x height source to the oriented image
+func (o Orientation) Apply(x, y float64, width, height int) (float64, float64) {
+	w, h := float64(width), float64(height)
+	switch o {
+	case OrientationFlipHorizontal:
+		return w - x, y
+	case OrientationRotate180:
+		return w - x, h - y
+	case OrientationFlipVertical:
+		return x, h - y
+	case OrientationTranspose:
+		return y, x
+	case OrientationRotate90:
+		return h - y, x
+	case OrientationTransverse:
+		return h - y, w - x
+	case OrientationRotate270:
+		return y, w - x
+	default:
+		return x, y
+	}
+}
+
+// Invert maps a point of the oriented image back to the width x height
+// source
+func (o Orientation) Invert(x, y float64, width, height int) (float64, float64) {
+	w, h := float64(width), float64(height)
+	switch o {
+	case OrientationFlipHorizontal:
+		return w - x, y
+	case OrientationRotate180:
+		return w - x, h - y
+	case OrientationFlipVertical:
+		return x, h - y
+	case OrientationTranspose:
+		return y, x
+	case OrientationRotate90:
+		return y, h - x
+	case OrientationTransverse:
+		return w - y, h - x
+	case OrientationRotate270:
+		return w - y, x
+	default:
+		return x, y
+	}
+}
@@ -0,0 +1,222 @@
+// Package annotations exports the ground truth of synthetic scene images as
+// COCO and Pascal VOC datasets, so object detection and segmentation
+// models can be evaluated against the generated fixtures.
+package annotations
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+)
+
+// COCOFileName is the name of the COCO dataset written next to
+// manifest.json
+const COCOFileName = "annotations.coco.json"
+
+// VOCDir is the directory of the Pascal VOC files, one per image
+const VOCDir = "annotations/voc"
+
+// COCO is a COCO object detection dataset
+type COCO struct {
+	Info        COCOInfo         `json:"info"`
+	Images      []COCOImage      `json:"images"`
+	Annotations []COCOAnnotation `json:"annotations"`
+	Categories  []COCOCategory   `json:"categories"`
+}
+
+// COCOInfo describes the dataset
+type COCOInfo struct {
+	Description string `json:"description"`
+	Version     string `json:"version"`
+	DateCreated string `json:"date_created"`
+}
+
+// COCOImage is an annotated image
+type COCOImage struct {
+	ID       int    `json:"id"`
+	FileName string `json:"file_name"`
+	Width    int    `json:"width"`
+	Height   int    `json:"height"`
+}
+
+// COCOAnnotation is an object instance with its box and outline
+type COCOAnnotation struct {
+	ID           int         `json:"id"`
+	ImageID      int         `json:"image_id"`
+	CategoryID   int         `json:"category_id"`
+	BBox         [4]float64  `json:"bbox"`
+	Area         float64     `json:"area"`
+	Segmentation [][]float64 `json:"segmentation"`
+	IsCrowd      int         `json:"iscrowd"`
+}
+
+// COCOCategory is an object class
+type COCOCategory struct {
+	ID            int    `json:"id"`
+	Name          string `json:"name"`
+	Supercategory string `json:"supercategory"`
+}
+
+// BuildCOCO collects the scene images of a manifest into a COCO dataset.
+// Categories cover every object class, numbered from 1 in a stable order.
+func BuildCOCO(m *manifest.Manifest) *COCO {
+	c := &COCO{
+		Info: COCOInfo{
+			Description: "Synthetic scenes with ground-truth objects",
+			Version:     m.ConfigVersion,
+			DateCreated: time.Now().UTC().Format(time.RFC3339),
+		},
+		Images:      []COCOImage{},
+		Annotations: []COCOAnnotation{},
+	}
+
+	categories := make(map[string]int)
+	for i, class := range generator.SceneClasses() {
+		categories[class.Name] = i + 1
+		c.Categories = append(c.Categories, COCOCategory{ID: i + 1, Name: class.Name, Supercategory: class.Supercategory})
+	}
+
+	for _, img := range m.Images {
+		if img.Scene == nil {
+			continue
+		}
+		imageID := len(c.Images) + 1
+		c.Images = append(c.Images, COCOImage{ID: imageID, FileName: img.Filename, Width: img.Width, Height: img.Height})
+		for _, o := range img.Scene.Objects {
+			c.Annotations = append(c.Annotations, COCOAnnotation{
+				ID:           len(c.Annotations) + 1,
+				ImageID:      imageID,
+				CategoryID:   categories[o.Class],
+				BBox:         o.BBox,
+				Area:         o.Area,
+				Segmentation: [][]float64{o.Polygon},
+			})
+		}
+	}
+	return c
+}
+
+// VOC is a Pascal VOC annotation of one image
+type VOC struct {
+	XMLName   xml.Name    `xml:"annotation"`
+	Folder    string      `xml:"folder"`
+	Filename  string      `xml:"filename"`
+	Source    VOCSource   `xml:"source"`
+	Size      VOCSize     `xml:"size"`
+	Segmented int         `xml:"segmented"`
+	Objects   []VOCObject `xml:"object"`
+}
+
+// VOCSource names the dataset an image belongs to
+type VOCSource struct {
+	Database string `xml:"database"`
+}
+
+// VOCSize is the size of an image
+type VOCSize struct {
+	Width  int `xml:"width"`
+	Height int `xml:"height"`
+	Depth  int `xml:"depth"`
+}
+
+// VOCObject is an object instance with its box
+type VOCObject struct {
+	Name      string    `xml:"name"`
+	Pose      string    `xml:"pose"`
+	Truncated int       `xml:"truncated"`
+	Difficult int       `xml:"difficult"`
+	BndBox    VOCBndBox `xml:"bndbox"`
+}
+
+// VOCBndBox is a box in 1-based, inclusive pixel coordinates
+type VOCBndBox struct {
+	XMin int `xml:"xmin"`
+	YMin int `xml:"ymin"`
+	XMax int `xml:"xmax"`
+	YMax int `xml:"ymax"`
+}
+
+// BuildVOC converts the scene of an image record to a Pascal VOC
+// annotation. Boxes are widened to whole pixels. Objects are never
+// truncated, since scenes keep them inside the image.
+func BuildVOC(img manifest.ImageRecord) VOC {
+	v := VOC{
+		Folder:   path.Dir(img.Filename),
+		Filename: path.Base(img.Filename),
+		Source:   VOCSource{Database: "futuage-test-image-generator"},
+		Size:     VOCSize{Width: img.Width, Height: img.Height, Depth: 3},
+	}
+	if img.Scene == nil {
+		return v
+	}
+	v.Segmented = 1
+	for _, o := range img.Scene.Objects {
+		x, y, w, h := o.BBox[0], o.BBox[1], o.BBox[2], o.BBox[3]
+		v.Objects = append(v.Objects, VOCObject{
+			Name: o.Class,
+			Pose: "Unspecified",
+			BndBox: VOCBndBox{
+				XMin: int(math.Floor(x)) + 1,
+				YMin: int(math.Floor(y)) + 1,
+				XMax: int(math.Ceil(x + w)),
+				YMax: int(math.Ceil(y + h)),
+			},
+		})
+	}
+	return v
+}
+
+// VOCFileName returns the path of an image's VOC file relative to the
+// output directory
+func VOCFileName(img manifest.ImageRecord) string {
+	name := path.Base(img.Filename)
+	return path.Join(VOCDir, strings.TrimSuffix(name, path.Ext(name))+".xml")
+}
+
+// Write writes the COCO dataset and one VOC file per scene image under
+// outputDir. It returns the number of annotated images and writes nothing
+// if the manifest has no scenes.
+func Write(outputDir string, m *manifest.Manifest) (int, error) {
+	coco := BuildCOCO(m)
+	if len(coco.Images) == 0 {
+		return 0, nil
+	}
+
+	// 1. COCO dataset
+	data, err := json.MarshalIndent(coco, "", "  ")
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal COCO annotations: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, COCOFileName), data, 0644); err != nil {
+		return 0, fmt.Errorf("failed to write COCO annotations: %w", err)
+	}
+
+	// 2. Pascal VOC files
+	if err := os.MkdirAll(filepath.Join(outputDir, filepath.FromSlash(VOCDir)), 0755); err != nil {
+		return 0, fmt.Errorf("failed to create VOC directory: %w", err)
+	}
+	for _, img := range m.Images {
+		if img.Scene == nil {
+			continue
+		}
+		data, err := xml.MarshalIndent(BuildVOC(img), "", "  ")
+		if err != nil {
+			return 0, fmt.Errorf("failed to marshal VOC annotation: %w", err)
+		}
+		outputPath := filepath.Join(outputDir, filepath.FromSlash(VOCFileName(img)))
+		if err := os.WriteFile(outputPath, append(data, '\n'), 0644); err != nil {
+			return 0, fmt.Errorf("failed to write VOC annotation: %w", err)
+		}
+	}
+
+	return len(coco.Images), nil
+}
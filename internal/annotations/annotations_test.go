@@ -0,0 +1,161 @@
+package annotations
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+)
+
+func testManifest(t *testing.T) *manifest.Manifest {
+	t.Helper()
+
+	m := manifest.NewManifest("1.0.0", "1.0.0")
+	if err := m.AddImage(generator.ImageSpec{
+		Width: 100, Height: 100, Ratio: "1:1", Format: "PNG", Quality: 95,
+		OutputPath: "/out/ratios/1-1/a.png",
+	}, 1000); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+	m.Images = append(m.Images, manifest.ImageRecord{
+		Filename: "scenes/scene-a_200x100_png_q95.png",
+		Width:    200,
+		Height:   100,
+		Scene: &manifest.Scene{Seed: 1, Objects: []manifest.SceneObject{
+			{Class: "square", BBox: [4]float64{10.5, 20.25, 30, 40}, Polygon: []float64{10.5, 20.25, 40.5, 20.25, 40.5, 60.25, 10.5, 60.25}, Area: 1200},
+			{Class: "star", BBox: [4]float64{100, 10, 50, 50}, Polygon: []float64{100, 10, 150, 10, 125, 60}, Area: 1250},
+		}},
+	}, manifest.ImageRecord{
+		Filename: "scenes/scene-b_100x100_png_q95.png",
+		Width:    100,
+		Height:   100,
+		Scene: &manifest.Scene{Seed: 2, Objects: []manifest.SceneObject{
+			{Class: "letter-t", BBox: [4]float64{5, 5, 20, 20}, Polygon: []float64{5, 5, 25, 5, 25, 25}, Area: 200},
+		}},
+	})
+	return m
+}
+
+func TestBuildCOCO(t *testing.T) {
+	c := BuildCOCO(testManifest(t))
+
+	if len(c.Images) != 2 {
+		t.Fatalf("len(Images) = %d, want 2", len(c.Images))
+	}
+	if c.Images[0].ID != 1 || c.Images[0].FileName != "scenes/scene-a_200x100_png_q95.png" || c.Images[0].Width != 200 {
+		t.Errorf("Images[0] = %+v", c.Images[0])
+	}
+	if len(c.Categories) != len(generator.SceneClasses()) {
+		t.Errorf("len(Categories) = %d, want %d", len(c.Categories), len(generator.SceneClasses()))
+	}
+
+	categories := make(map[int]string)
+	for i, cat := range c.Categories {
+		if cat.ID != i+1 {
+			t.Errorf("Categories[%d].ID = %d, want %d", i, cat.ID, i+1)
+		}
+		categories[cat.ID] = cat.Name
+	}
+
+	tests := []struct {
+		imageID int
+		class   string
+	}{
+		{1, "square"},
+		{1, "star"},
+		{2, "letter-t"},
+	}
+	if len(c.Annotations) != len(tests) {
+		t.Fatalf("len(Annotations) = %d, want %d", len(c.Annotations), len(tests))
+	}
+	for i, tt := range tests {
+		a := c.Annotations[i]
+		if a.ID != i+1 || a.ImageID != tt.imageID || categories[a.CategoryID] != tt.class {
+			t.Errorf("Annotations[%d] = id %d, image %d, class %s, want id %d, image %d, class %s",
+				i, a.ID, a.ImageID, categories[a.CategoryID], i+1, tt.imageID, tt.class)
+		}
+	}
+	if a := c.Annotations[0]; a.BBox != [4]float64{10.5, 20.25, 30, 40} || a.Area != 1200 || len(a.Segmentation) != 1 || len(a.Segmentation[0]) != 8 {
+		t.Errorf("Annotations[0] = %+v", a)
+	}
+}
+
+func TestBuildVOC(t *testing.T) {
+	v := BuildVOC(testManifest(t).Images[1])
+
+	if v.Folder != "scenes" || v.Filename != "scene-a_200x100_png_q95.png" {
+		t.Errorf("Folder, Filename = %q, %q", v.Folder, v.Filename)
+	}
+	if v.Size != (VOCSize{Width: 200, Height: 100, Depth: 3}) || v.Segmented != 1 {
+		t.Errorf("Size = %+v, Segmented = %d", v.Size, v.Segmented)
+	}
+
+	tests := []struct {
+		name string
+		want VOCBndBox
+	}{
+		{"square", VOCBndBox{XMin: 11, YMin: 21, XMax: 41, YMax: 61}},
+		{"star", VOCBndBox{XMin: 101, YMin: 11, XMax: 150, YMax: 60}},
+	}
+	if len(v.Objects) != len(tests) {
+		t.Fatalf("len(Objects) = %d, want %d", len(v.Objects), len(tests))
+	}
+	for i, tt := range tests {
+		if v.Objects[i].Name != tt.name || v.Objects[i].BndBox != tt.want {
+			t.Errorf("Objects[%d] = %s %+v, want %s %+v", i, v.Objects[i].Name, v.Objects[i].BndBox, tt.name, tt.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+
+	n, err := Write(dir, testManifest(t))
+	if err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Write() = %d, want 2", n)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, COCOFileName))
+	if err != nil {
+		t.Fatalf("failed to read COCO annotations: %v", err)
+	}
+	var c COCO
+	if err := json.Unmarshal(data, &c); err != nil {
+		t.Fatalf("failed to parse COCO annotations: %v", err)
+	}
+	if len(c.Annotations) != 3 {
+		t.Errorf("len(Annotations) = %d, want 3", len(c.Annotations))
+	}
+
+	data, err = os.ReadFile(filepath.Join(dir, "annotations", "voc", "scene-b_100x100_png_q95.xml"))
+	if err != nil {
+		t.Fatalf("failed to read VOC annotation: %v", err)
+	}
+	var v VOC
+	if err := xml.Unmarshal(data, &v); err != nil {
+		t.Fatalf("failed to parse VOC annotation: %v", err)
+	}
+	if len(v.Objects) != 1 || v.Objects[0].Name != "letter-t" {
+		t.Errorf("Objects = %+v", v.Objects)
+	}
+}
+
+func TestWrite_NoScenes(t *testing.T) {
+	dir := t.TempDir()
+	m := manifest.NewManifest("1.0.0", "1.0.0")
+
+	n, err := Write(dir, m)
+	if err != nil || n != 0 {
+		t.Fatalf("Write() = %d, %v, want 0, nil", n, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, COCOFileName)); !os.IsNotExist(err) {
+		t.Errorf("COCO annotations written without scenes")
+	}
+}
@@ -0,0 +1,205 @@
+// Package compare checks a pipeline's processed outputs against the
+// generated inputs in a manifest and per-target rules.
+package compare
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/config"
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+)
+
+// Case statuses
+const (
+	StatusPass = "pass"
+	StatusFail = "fail"
+	StatusSkip = "skip"
+)
+
+// Case is the result of checking one input for one target
+type Case struct {
+	Target   string   `json:"target"`
+	Input    string   `json:"input"`
+	Output   string   `json:"output,omitempty"`
+	Status   string   `json:"status"`
+	Failures []string `json:"failures,omitempty"`
+	Width    int      `json:"width,omitempty"`
+	Height   int      `json:"height,omitempty"`
+	Format   string   `json:"format,omitempty"`
+	Bytes    int64    `json:"bytes,omitempty"`
+	Metadata []string `json:"metadata,omitempty"`
+}
+
+// Result is the outcome of a comparison run
+type Result struct {
+	Cases   []Case `json:"cases"`
+	Passed  int    `json:"passed"`
+	Failed  int    `json:"failed"`
+	Skipped int    `json:"skipped"`
+}
+
+// OK returns true if no case failed
+func (r *Result) OK() bool {
+	return r.Failed == 0
+}
+
+// Run checks the outputs in dir of every manifest image for every target
+// in the rules, sorted by target and input
+func Run(dir string, m *manifest.Manifest, rules *Rules) (*Result, error) {
+	targets := make([]string, 0, len(rules.Targets))
+	for name := range rules.Targets {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+
+	result := &Result{Cases: []Case{}}
+	for _, target := range targets {
+		rule := rules.Targets[target]
+		pattern := rule.OutputPattern
+		if pattern == "" {
+			pattern = rules.OutputPattern
+		}
+
+		for _, img := range m.Images {
+			c, err := runCase(dir, target, rule, pattern, img, rules.SkipMissing)
+			if err != nil {
+				return nil, err
+			}
+
+			switch c.Status {
+			case StatusPass:
+				result.Passed++
+			case StatusFail:
+				result.Failed++
+			default:
+				result.Skipped++
+			}
+			result.Cases = append(result.Cases, c)
+		}
+	}
+	return result, nil
+}
+
+// runCase locates and checks the output of one input for one target
+func runCase(dir, target string, rule TargetRule, pattern string, img manifest.ImageRecord, skipMissing bool) (Case, error) {
+	c := Case{Target: target, Input: img.Filename}
+	fail := func(format string, args ...interface{}) {
+		c.Failures = append(c.Failures, fmt.Sprintf(format, args...))
+	}
+
+	// 1. Map the input to its output
+	if strings.Contains(pattern, "{id}") && img.SpecID() == "" {
+		c.Status = StatusFail
+		fail("input has no spec ID (manifest predates spec_sha256)")
+		return c, nil
+	}
+	glob := ExpandPattern(pattern, target, img)
+	matches, err := Locate(dir, pattern, target, img)
+	if err != nil {
+		return c, err
+	}
+
+	switch {
+	case len(matches) == 0 && skipMissing:
+		c.Status = StatusSkip
+		return c, nil
+	case len(matches) == 0:
+		c.Status = StatusFail
+		fail("no output matches %s", glob)
+		return c, nil
+	case len(matches) > 1:
+		c.Status = StatusFail
+		fail("%d outputs match %s", len(matches), glob)
+		return c, nil
+	}
+
+	rel, err := filepath.Rel(dir, matches[0])
+	if err != nil {
+		return c, err
+	}
+	c.Output = filepath.ToSlash(rel)
+
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		return c, fmt.Errorf("failed to read %s: %w", matches[0], err)
+	}
+
+	// 2. Inspect the output
+	fp := fingerprint.Compute(data)
+	c.Width, c.Height, c.Format = fp.Width, fp.Height, fp.Format
+	c.Bytes = int64(len(data))
+	c.Metadata = fingerprint.Metadata(data)
+
+	// 3. Apply the rule
+	if fp.Format == "" {
+		fail("output cannot be decoded")
+	} else {
+		if rule.Width > 0 && rule.Height > 0 && (fp.Width != rule.Width || fp.Height != rule.Height) {
+			fail("dimensions %dx%d, want %dx%d", fp.Width, fp.Height, rule.Width, rule.Height)
+		}
+		if rule.Ratio != "" && rule.RatioTolerance > 0 {
+			ratio, _ := config.ParseRatio(rule.Ratio) // validated by LoadRules
+			actual := float64(fp.Width) / float64(fp.Height)
+			if deviation := math.Abs(actual-ratio.Decimal) / ratio.Decimal; deviation > rule.RatioTolerance {
+				fail("ratio %.4f deviates %.2f%% from %s, tolerance %g%%", actual, deviation*100, rule.Ratio, rule.RatioTolerance*100)
+			}
+		}
+		if len(rule.Formats) > 0 && !contains(rule.Formats, fp.Format) {
+			fail("format %s, want one of %s", fp.Format, strings.Join(rule.Formats, ", "))
+		}
+	}
+	if rule.MaxBytes > 0 && c.Bytes > rule.MaxBytes {
+		fail("%d bytes exceeds limit of %d", c.Bytes, rule.MaxBytes)
+	}
+	if rule.StripMetadata && len(c.Metadata) > 0 {
+		fail("metadata not stripped: %s", strings.Join(c.Metadata, ", "))
+	}
+
+	c.Status = StatusPass
+	if len(c.Failures) > 0 {
+		c.Status = StatusFail
+	}
+	return c, nil
+}
+
+// Locate returns the files in dir matching the output pattern of an input
+// image and target
+func Locate(dir, pattern, target string, img manifest.ImageRecord) ([]string, error) {
+	glob := ExpandPattern(pattern, target, img)
+	matches, err := filepath.Glob(filepath.Join(dir, filepath.FromSlash(glob)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid output pattern %q: %w", pattern, err)
+	}
+	return matches, nil
+}
+
+// ExpandPattern substitutes the placeholders of an output pattern for an
+// input image and target
+func ExpandPattern(pattern, target string, img manifest.ImageRecord) string {
+	withoutExt := strings.TrimSuffix(img.Filename, path.Ext(img.Filename))
+
+	return strings.NewReplacer(
+		"{target}", target,
+		"{name}", path.Base(withoutExt),
+		"{path}", withoutExt,
+		"{category}", img.Category,
+		"{format}", img.Format,
+		"{id}", img.SpecID(),
+	).Replace(pattern)
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
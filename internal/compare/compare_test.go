@@ -0,0 +1,172 @@
+package compare
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/config"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+	"github.com/gruz0/futuage-test-image-generator/pkg/testimages"
+)
+
+func testManifest() *manifest.Manifest {
+	m := manifest.NewManifest("1.0.0", "1.0.0")
+	m.Images = []manifest.ImageRecord{
+		{Filename: "ratios/1-1/a.png", Category: "ratios", Width: 500, Height: 500, Format: "png", SpecSHA256: strings.Repeat("a", 64)},
+		{Filename: "ratios/1-1/b.png", Category: "ratios", Width: 500, Height: 500, Format: "png", SpecSHA256: strings.Repeat("b", 64)},
+		{Filename: "ratios/1-1/c.png", Category: "ratios", Width: 500, Height: 500, Format: "png", SpecSHA256: strings.Repeat("c", 64)},
+	}
+	return m
+}
+
+func writeOutput(t *testing.T, dir, name string, data []byte) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	writeOutput(t, dir, "SQUARE/a.jpg", testimages.Bytes(t, "jpeg", 100, 100))
+	writeOutput(t, dir, "SQUARE/b.webp", testimages.Bytes(t, "webp", 100, 80, testimages.Metadata("exif")))
+
+	cfg := &config.Config{Targets: map[string]config.Target{
+		"SQUARE": {Platform: "Test", Dimensions: []int{100, 100}, Ratio: "1:1"},
+	}}
+	rules := &Rules{Targets: map[string]TargetRule{
+		"SQUARE": {RatioTolerance: 0.01, Formats: []string{"jpg"}, MaxBytes: 1 << 20, StripMetadata: true},
+	}}
+	if err := rules.resolve(cfg); err != nil {
+		t.Fatalf("resolve() error = %v", err)
+	}
+
+	result, err := Run(dir, testManifest(), rules)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if result.Passed != 1 || result.Failed != 2 || result.OK() {
+		t.Fatalf("Run() = %d passed, %d failed, want 1 and 2", result.Passed, result.Failed)
+	}
+
+	pass, bad, missing := result.Cases[0], result.Cases[1], result.Cases[2]
+	if pass.Status != StatusPass || pass.Output != "SQUARE/a.jpg" || pass.Format != "jpeg" {
+		t.Errorf("a = %+v, want pass", pass)
+	}
+	if len(bad.Failures) != 4 || !reflect.DeepEqual(bad.Metadata, []string{"exif"}) {
+		t.Errorf("b failures = %q, want dimensions, ratio, format and metadata", bad.Failures)
+	}
+	if missing.Status != StatusFail || missing.Output != "" {
+		t.Errorf("c = %+v, want missing output failure", missing)
+	}
+
+	// Missing outputs can be skipped instead
+	rules.SkipMissing = true
+	result, err = Run(dir, testManifest(), rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Skipped != 1 || result.Failed != 1 {
+		t.Errorf("Run() with skip_missing = %d failed, %d skipped, want 1 and 1", result.Failed, result.Skipped)
+	}
+}
+
+func TestExpandPattern(t *testing.T) {
+	img := manifest.ImageRecord{Filename: "ratios/2-3/tiny_66x100_png_q95.png", Category: "ratios", Format: "png", SpecSHA256: "0123456789abcdef" + strings.Repeat("0", 48)}
+
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{DefaultOutputPattern, "IG/tiny_66x100_png_q95.*"},
+		{"{path}_{target}.jpg", "ratios/2-3/tiny_66x100_png_q95_IG.jpg"},
+		{"{category}/{format}/{id}.*", "ratios/png/0123456789abcdef.*"},
+	}
+
+	for _, tt := range tests {
+		if got := ExpandPattern(tt.pattern, "IG", img); got != tt.want {
+			t.Errorf("ExpandPattern(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestResult_Write(t *testing.T) {
+	result := &Result{
+		Cases: []Case{
+			{Target: "A", Input: "x.png", Output: "A/x.jpg", Status: StatusPass},
+			{Target: "A", Input: "y.png", Status: StatusFail, Failures: []string{"no output"}},
+			{Target: "B", Input: "x.png", Status: StatusSkip},
+		},
+		Passed: 1, Failed: 1, Skipped: 1,
+	}
+
+	var junit strings.Builder
+	if err := result.Write(&junit, FormatJUnit); err != nil {
+		t.Fatalf("Write(junit) error = %v", err)
+	}
+	var suites junitSuites
+	if err := xml.Unmarshal([]byte(junit.String()), &suites); err != nil {
+		t.Fatalf("JUnit output is not valid XML: %v", err)
+	}
+	if suites.Tests != 3 || len(suites.Suites) != 2 || suites.Suites[0].Failures != 1 || suites.Suites[1].Skipped != 1 {
+		t.Errorf("JUnit = %+v", suites)
+	}
+
+	var text strings.Builder
+	if err := result.Write(&text, FormatText); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.String(), "✗ A y.png -> (no output): no output") || !strings.Contains(text.String(), "1 passed, 1 failed, 1 skipped") {
+		t.Errorf("text = %q", text.String())
+	}
+
+	if err := result.Write(&text, "xml"); err == nil {
+		t.Error("Write() expected error for unknown format")
+	}
+	if IsValidFormat("xml") || !IsValidFormat(FormatJUnit) {
+		t.Error("IsValidFormat() disagrees with the supported formats")
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"targets": {"IG_FEED_4_5": {"formats": ["JPG"]}, "CUSTOM": {"width": 300, "height": 200}}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := LoadRules(path, cfg)
+	if err != nil {
+		t.Fatalf("LoadRules() error = %v", err)
+	}
+	if rules.OutputPattern != DefaultOutputPattern {
+		t.Errorf("OutputPattern = %q", rules.OutputPattern)
+	}
+	ig := rules.Targets["IG_FEED_4_5"]
+	if ig.Width != 1080 || ig.Height != 1350 || ig.Ratio != "4:5" || ig.Formats[0] != "jpeg" {
+		t.Errorf("IG_FEED_4_5 = %+v, want config dimensions and normalized format", ig)
+	}
+	if custom := rules.Targets["CUSTOM"]; custom.Ratio != "300:200" {
+		t.Errorf("CUSTOM ratio = %q, want 300:200", custom.Ratio)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"targets": {}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRules(path, cfg); err == nil {
+		t.Error("LoadRules() expected error without targets")
+	}
+}
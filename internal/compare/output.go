@@ -0,0 +1,129 @@
+package compare
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Output formats
+const (
+	FormatText  = "text"
+	FormatJSON  = "json"
+	FormatJUnit = "junit"
+)
+
+// IsValidFormat reports whether format is a supported output format
+func IsValidFormat(format string) bool {
+	return format == FormatText || format == FormatJSON || format == FormatJUnit
+}
+
+// Write writes the result in the given output format
+func (r *Result) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatText:
+		return r.writeText(w)
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	case FormatJUnit:
+		return r.writeJUnit(w)
+	default:
+		return fmt.Errorf("invalid report format: %s (supported: text, json, junit)", format)
+	}
+}
+
+// writeText lists failed cases and a summary
+func (r *Result) writeText(w io.Writer) error {
+	for _, c := range r.Cases {
+		if c.Status != StatusFail {
+			continue
+		}
+		output := c.Output
+		if output == "" {
+			output = "(no output)"
+		}
+		if _, err := fmt.Fprintf(w, "  ✗ %s %s -> %s: %s\n", c.Target, c.Input, output, strings.Join(c.Failures, "; ")); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "%d cases: %d passed, %d failed, %d skipped\n", len(r.Cases), r.Passed, r.Failed, r.Skipped)
+	return err
+}
+
+// JUnit XML structures, one test suite per target
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// writeJUnit writes the result as JUnit XML for CI test reporting
+func (r *Result) writeJUnit(w io.Writer) error {
+	report := junitSuites{Name: "compare", Tests: len(r.Cases), Failures: r.Failed, Skipped: r.Skipped}
+
+	index := make(map[string]int)
+	for _, c := range r.Cases {
+		i, ok := index[c.Target]
+		if !ok {
+			i = len(report.Suites)
+			index[c.Target] = i
+			report.Suites = append(report.Suites, junitSuite{Name: c.Target})
+		}
+		suite := &report.Suites[i]
+		suite.Tests++
+
+		tc := junitCase{Name: c.Input, ClassName: c.Target}
+		switch c.Status {
+		case StatusFail:
+			suite.Failures++
+			tc.Failure = &junitFailure{
+				Message: strings.Join(c.Failures, "; "),
+				Text:    fmt.Sprintf("output: %s\n%s", c.Output, strings.Join(c.Failures, "\n")),
+			}
+		case StatusSkip:
+			suite.Skipped++
+			tc.Skipped = &struct{}{}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,111 @@
+package compare
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/config"
+)
+
+// DefaultOutputPattern locates a processed file by target and input name
+const DefaultOutputPattern = "{target}/{name}.*"
+
+// Rules describes what the pipeline is expected to produce per target
+type Rules struct {
+	// OutputPattern is a glob relative to the pipeline output directory
+	// with placeholders {target}, {name} (input file name without
+	// extension), {path} (input path without extension), {category},
+	// {format} and {id} (spec ID)
+	OutputPattern string `json:"output_pattern"`
+
+	// SkipMissing reports inputs without an output as skipped instead of
+	// failed
+	SkipMissing bool `json:"skip_missing"`
+
+	Targets map[string]TargetRule `json:"targets"`
+}
+
+// TargetRule lists the checks applied to every output for one target.
+// Zero values disable a check.
+type TargetRule struct {
+	OutputPattern  string   `json:"output_pattern,omitempty"`  // overrides Rules.OutputPattern
+	Width          int      `json:"width,omitempty"`           // defaults to the config target
+	Height         int      `json:"height,omitempty"`          // defaults to the config target
+	Ratio          string   `json:"ratio,omitempty"`           // defaults to the config target, else width:height
+	RatioTolerance float64  `json:"ratio_tolerance,omitempty"` // relative, e.g. 0.01 for 1%
+	Formats        []string `json:"formats,omitempty"`
+	MaxBytes       int64    `json:"max_bytes,omitempty"`
+	StripMetadata  bool     `json:"strip_metadata,omitempty"`
+}
+
+// LoadRules reads a rules file and fills in target dimensions from the
+// config
+func LoadRules(path string, cfg *config.Config) (*Rules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules file: %w", err)
+	}
+
+	var rules Rules
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules file: %w", err)
+	}
+
+	if err := rules.resolve(cfg); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+	return &rules, nil
+}
+
+// resolve validates the rules and applies defaults
+func (r *Rules) resolve(cfg *config.Config) error {
+	if r.OutputPattern == "" {
+		r.OutputPattern = DefaultOutputPattern
+	}
+	if len(r.Targets) == 0 {
+		return fmt.Errorf("at least one target is required")
+	}
+
+	for name, rule := range r.Targets {
+		if target, ok := cfg.Targets[name]; ok {
+			if rule.Width == 0 && rule.Height == 0 {
+				rule.Width, rule.Height = target.Dimensions[0], target.Dimensions[1]
+			}
+			if rule.Ratio == "" {
+				rule.Ratio = target.Ratio
+			}
+		}
+		if rule.Ratio == "" && rule.Width > 0 && rule.Height > 0 {
+			rule.Ratio = fmt.Sprintf("%d:%d", rule.Width, rule.Height)
+		}
+
+		if rule.Width < 0 || rule.Height < 0 || rule.MaxBytes < 0 || rule.RatioTolerance < 0 {
+			return fmt.Errorf("target %s has negative limits", name)
+		}
+		if rule.Ratio != "" {
+			if _, err := config.ParseRatio(rule.Ratio); err != nil {
+				return fmt.Errorf("target %s: %w", name, err)
+			}
+		}
+		for i, format := range rule.Formats {
+			rule.Formats[i] = normalizeFormat(format)
+		}
+
+		r.Targets[name] = rule
+	}
+	return nil
+}
+
+// normalizeFormat maps format names to the names decoders report
+func normalizeFormat(format string) string {
+	switch format = strings.ToLower(format); format {
+	case "jpg":
+		return "jpeg"
+	case "tif":
+		return "tiff"
+	default:
+		return format
+	}
+}
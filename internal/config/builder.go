@@ -3,6 +3,8 @@ package config
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
 
 	"github.com/gruz0/futuage-test-image-generator/internal/generator"
@@ -51,6 +53,32 @@ func (b *SpecBuilder) BuildSpecs() ([]generator.ImageSpec, error) {
 	}
 	specs = append(specs, edgeSpecs...)
 
+	// 4. Generate focal point images
+	focalSpecs, err := b.buildFocalPointSpecs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build focal point specs: %w", err)
+	}
+	specs = append(specs, focalSpecs...)
+
+	// 5. Generate scene images
+	sceneSpecs, err := b.buildSceneSpecs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build scene specs: %w", err)
+	}
+	specs = append(specs, sceneSpecs...)
+
+	// 6. Generate format variant images
+	variantSpecs, err := b.buildVariantSpecs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to build variant specs: %w", err)
+	}
+	specs = append(specs, variantSpecs...)
+
+	// 7. Apply the selected overlay layers
+	for i := range specs {
+		specs[i].Overlays = b.Config.Overlays
+	}
+
 	return specs, nil
 }
 
@@ -87,7 +115,7 @@ func (b *SpecBuilder) buildRatioSpecs() ([]generator.ImageSpec, error) {
 
 				// Generate for each format
 				for formatName, format := range b.Config.Formats {
-					if !b.Filters.ShouldIncludeFormat(formatName) {
+					if !b.Filters.ShouldIncludeFormat(formatName) || format.VariantsOnly {
 						continue
 					}
 
@@ -125,6 +153,8 @@ func (b *SpecBuilder) buildRatioSpecs() ([]generator.ImageSpec, error) {
 						Category:     presetName,
 						OutputPath:   outputPath,
 						Filename:     filename,
+						MimeType:     format.MimeType,
+						CropGuides:   b.cropGuides(),
 					}
 
 					specs = append(specs, spec)
@@ -136,6 +166,46 @@ func (b *SpecBuilder) buildRatioSpecs() ([]generator.ImageSpec, error) {
 	return specs, nil
 }
 
+// cropGuides returns one crop guide per distinct target aspect ratio,
+// ordered from portrait to landscape, if the crop-guides overlay is
+// selected
+func (b *SpecBuilder) cropGuides() []generator.CropGuide {
+	if !slices.Contains(b.Config.Overlays, generator.OverlayCropGuides) {
+		return nil
+	}
+
+	names := make([]string, 0, len(b.Config.Targets))
+	for name := range b.Config.Targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	// Group targets by their reduced aspect ratio
+	var guides []generator.CropGuide
+	index := make(map[[2]int]int)
+	for _, name := range names {
+		target := b.Config.Targets[name]
+		w, h := target.Dimensions[0], target.Dimensions[1]
+		g := gcd(w, h)
+		key := [2]int{w / g, h / g}
+		if i, ok := index[key]; ok {
+			guides[i].Label += ", " + name
+			continue
+		}
+		index[key] = len(guides)
+		guides = append(guides, generator.CropGuide{
+			Label:       target.Ratio + " " + name,
+			RatioWidth:  key[0],
+			RatioHeight: key[1],
+		})
+	}
+
+	sort.SliceStable(guides, func(i, j int) bool {
+		return guides[i].RatioWidth*guides[j].RatioHeight < guides[j].RatioWidth*guides[i].RatioHeight
+	})
+	return guides
+}
+
 // buildTargetSpecs builds specs for platform targets
 func (b *SpecBuilder) buildTargetSpecs() ([]generator.ImageSpec, error) {
 	var specs []generator.ImageSpec
@@ -170,7 +240,7 @@ func (b *SpecBuilder) buildTargetSpecs() ([]generator.ImageSpec, error) {
 
 		// Generate for each format (typically only JPEG for targets)
 		for formatName, format := range b.Config.Formats {
-			if !b.Filters.ShouldIncludeFormat(formatName) {
+			if !b.Filters.ShouldIncludeFormat(formatName) || format.VariantsOnly {
 				continue
 			}
 
@@ -213,6 +283,8 @@ func (b *SpecBuilder) buildTargetSpecs() ([]generator.ImageSpec, error) {
 				Category:     category,
 				OutputPath:   outputPath,
 				Filename:     filename,
+				MimeType:     format.MimeType,
+				SafeAreas:    safeAreas(target),
 			}
 
 			specs = append(specs, spec)
@@ -222,6 +294,18 @@ func (b *SpecBuilder) buildTargetSpecs() ([]generator.ImageSpec, error) {
 	return specs, nil
 }
 
+// safeAreas resolves the safe areas of a target to rectangles
+func safeAreas(target Target) []generator.SafeArea {
+	var areas []generator.SafeArea
+	for _, area := range target.SafeAreas {
+		areas = append(areas, generator.SafeArea{
+			Name: area.Name,
+			Rect: area.Bounds(target.Dimensions[0], target.Dimensions[1]),
+		})
+	}
+	return areas
+}
+
 // buildEdgeCaseSpecs builds specs for edge cases
 func (b *SpecBuilder) buildEdgeCaseSpecs() ([]generator.ImageSpec, error) {
 	var specs []generator.ImageSpec
@@ -261,7 +345,7 @@ func (b *SpecBuilder) buildEdgeCaseSpecs() ([]generator.ImageSpec, error) {
 
 		// Generate for each format
 		for formatName, format := range b.Config.Formats {
-			if !b.Filters.ShouldIncludeFormat(formatName) {
+			if !b.Filters.ShouldIncludeFormat(formatName) || format.VariantsOnly {
 				continue
 			}
 
@@ -298,6 +382,150 @@ func (b *SpecBuilder) buildEdgeCaseSpecs() ([]generator.ImageSpec, error) {
 				Category:     category,
 				OutputPath:   outputPath,
 				Filename:     filename,
+				MimeType:     format.MimeType,
+			}
+
+			specs = append(specs, spec)
+		}
+	}
+
+	return specs, nil
+}
+
+// buildFocalPointSpecs builds specs for focal point cases
+func (b *SpecBuilder) buildFocalPointSpecs() ([]generator.ImageSpec, error) {
+	var specs []generator.ImageSpec
+
+	for _, focal := range b.Config.FocalPoints {
+		point, err := generator.ParseFocalPoint(focal.Position)
+		if err != nil {
+			return nil, fmt.Errorf("invalid focal point case %s: %w", focal.Name, err)
+		}
+
+		if err := validateDimensions("focal point case "+focal.Name, focal.Dimensions); err != nil {
+			return nil, err
+		}
+		width := focal.Dimensions[0]
+		height := focal.Dimensions[1]
+
+		// Reduce the ratio and look up its category for the background
+		g := gcd(width, height)
+		ratioStr := fmt.Sprintf("%d:%d", width/g, height/g)
+		category := b.Config.GetCategoryForRatio(ratioStr)
+		if !b.Filters.ShouldIncludeRatioCategory(category) {
+			continue
+		}
+
+		sizeCategory := b.getSizeCategoryForDimension(max(width, height))
+		if !b.Filters.ShouldIncludeSizeCategory(sizeCategory) {
+			continue
+		}
+
+		for formatName, format := range b.Config.Formats {
+			if !b.Filters.ShouldIncludeFormat(formatName) || format.VariantsOnly {
+				continue
+			}
+
+			// Use first quality
+			if len(format.Qualities) == 0 {
+				continue
+			}
+			quality := format.Qualities[0]
+
+			filename := fmt.Sprintf("%s_%dx%d_%s_q%d%s",
+				focal.Name,
+				width, height,
+				strings.ToLower(formatName),
+				quality,
+				format.Extension,
+			)
+
+			spec := generator.ImageSpec{
+				Width:        width,
+				Height:       height,
+				Ratio:        ratioStr,
+				RatioDecimal: float64(width) / float64(height),
+				Format:       strings.ToUpper(formatName),
+				Quality:      quality,
+				SizeCategory: cases.Title(language.English).String(sizeCategory),
+				Category:     category,
+				OutputPath:   filepath.Join(b.BaseDir, "focal-points", filename),
+				Filename:     filename,
+				MimeType:     format.MimeType,
+				FocalPoint:   &point,
+			}
+
+			specs = append(specs, spec)
+		}
+	}
+
+	return specs, nil
+}
+
+// buildSceneSpecs builds specs for synthetic scenes
+func (b *SpecBuilder) buildSceneSpecs() ([]generator.ImageSpec, error) {
+	var specs []generator.ImageSpec
+
+	for _, scene := range b.Config.Scenes {
+		if err := validateDimensions("scene "+scene.Name, scene.Dimensions); err != nil {
+			return nil, err
+		}
+		if err := validateSceneClasses(scene); err != nil {
+			return nil, err
+		}
+
+		width := scene.Dimensions[0]
+		height := scene.Dimensions[1]
+
+		// Reduce the ratio and look up its category for the background
+		g := gcd(width, height)
+		ratioStr := fmt.Sprintf("%d:%d", width/g, height/g)
+		category := b.Config.GetCategoryForRatio(ratioStr)
+		if !b.Filters.ShouldIncludeRatioCategory(category) {
+			continue
+		}
+
+		sizeCategory := b.getSizeCategoryForDimension(max(width, height))
+		if !b.Filters.ShouldIncludeSizeCategory(sizeCategory) {
+			continue
+		}
+
+		for formatName, format := range b.Config.Formats {
+			if !b.Filters.ShouldIncludeFormat(formatName) || format.VariantsOnly {
+				continue
+			}
+
+			// Use first quality
+			if len(format.Qualities) == 0 {
+				continue
+			}
+			quality := format.Qualities[0]
+
+			filename := fmt.Sprintf("%s_%dx%d_%s_q%d%s",
+				scene.Name,
+				width, height,
+				strings.ToLower(formatName),
+				quality,
+				format.Extension,
+			)
+
+			spec := generator.ImageSpec{
+				Width:        width,
+				Height:       height,
+				Ratio:        ratioStr,
+				RatioDecimal: float64(width) / float64(height),
+				Format:       strings.ToUpper(formatName),
+				Quality:      quality,
+				SizeCategory: cases.Title(language.English).String(sizeCategory),
+				Category:     category,
+				OutputPath:   filepath.Join(b.BaseDir, "scenes", filename),
+				Filename:     filename,
+				MimeType:     format.MimeType,
+				Scene: &generator.Scene{
+					Seed:    scene.Seed,
+					Objects: scene.Objects,
+					Classes: scene.Classes,
+				},
 			}
 
 			specs = append(specs, spec)
@@ -307,6 +535,144 @@ func (b *SpecBuilder) buildEdgeCaseSpecs() ([]generator.ImageSpec, error) {
 	return specs, nil
 }
 
+// buildVariantSpecs builds specs for format variants
+func (b *SpecBuilder) buildVariantSpecs() ([]generator.ImageSpec, error) {
+	var specs []generator.ImageSpec
+
+	for formatName, format := range b.Config.Formats {
+		if !b.Filters.ShouldIncludeFormat(formatName) || len(format.Variants) == 0 {
+			continue
+		}
+
+		encoder, ok := generator.LookupEncoder(formatName)
+		if !ok {
+			return nil, fmt.Errorf("format %s has no registered encoder", formatName)
+		}
+		if err := validateVariants(formatName, encoder, format.Variants); err != nil {
+			return nil, err
+		}
+
+		for _, variant := range format.Variants {
+			spec := b.variantSpec(formatName, format, variant)
+
+			// Check if ratio and size categories should be included
+			if !b.Filters.ShouldIncludeRatioCategory(spec.Category) {
+				continue
+			}
+			if !b.Filters.ShouldIncludeSizeCategory(strings.ToLower(spec.SizeCategory)) {
+				continue
+			}
+
+			specs = append(specs, spec)
+		}
+	}
+
+	return specs, nil
+}
+
+// BuildVariantSpec validates a single variant and builds its spec, ignoring
+// filters. The format does not have to be in the config as long as an
+// encoder is registered for it, which allows one-off fixtures outside the
+// configured sets.
+func (b *SpecBuilder) BuildVariantSpec(formatName string, variant FormatVariant) (generator.ImageSpec, error) {
+	encoder, ok := generator.LookupEncoder(formatName)
+	if !ok {
+		return generator.ImageSpec{}, fmt.Errorf("format %s has no registered encoder", formatName)
+	}
+	if err := validateVariants(formatName, encoder, []FormatVariant{variant}); err != nil {
+		return generator.ImageSpec{}, err
+	}
+
+	format, ok := b.Config.Formats[formatName]
+	if !ok {
+		format = Format{Qualities: []int{defaultVariantQuality}}
+	}
+	if format.Extension == "" {
+		format.Extension = encoder.Extensions()[0]
+	}
+
+	return b.variantSpec(formatName, format, variant), nil
+}
+
+// defaultVariantQuality is used for variants of formats without configured qualities
+const defaultVariantQuality = 90
+
+// variantSpec builds the spec of a validated variant
+func (b *SpecBuilder) variantSpec(formatName string, format Format, variant FormatVariant) generator.ImageSpec {
+	width := variant.Dimensions[0]
+	height := variant.Dimensions[1]
+
+	// Calculate simplified ratio
+	ratioDecimal := float64(width) / float64(height)
+	gcd := gcd(width, height)
+	ratioStr := fmt.Sprintf("%d:%d", width/gcd, height/gcd)
+
+	// Determine ratio and size categories
+	category := b.Config.GetCategoryForRatio(ratioStr)
+	maxDim := width
+	if height > maxDim {
+		maxDim = height
+	}
+	sizeCategory := b.getSizeCategoryForDimension(maxDim)
+
+	// Use variant quality, falling back to the first format quality
+	quality := variant.Quality
+	if quality == 0 && len(format.Qualities) > 0 {
+		quality = format.Qualities[0]
+	}
+	if quality == 0 {
+		quality = defaultVariantQuality
+	}
+
+	// Build filename
+	filename := fmt.Sprintf("%s_%dx%d_%s_q%d%s",
+		variant.Name,
+		width, height,
+		strings.ToLower(formatName),
+		quality,
+		format.Extension,
+	)
+
+	// Build output path
+	outputPath := filepath.Join(
+		b.BaseDir,
+		"variants",
+		strings.ToLower(formatName),
+		filename,
+	)
+
+	return generator.ImageSpec{
+		Width:        width,
+		Height:       height,
+		Ratio:        ratioStr,
+		RatioDecimal: ratioDecimal,
+		Format:       strings.ToUpper(formatName),
+		Quality:      quality,
+		SizeCategory: cases.Title(language.English).String(sizeCategory),
+		Category:     category,
+		OutputPath:   outputPath,
+		Filename:     filename,
+		MimeType:     format.MimeType,
+		Variant:      variant.Name,
+		Options: generator.EncodeOptions{
+			Lossless:     variant.Lossless,
+			NearLossless: variant.NearLossless,
+			Alpha:        variant.Alpha,
+			Interlaced:   variant.Interlaced,
+			Metadata:     variant.Metadata,
+			Frames:       variant.Frames,
+			FrameDelay:   variant.FrameDelayMs,
+			LoopCount:    variant.LoopCount,
+			DisposeOp:    variant.DisposeOp,
+			BlendOp:      variant.BlendOp,
+			Compression:  variant.Compression,
+			Pages:        variant.Pages,
+
+			SeparateDefault: variant.SeparateDefaultImage,
+		},
+	}
+}
+
 // getSizeCategoryForDimension returns the size category for a given dimension
 func (b *SpecBuilder) getSizeCategoryForDimension(dim int) string {
 	switch {
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"image"
 	"os"
 	"strconv"
 	"strings"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
 )
 
 // Config represents the complete configuration
@@ -16,6 +21,15 @@ type Config struct {
 	Formats   map[string]Format     `json:"formats"`
 	Targets   map[string]Target     `json:"targets"`
 	EdgeCases []EdgeCase            `json:"edge_cases"`
+
+	// FocalPoints lists images with an off-center subject for smart-crop tests
+	FocalPoints []FocalPointCase `json:"focal_points,omitempty"`
+
+	// Scenes lists images of seeded random objects with exported annotations
+	Scenes []SceneCase `json:"scenes,omitempty"`
+
+	// Overlays lists the overlay layers drawn on every image, e.g. "rulers"
+	Overlays []string `json:"overlays,omitempty"`
 }
 
 // Preset represents a ratio preset category
@@ -32,9 +46,37 @@ type SizeConfig struct {
 
 // Format represents an image format specification
 type Format struct {
-	Qualities []int  `json:"qualities"`
-	MimeType  string `json:"mime_type"`
-	Extension string `json:"extension"`
+	Qualities []int           `json:"qualities"`
+	MimeType  string          `json:"mime_type"`
+	Extension string          `json:"extension"`
+	Variants  []FormatVariant `json:"variants,omitempty"`
+
+	// VariantsOnly skips the format in ratio, target and edge case images
+	VariantsOnly bool `json:"variants_only,omitempty"`
+}
+
+// FormatVariant represents an encoder variant of a format, generated once at
+// fixed dimensions (e.g. lossless or animated WebP)
+type FormatVariant struct {
+	Name         string   `json:"name"`
+	Dimensions   []int    `json:"dimensions"`
+	Quality      int      `json:"quality,omitempty"`
+	Lossless     bool     `json:"lossless,omitempty"`
+	NearLossless int      `json:"near_lossless,omitempty"`
+	Alpha        bool     `json:"alpha,omitempty"`
+	Interlaced   bool     `json:"interlaced,omitempty"`
+	Metadata     []string `json:"metadata,omitempty"`
+	Frames       int      `json:"frames,omitempty"`
+	FrameDelayMs int      `json:"frame_delay_ms,omitempty"`
+	LoopCount    int      `json:"loop_count,omitempty"`
+	DisposeOp    string   `json:"dispose_op,omitempty"`
+	BlendOp      string   `json:"blend_op,omitempty"`
+	Compression  string   `json:"compression,omitempty"`
+	Pages        int      `json:"pages,omitempty"`
+	Description  string   `json:"description"`
+
+	// SeparateDefaultImage adds a still image outside the animation (APNG)
+	SeparateDefaultImage bool `json:"separate_default_image,omitempty"`
 }
 
 // Target represents a platform target specification
@@ -43,6 +85,48 @@ type Target struct {
 	Dimensions  []int  `json:"dimensions"`
 	Ratio       string `json:"ratio"`
 	Description string `json:"description"`
+
+	// SafeAreas lists the regions left clear of platform UI, e.g. the
+	// caption and buttons drawn over Stories
+	SafeAreas []SafeArea `json:"safe_areas,omitempty"`
+}
+
+// SafeArea is a named region of a target that the platform does not cover
+// with UI. It is given either as insets from the edges or as a rectangle.
+type SafeArea struct {
+	Name        string          `json:"name"`
+	Insets      *SafeAreaInsets `json:"insets,omitempty"`
+	Rect        []int           `json:"rect,omitempty"` // x, y, width, height
+	Description string          `json:"description,omitempty"`
+}
+
+// SafeAreaInsets are the distances in pixels from each edge of a target to
+// its safe area
+type SafeAreaInsets struct {
+	Top    int `json:"top"`
+	Right  int `json:"right"`
+	Bottom int `json:"bottom"`
+	Left   int `json:"left"`
+}
+
+// Bounds returns the safe area within a width x height target. The
+// rectangle is not canonicalized, so insets that overlap or a negative
+// size give an empty rectangle.
+func (a SafeArea) Bounds(width, height int) image.Rectangle {
+	switch {
+	case a.Insets != nil:
+		return image.Rectangle{
+			Min: image.Pt(a.Insets.Left, a.Insets.Top),
+			Max: image.Pt(width-a.Insets.Right, height-a.Insets.Bottom),
+		}
+	case len(a.Rect) == 4:
+		return image.Rectangle{
+			Min: image.Pt(a.Rect[0], a.Rect[1]),
+			Max: image.Pt(a.Rect[0]+a.Rect[2], a.Rect[1]+a.Rect[3]),
+		}
+	default:
+		return image.Rectangle{}
+	}
 }
 
 // EdgeCase represents an edge case test scenario
@@ -52,6 +136,30 @@ type EdgeCase struct {
 	Description string `json:"description"`
 }
 
+// FocalPointCase represents an image with a high-salience subject at a
+// named position (e.g. "top-left-third") or normalized coordinates
+// (e.g. "0.8,0.25")
+type FocalPointCase struct {
+	Name        string `json:"name"`
+	Position    string `json:"position"`
+	Dimensions  []int  `json:"dimensions"`
+	Description string `json:"description"`
+}
+
+// SceneCase represents a synthetic scene of randomly placed objects of
+// known classes. The seed makes the placement reproducible.
+type SceneCase struct {
+	Name        string   `json:"name"`
+	Dimensions  []int    `json:"dimensions"`
+	Seed        int64    `json:"seed"`
+	Objects     int      `json:"objects"`
+	Classes     []string `json:"classes,omitempty"` // all classes if empty
+	Description string   `json:"description"`
+}
+
+// MaxSceneObjects is the largest number of objects in a scene
+const MaxSceneObjects = 50
+
 // LoadConfig loads configuration from file or returns default
 func LoadConfig(configPath string) (*Config, error) {
 	var cfg Config
@@ -78,9 +186,23 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
+	// Fill in format details from the registered encoders
+	cfg.resolveFormats()
+
 	return &cfg, nil
 }
 
+// SHA256 returns the SHA-256 of the effective configuration, i.e. after
+// defaults from the encoders are filled in, encoded as canonical JSON
+func (c *Config) SHA256() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal config: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
 	if c.Version == "" {
@@ -108,29 +230,251 @@ func (c *Config) Validate() error {
 		}
 	}
 
+	// Validate formats resolve to registered encoders and their variants
+	for formatName, format := range c.Formats {
+		encoder, ok := generator.LookupEncoder(formatName)
+		if !ok {
+			return fmt.Errorf("format %s has no registered encoder (available: %s)",
+				formatName, strings.Join(generator.EncoderNames(), ", "))
+		}
+		if err := validateVariants(formatName, encoder, format.Variants); err != nil {
+			return err
+		}
+	}
+
 	// Validate targets
 	for targetName, target := range c.Targets {
-		if len(target.Dimensions) != 2 {
-			return fmt.Errorf("target %s must have exactly 2 dimensions", targetName)
+		if err := validateDimensions("target "+targetName, target.Dimensions); err != nil {
+			return err
 		}
-		if target.Dimensions[0] <= 0 || target.Dimensions[1] <= 0 {
-			return fmt.Errorf("target %s dimensions must be positive", targetName)
+		if err := validateSafeAreas(targetName, target); err != nil {
+			return err
+		}
+	}
+
+	// Validate overlay layers
+	for _, overlay := range c.Overlays {
+		if !generator.IsValidOverlay(overlay) {
+			return fmt.Errorf("unknown overlay %s (available: %s)",
+				overlay, strings.Join(generator.OverlayNames(), ", "))
 		}
 	}
 
 	// Validate edge cases
 	for _, edgeCase := range c.EdgeCases {
-		if len(edgeCase.Dimensions) != 2 {
-			return fmt.Errorf("edge case %s must have exactly 2 dimensions", edgeCase.Name)
+		if err := validateDimensions("edge case "+edgeCase.Name, edgeCase.Dimensions); err != nil {
+			return err
 		}
-		if edgeCase.Dimensions[0] <= 0 || edgeCase.Dimensions[1] <= 0 {
-			return fmt.Errorf("edge case %s dimensions must be positive", edgeCase.Name)
+	}
+
+	// Validate focal point cases
+	seen := make(map[string]bool)
+	for _, focal := range c.FocalPoints {
+		if focal.Name == "" {
+			return fmt.Errorf("focal point case without a name")
+		}
+		if seen[focal.Name] {
+			return fmt.Errorf("duplicate focal point case %s", focal.Name)
+		}
+		seen[focal.Name] = true
+
+		if err := validateDimensions("focal point case "+focal.Name, focal.Dimensions); err != nil {
+			return err
+		}
+		if _, err := generator.ParseFocalPoint(focal.Position); err != nil {
+			return fmt.Errorf("focal point case %s: %w", focal.Name, err)
+		}
+	}
+
+	// Validate scenes
+	seen = make(map[string]bool)
+	for _, scene := range c.Scenes {
+		if scene.Name == "" {
+			return fmt.Errorf("scene without a name")
+		}
+		if seen[scene.Name] {
+			return fmt.Errorf("duplicate scene %s", scene.Name)
+		}
+		seen[scene.Name] = true
+
+		if err := validateDimensions("scene "+scene.Name, scene.Dimensions); err != nil {
+			return err
+		}
+		if scene.Objects < 1 || scene.Objects > MaxSceneObjects {
+			return fmt.Errorf("scene %s objects must be between 1 and %d", scene.Name, MaxSceneObjects)
+		}
+		if err := validateSceneClasses(scene); err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// validateDimensions checks that subject has a positive width and height
+func validateDimensions(subject string, dimensions []int) error {
+	if len(dimensions) != 2 {
+		return fmt.Errorf("%s must have exactly 2 dimensions", subject)
+	}
+	if dimensions[0] <= 0 || dimensions[1] <= 0 {
+		return fmt.Errorf("%s dimensions must be positive", subject)
+	}
+	return nil
+}
+
+// validateSceneClasses checks that every class of a scene is registered
+func validateSceneClasses(scene SceneCase) error {
+	for _, class := range scene.Classes {
+		if !generator.IsValidSceneClass(class) {
+			return fmt.Errorf("scene %s has unknown class %s (available: %s)",
+				scene.Name, class, strings.Join(generator.SceneClassNames(), ", "))
+		}
+	}
+	return nil
+}
+
+// validateSafeAreas checks the safe areas of a single target
+func validateSafeAreas(targetName string, target Target) error {
+	bounds := image.Rect(0, 0, target.Dimensions[0], target.Dimensions[1])
+	seen := make(map[string]bool)
+	for _, area := range target.SafeAreas {
+		if area.Name == "" {
+			return fmt.Errorf("target %s has a safe area without a name", targetName)
+		}
+		if seen[area.Name] {
+			return fmt.Errorf("target %s has duplicate safe area %s", targetName, area.Name)
+		}
+		seen[area.Name] = true
+
+		if (area.Insets == nil) == (area.Rect == nil) {
+			return fmt.Errorf("safe area %s/%s must set exactly one of insets or rect", targetName, area.Name)
+		}
+		if area.Rect != nil && len(area.Rect) != 4 {
+			return fmt.Errorf("safe area %s/%s rect must be [x, y, width, height]", targetName, area.Name)
+		}
+		r := area.Bounds(target.Dimensions[0], target.Dimensions[1])
+		if r.Empty() || !r.In(bounds) {
+			return fmt.Errorf("safe area %s/%s must be a non-empty region inside %dx%d",
+				targetName, area.Name, bounds.Dx(), bounds.Dy())
+		}
+	}
+
+	return nil
+}
+
+// MaxVariantNameLength is the longest variant name. The name is appended to
+// the QR payload, and at this length a payload with five-digit dimensions
+// still fits the largest supported QR version.
+const MaxVariantNameLength = 48
+
+// validateVariants checks the variants of a single format
+func validateVariants(formatName string, encoder generator.Encoder, variants []FormatVariant) error {
+	seen := make(map[string]bool)
+	for _, variant := range variants {
+		if variant.Name == "" {
+			return fmt.Errorf("format %s has a variant without a name", formatName)
+		}
+		if seen[variant.Name] {
+			return fmt.Errorf("format %s has duplicate variant %s", formatName, variant.Name)
+		}
+		seen[variant.Name] = true
+
+		if strings.Contains(variant.Name, ":") {
+			return fmt.Errorf("variant %s/%s name must not contain ':'", formatName, variant.Name)
+		}
+		if len(variant.Name) > MaxVariantNameLength {
+			return fmt.Errorf("variant %s/%s name must be at most %d bytes", formatName, variant.Name, MaxVariantNameLength)
+		}
+		if err := validateDimensions("variant "+formatName+"/"+variant.Name, variant.Dimensions); err != nil {
+			return err
+		}
+		if variant.Quality < 0 || variant.Quality > 100 {
+			return fmt.Errorf("variant %s/%s quality must be between 0 and 100", formatName, variant.Name)
+		}
+		if variant.NearLossless < 0 || variant.NearLossless > 99 {
+			return fmt.Errorf("variant %s/%s near_lossless must be between 1 and 99, or 0 to disable", formatName, variant.Name)
+		}
+		for _, kind := range variant.Metadata {
+			if !generator.IsValidMetadataKind(kind) {
+				return fmt.Errorf("variant %s/%s has unknown metadata %s", formatName, variant.Name, kind)
+			}
+		}
+		if variant.Frames < 0 || variant.FrameDelayMs < 0 || variant.LoopCount < 0 {
+			return fmt.Errorf("variant %s/%s animation settings must not be negative", formatName, variant.Name)
+		}
+		if variant.FrameDelayMs > generator.MaxFrameDelay {
+			return fmt.Errorf("variant %s/%s frame_delay_ms must be at most %d", formatName, variant.Name, generator.MaxFrameDelay)
+		}
+		if variant.LoopCount > generator.MaxLoopCount {
+			return fmt.Errorf("variant %s/%s loop_count must be at most %d", formatName, variant.Name, generator.MaxLoopCount)
+		}
+		if !generator.IsValidDisposeOp(variant.DisposeOp) {
+			return fmt.Errorf("variant %s/%s has unknown dispose_op %s", formatName, variant.Name, variant.DisposeOp)
+		}
+		if !generator.IsValidBlendOp(variant.BlendOp) {
+			return fmt.Errorf("variant %s/%s has unknown blend_op %s", formatName, variant.Name, variant.BlendOp)
+		}
+		if !generator.IsValidTIFFCompression(variant.Compression) {
+			return fmt.Errorf("variant %s/%s has unknown compression %s", formatName, variant.Name, variant.Compression)
+		}
+		if variant.Pages < 0 {
+			return fmt.Errorf("variant %s/%s pages must not be negative", formatName, variant.Name)
+		}
+		for _, option := range variant.setOptions() {
+			if !generator.SupportsOption(encoder, option) {
+				return fmt.Errorf("variant %s/%s sets %s, which the %s encoder does not support",
+					formatName, variant.Name, option, encoder.Name())
+			}
+		}
+	}
+
+	return nil
+}
+
+// setOptions returns the config keys of the encoder options a variant sets
+func (v FormatVariant) setOptions() []string {
+	var options []string
+	add := func(name string, set bool) {
+		if set {
+			options = append(options, name)
+		}
+	}
+
+	add("lossless", v.Lossless)
+	add("near_lossless", v.NearLossless != 0)
+	add("alpha", v.Alpha)
+	add("interlaced", v.Interlaced)
+	add("metadata", len(v.Metadata) > 0)
+	add("frames", v.Frames != 0)
+	add("frame_delay_ms", v.FrameDelayMs != 0)
+	add("loop_count", v.LoopCount != 0)
+	add("dispose_op", v.DisposeOp != "")
+	add("blend_op", v.BlendOp != "")
+	add("separate_default_image", v.SeparateDefaultImage)
+	add("compression", v.Compression != "")
+	add("pages", v.Pages != 0)
+
+	return options
+}
+
+// resolveFormats fills in missing extensions and MIME types from the
+// registered encoders. Formats must have been validated.
+func (c *Config) resolveFormats() {
+	for formatName, format := range c.Formats {
+		encoder, ok := generator.LookupEncoder(formatName)
+		if !ok {
+			continue
+		}
+		if format.Extension == "" {
+			format.Extension = encoder.Extensions()[0]
+		}
+		if format.MimeType == "" {
+			format.MimeType = encoder.MimeType()
+		}
+		c.Formats[formatName] = format
+	}
+}
+
 // RatioInfo represents parsed ratio information
 type RatioInfo struct {
 	Ratio       string
@@ -1,9 +1,14 @@
 package config
 
 import (
+	"image"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
 )
 
 func TestParseRatio(t *testing.T) {
@@ -251,6 +256,9 @@ func TestLoadConfig_CustomFile(t *testing.T) {
 				"qualities": [85],
 				"mime_type": "image/jpeg",
 				"extension": ".jpg"
+			},
+			"tif": {
+				"qualities": [100]
 			}
 		},
 		"targets": {},
@@ -273,6 +281,11 @@ func TestLoadConfig_CustomFile(t *testing.T) {
 	if _, ok := cfg.Presets["test"]; !ok {
 		t.Error("LoadConfig() missing 'test' preset")
 	}
+
+	// Missing format details are filled in from the registered encoder
+	if tif := cfg.Formats["tif"]; tif.Extension != ".tiff" || tif.MimeType != "image/tiff" {
+		t.Errorf("LoadConfig() tif format = %q/%q, want .tiff/image/tiff", tif.Extension, tif.MimeType)
+	}
 }
 
 func TestLoadConfig_InvalidFile(t *testing.T) {
@@ -347,6 +360,516 @@ func TestConfigValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid format variants",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants: []FormatVariant{
+						{Name: "lossless", Dimensions: []int{100, 100}, Lossless: true},
+						{Name: "animated", Dimensions: []int{100, 100}, Frames: 5, Metadata: []string{"exif", "XMP"}},
+					},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "variant name with colon",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: "a:b", Dimensions: []int{100, 100}}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "variant name at maximum length",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: strings.Repeat("v", MaxVariantNameLength), Dimensions: []int{100, 100}}},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "variant name too long",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: strings.Repeat("v", MaxVariantNameLength+1), Dimensions: []int{100, 100}}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "frame delay at maximum",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"apng": {
+					Qualities: []int{100},
+					Variants:  []FormatVariant{{Name: "animated", Dimensions: []int{100, 100}, Frames: 2, FrameDelayMs: generator.MaxFrameDelay}},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "frame delay too long",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"apng": {
+					Qualities: []int{100},
+					Variants:  []FormatVariant{{Name: "animated", Dimensions: []int{100, 100}, Frames: 2, FrameDelayMs: generator.MaxFrameDelay + 1}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "loop count at maximum",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: "v", Dimensions: []int{100, 100}, Frames: 2, LoopCount: generator.MaxLoopCount}},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "loop count too large",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: "v", Dimensions: []int{100, 100}, Frames: 2, LoopCount: generator.MaxLoopCount + 1}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "near lossless at maximum",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: "v", Dimensions: []int{100, 100}, NearLossless: 99}},
+				}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "near lossless too large",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: "v", Dimensions: []int{100, 100}, NearLossless: 100}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate variant name",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants: []FormatVariant{
+						{Name: "lossless", Dimensions: []int{100, 100}},
+						{Name: "lossless", Dimensions: []int{200, 200}},
+					},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "variant without dimensions",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: "lossless"}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "variant with unknown blend op",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"apng": {
+					Qualities: []int{100},
+					Variants:  []FormatVariant{{Name: "anim", Dimensions: []int{100, 100}, Frames: 3, BlendOp: "multiply"}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "format without registered encoder",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"heic": {Qualities: []int{80}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "variant option unsupported by encoder",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"gif": {
+					Qualities: []int{100},
+					Variants:  []FormatVariant{{Name: "lossless", Dimensions: []int{100, 100}, Lossless: true}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "variant with unknown compression",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"tiff": {
+					Qualities: []int{100},
+					Variants:  []FormatVariant{{Name: "lzw", Dimensions: []int{100, 100}, Compression: "lzw"}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "variant with unknown metadata",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"webp": {
+					Qualities: []int{82},
+					Variants:  []FormatVariant{{Name: "meta", Dimensions: []int{100, 100}, Metadata: []string{"iptc"}}},
+				}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "known overlay",
+			config: Config{
+				Version:  "1.0.0",
+				Presets:  map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:    map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:  map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Overlays: []string{"rulers"},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown overlay",
+			config: Config{
+				Version:  "1.0.0",
+				Presets:  map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:    map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:  map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Overlays: []string{"watermark"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "safe area insets",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Name: "ui", Insets: &SafeAreaInsets{Top: 250, Bottom: 250}}}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "safe area rect",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Name: "ui", Rect: []int{60, 130, 880, 1310}}}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "safe area without name",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Rect: []int{0, 0, 100, 100}}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate safe area",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Name: "ui", Rect: []int{0, 0, 100, 100}}, {Name: "ui", Rect: []int{0, 0, 50, 50}}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "safe area with insets and rect",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Name: "ui", Insets: &SafeAreaInsets{Top: 10}, Rect: []int{0, 0, 100, 100}}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "safe area without region",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Name: "ui"}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "safe area insets overlap",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Name: "ui", Insets: &SafeAreaInsets{Left: 600, Right: 600}}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "safe area rect outside target",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Name: "ui", Rect: []int{1000, 0, 200, 100}}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "safe area rect negative size",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Targets: map[string]Target{"STORY": {Dimensions: []int{1080, 1920}, Ratio: "9:16", SafeAreas: []SafeArea{{Name: "ui", Rect: []int{500, 500, -100, 100}}}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "named focal point",
+			config: Config{
+				Version:     "1.0.0",
+				Presets:     map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:       map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:     map[string]Format{"jpeg": {Qualities: []int{82}}},
+				FocalPoints: []FocalPointCase{{Name: "a", Position: "top-left-third", Dimensions: []int{1600, 900}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "focal point coordinates",
+			config: Config{
+				Version:     "1.0.0",
+				Presets:     map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:       map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:     map[string]Format{"jpeg": {Qualities: []int{82}}},
+				FocalPoints: []FocalPointCase{{Name: "a", Position: "0.85,0.2", Dimensions: []int{1200, 1200}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown focal point",
+			config: Config{
+				Version:     "1.0.0",
+				Presets:     map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:       map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:     map[string]Format{"jpeg": {Qualities: []int{82}}},
+				FocalPoints: []FocalPointCase{{Name: "a", Position: "middle", Dimensions: []int{1200, 1200}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "focal point outside image",
+			config: Config{
+				Version:     "1.0.0",
+				Presets:     map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:       map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:     map[string]Format{"jpeg": {Qualities: []int{82}}},
+				FocalPoints: []FocalPointCase{{Name: "a", Position: "1.2,0.5", Dimensions: []int{1200, 1200}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "focal point without name",
+			config: Config{
+				Version:     "1.0.0",
+				Presets:     map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:       map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:     map[string]Format{"jpeg": {Qualities: []int{82}}},
+				FocalPoints: []FocalPointCase{{Position: "center", Dimensions: []int{1200, 1200}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate focal point",
+			config: Config{
+				Version:     "1.0.0",
+				Presets:     map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:       map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:     map[string]Format{"jpeg": {Qualities: []int{82}}},
+				FocalPoints: []FocalPointCase{{Name: "a", Position: "center", Dimensions: []int{100, 100}}, {Name: "a", Position: "center", Dimensions: []int{200, 200}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "focal point without dimensions",
+			config: Config{
+				Version:     "1.0.0",
+				Presets:     map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:       map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats:     map[string]Format{"jpeg": {Qualities: []int{82}}},
+				FocalPoints: []FocalPointCase{{Name: "a", Position: "center"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scene",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Scenes:  []SceneCase{{Name: "a", Dimensions: []int{1600, 900}, Seed: 1, Objects: 8}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "scene with classes",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Scenes:  []SceneCase{{Name: "a", Dimensions: []int{1600, 900}, Objects: 4, Classes: []string{"star", "plus"}}},
+			},
+			wantErr: false,
+		},
+		{
+			name: "scene without name",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Scenes:  []SceneCase{{Dimensions: []int{1600, 900}, Objects: 4}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate scene",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Scenes:  []SceneCase{{Name: "a", Dimensions: []int{100, 100}, Objects: 1}, {Name: "a", Dimensions: []int{200, 200}, Objects: 1}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scene without dimensions",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Scenes:  []SceneCase{{Name: "a", Objects: 4}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scene without objects",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Scenes:  []SceneCase{{Name: "a", Dimensions: []int{1600, 900}}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scene with too many objects",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Scenes:  []SceneCase{{Name: "a", Dimensions: []int{1600, 900}, Objects: MaxSceneObjects + 1}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "scene with unknown class",
+			config: Config{
+				Version: "1.0.0",
+				Presets: map[string]Preset{"test": {Ratios: []string{"1:1"}}},
+				Sizes:   map[string]SizeConfig{"test": {BaseSizes: []int{100}}},
+				Formats: map[string]Format{"jpeg": {Qualities: []int{82}}},
+				Scenes:  []SceneCase{{Name: "a", Dimensions: []int{1600, 900}, Objects: 4, Classes: []string{"cloud"}}},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -387,3 +910,295 @@ func TestGetCategoryForRatio(t *testing.T) {
 	}
 }
 
+func TestSpecBuilder_VariantNameFitsQR(t *testing.T) {
+	cfg := &Config{Version: "1.0.0", Formats: map[string]Format{}}
+	variant := FormatVariant{
+		Name:       strings.Repeat("v", MaxVariantNameLength),
+		Dimensions: []int{99999, 99999},
+		Quality:    100,
+	}
+
+	spec, err := NewSpecBuilder(cfg, NewFilters(nil, nil, nil), "/out").BuildVariantSpec("apng", variant)
+	if err != nil {
+		t.Fatalf("BuildVariantSpec() error = %v", err)
+	}
+	payload, err := generator.SpecPayload(spec)
+	if err != nil {
+		t.Fatalf("SpecPayload() error = %v", err)
+	}
+	if _, err := generator.EncodeQR(payload); err != nil {
+		t.Errorf("EncodeQR(%d bytes) error = %v", len(payload), err)
+	}
+	label, err := generator.ParseSpecPayload(payload)
+	if err != nil || label.Variant != variant.Name {
+		t.Errorf("ParseSpecPayload() = %+v, %v, want variant %q", label, err, variant.Name)
+	}
+}
+
+func TestSpecBuilder_Variants(t *testing.T) {
+	cfg := &Config{
+		Version: "1.0.0",
+		Presets: map[string]Preset{"platform": {Ratios: []string{"1:1"}}},
+		Sizes:   map[string]SizeConfig{"medium": {BaseSizes: []int{1000}}},
+		Formats: map[string]Format{
+			"apng": {
+				Qualities:    []int{100},
+				Extension:    ".png",
+				VariantsOnly: true,
+				Variants: []FormatVariant{
+					{Name: "animated", Dimensions: []int{1000, 1000}, Frames: 4, SeparateDefaultImage: true},
+				},
+			},
+		},
+	}
+
+	specs, err := NewSpecBuilder(cfg, NewFilters(nil, nil, nil), "/out").BuildSpecs()
+	if err != nil {
+		t.Fatalf("BuildSpecs() error = %v", err)
+	}
+
+	if len(specs) != 1 {
+		t.Fatalf("BuildSpecs() returned %d specs, want only the variant", len(specs))
+	}
+
+	spec := specs[0]
+	wantPath := filepath.Join("/out", "variants", "apng", "animated_1000x1000_apng_q100.png")
+	if spec.OutputPath != wantPath {
+		t.Errorf("OutputPath = %q, want %q", spec.OutputPath, wantPath)
+	}
+	if spec.Category != "platform" || spec.Ratio != "1:1" {
+		t.Errorf("Category/Ratio = %q/%q, want platform/1:1", spec.Category, spec.Ratio)
+	}
+	if spec.Options.Frames != 4 || !spec.Options.SeparateDefault {
+		t.Errorf("Options = %+v, want 4 frames with separate default image", spec.Options)
+	}
+}
+
+func TestSpecBuilder_Overlays(t *testing.T) {
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	specs, err := NewSpecBuilder(cfg, NewFilters([]string{"platform"}, []string{"tiny"}, []string{"png"}), "/out").BuildSpecs()
+	if err != nil {
+		t.Fatalf("BuildSpecs() error = %v", err)
+	}
+	for _, spec := range specs {
+		if len(spec.Overlays) != 0 {
+			t.Fatalf("%s has overlays %v by default", spec.Filename, spec.Overlays)
+		}
+	}
+
+	cfg.Overlays = []string{"rulers"}
+	specs, err = NewSpecBuilder(cfg, NewFilters([]string{"platform"}, []string{"tiny"}, []string{"png"}), "/out").BuildSpecs()
+	if err != nil {
+		t.Fatalf("BuildSpecs() error = %v", err)
+	}
+	for _, spec := range specs {
+		if len(spec.Overlays) != 1 || spec.Overlays[0] != "rulers" {
+			t.Errorf("%s overlays = %v, want [rulers]", spec.Filename, spec.Overlays)
+		}
+	}
+}
+
+func TestConfig_SHA256(t *testing.T) {
+	a, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashA, err := a.SHA256()
+	if err != nil {
+		t.Fatalf("SHA256() error = %v", err)
+	}
+	hashB, _ := b.SHA256()
+	if hashA != hashB || len(hashA) != 64 {
+		t.Errorf("SHA256() = %s and %s, want equal SHA-256s", hashA, hashB)
+	}
+
+	b.Version = "2.0.0"
+	if hashB, _ = b.SHA256(); hashA == hashB {
+		t.Error("SHA256() unchanged after editing the config")
+	}
+}
+
+func TestSpecBuilder_CropGuides(t *testing.T) {
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	cfg.Overlays = []string{"crop-guides"}
+
+	specs, err := NewSpecBuilder(cfg, NewFilters([]string{"common"}, []string{"tiny"}, []string{"png"}), "/out").BuildSpecs()
+	if err != nil {
+		t.Fatalf("BuildSpecs() error = %v", err)
+	}
+
+	want := []generator.CropGuide{
+		{Label: "9:16 IG_STORY, TIKTOK_9_16", RatioWidth: 9, RatioHeight: 16},
+		{Label: "2:3 PINTEREST_2_3", RatioWidth: 2, RatioHeight: 3},
+		{Label: "4:5 IG_FEED_4_5", RatioWidth: 4, RatioHeight: 5},
+		{Label: "1:1 IG_FEED_1_1, LI_1_1", RatioWidth: 1, RatioHeight: 1},
+		{Label: "1.91:1 LI_1_91_1", RatioWidth: 300, RatioHeight: 157},
+	}
+	for _, spec := range specs {
+		isRatio := strings.Contains(filepath.ToSlash(spec.OutputPath), "/ratios/")
+		if !isRatio {
+			if len(spec.CropGuides) != 0 {
+				t.Errorf("%s is not a ratio image but has crop guides", spec.Filename)
+			}
+			continue
+		}
+		if !reflect.DeepEqual(spec.CropGuides, want) {
+			t.Fatalf("%s crop guides = %+v, want %+v", spec.Filename, spec.CropGuides, want)
+		}
+	}
+}
+
+func TestSpecBuilder_SafeAreas(t *testing.T) {
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	specs, err := NewSpecBuilder(cfg, NewFilters([]string{"platform"}, []string{"large"}, []string{"jpeg"}), "/out").BuildSpecs()
+	if err != nil {
+		t.Fatalf("BuildSpecs() error = %v", err)
+	}
+
+	want := map[string][]generator.SafeArea{
+		"IG_STORY":    {{Name: "ui", Rect: image.Rect(0, 250, 1080, 1670)}},
+		"TIKTOK_9_16": {{Name: "ui", Rect: image.Rect(60, 130, 940, 1440)}},
+	}
+	found := 0
+	for _, spec := range specs {
+		target := strings.SplitN(spec.Filename, "_1080x1920", 2)[0]
+		if areas, ok := want[target]; ok {
+			found++
+			if !reflect.DeepEqual(spec.SafeAreas, areas) {
+				t.Errorf("%s safe areas = %+v, want %+v", spec.Filename, spec.SafeAreas, areas)
+			}
+		} else if len(spec.SafeAreas) != 0 {
+			t.Errorf("%s has safe areas %+v", spec.Filename, spec.SafeAreas)
+		}
+	}
+	if found != len(want) {
+		t.Errorf("found %d targets with safe areas, want %d", found, len(want))
+	}
+}
+
+func TestSpecBuilder_FocalPoints(t *testing.T) {
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	specs, err := NewSpecBuilder(cfg, NewFilters(nil, nil, []string{"jpeg"}), "/out").BuildSpecs()
+	if err != nil {
+		t.Fatalf("BuildSpecs() error = %v", err)
+	}
+
+	var focal []generator.ImageSpec
+	for _, spec := range specs {
+		isFocal := strings.Contains(filepath.ToSlash(spec.OutputPath), "/focal-points/")
+		if isFocal != (spec.FocalPoint != nil) {
+			t.Errorf("%s in focal-points/ = %v, has focal point = %v", spec.Filename, isFocal, spec.FocalPoint != nil)
+		}
+		if isFocal {
+			focal = append(focal, spec)
+		}
+	}
+	if len(focal) != len(cfg.FocalPoints) {
+		t.Fatalf("built %d focal point specs, want %d", len(focal), len(cfg.FocalPoints))
+	}
+
+	for _, spec := range focal {
+		if spec.Filename == "wide-top-left_1600x900_jpeg_q60.jpg" {
+			want := generator.FocalPoint{Name: "top-left-third", X: 1.0 / 3, Y: 1.0 / 3}
+			if *spec.FocalPoint != want || spec.Ratio != "16:9" || spec.Category != "common" {
+				t.Errorf("spec = %s %s %+v", spec.Ratio, spec.Category, *spec.FocalPoint)
+			}
+			return
+		}
+	}
+	t.Error("wide-top-left focal point spec not found")
+}
+
+func TestSpecBuilder_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  *Config
+	}{
+		{"unknown scene class", &Config{
+			Formats: map[string]Format{"png": {Qualities: []int{95}}},
+			Scenes:  []SceneCase{{Name: "a", Dimensions: []int{400, 400}, Objects: 1, Classes: []string{"cloud"}}},
+		}},
+		{"scene with one dimension", &Config{
+			Formats: map[string]Format{"png": {Qualities: []int{95}}},
+			Scenes:  []SceneCase{{Name: "a", Dimensions: []int{400}, Objects: 1}},
+		}},
+		{"scene with zero dimensions", &Config{
+			Formats: map[string]Format{"png": {Qualities: []int{95}}},
+			Scenes:  []SceneCase{{Name: "a", Dimensions: []int{0, 0}, Objects: 1}},
+		}},
+		{"focal point without dimensions", &Config{
+			Formats:     map[string]Format{"png": {Qualities: []int{95}}},
+			FocalPoints: []FocalPointCase{{Name: "a", Position: "center"}},
+		}},
+		{"variant of unregistered format", &Config{
+			Formats: map[string]Format{"jxl": {Variants: []FormatVariant{{Name: "a", Dimensions: []int{100, 100}}}}},
+		}},
+		{"invalid variant", &Config{
+			Formats: map[string]Format{"png": {Variants: []FormatVariant{{Name: "a"}}}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSpecBuilder(tt.cfg, NewFilters(nil, nil, nil), "/out").BuildSpecs(); err == nil {
+				t.Error("BuildSpecs() succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestSpecBuilder_Scenes(t *testing.T) {
+	cfg, err := LoadConfig("")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	specs, err := NewSpecBuilder(cfg, NewFilters(nil, nil, []string{"png"}), "/out").BuildSpecs()
+	if err != nil {
+		t.Fatalf("BuildSpecs() error = %v", err)
+	}
+
+	var scenes []generator.ImageSpec
+	for _, spec := range specs {
+		isScene := strings.Contains(filepath.ToSlash(spec.OutputPath), "/scenes/")
+		if isScene != (spec.Scene != nil) {
+			t.Errorf("%s in scenes/ = %v, has scene = %v", spec.Filename, isScene, spec.Scene != nil)
+		}
+		if isScene {
+			scenes = append(scenes, spec)
+		}
+	}
+	if len(scenes) != len(cfg.Scenes) {
+		t.Fatalf("built %d scene specs, want %d", len(scenes), len(cfg.Scenes))
+	}
+
+	for _, spec := range scenes {
+		if spec.Filename == "scene-tall-shapes_900x1600_png_q95.png" {
+			if spec.Scene.Seed != 3 || spec.Scene.Objects != 6 || len(spec.Scene.Classes) != 6 || spec.Ratio != "9:16" {
+				t.Errorf("spec = %s %+v", spec.Ratio, *spec.Scene)
+			}
+			return
+		}
+	}
+	t.Error("scene-tall-shapes scene spec not found")
+}
@@ -13,6 +13,9 @@ func EnsureDirectoryStructure(baseDir string) error {
 		filepath.Join(baseDir, "ratios"),
 		filepath.Join(baseDir, "targets"),
 		filepath.Join(baseDir, "edge-cases"),
+		filepath.Join(baseDir, "focal-points"),
+		filepath.Join(baseDir, "scenes"),
+		filepath.Join(baseDir, "variants"),
 	}
 
 	for _, dir := range dirs {
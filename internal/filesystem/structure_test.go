@@ -20,6 +20,8 @@ func TestEnsureDirectoryStructure(t *testing.T) {
 		filepath.Join(baseDir, "ratios"),
 		filepath.Join(baseDir, "targets"),
 		filepath.Join(baseDir, "edge-cases"),
+		filepath.Join(baseDir, "focal-points"),
+		filepath.Join(baseDir, "scenes"),
 	}
 
 	for _, dir := range expectedDirs {
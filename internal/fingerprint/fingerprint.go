@@ -0,0 +1,167 @@
+package fingerprint
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif"  // Register GIF decoder
+	_ "image/jpeg" // Register JPEG decoder
+	_ "image/png"  // Register PNG decoder
+	"math/bits"
+
+	_ "github.com/chai2010/webp" // Register WebP decoder
+	_ "golang.org/x/image/bmp"   // Register BMP decoder
+	_ "golang.org/x/image/tiff"  // Register TIFF decoder
+)
+
+// Fingerprint holds the hashes identifying an encoded image file
+type Fingerprint struct {
+	SHA256      string // Hex SHA-256 of the file bytes
+	PixelSHA256 string // Hex SHA-256 of the decoded pixels, empty if undecodable
+	DHash       string // 64-bit difference hash in hex, empty if undecodable
+	PHash       string // 64-bit DCT perceptual hash in hex, empty if undecodable
+
+	// Decoded details, not recorded in the manifest
+	Format string // Format reported by the decoder, e.g. "png" for APNG
+	Width  int
+	Height int
+}
+
+// Compute hashes an encoded image file. The file checksum is always set;
+// the pixel and perceptual hashes are only set when the data decodes, so
+// formats without a registered decoder still get a checksum.
+func Compute(data []byte) Fingerprint {
+	sum := sha256.Sum256(data)
+	fp := Fingerprint{SHA256: hex.EncodeToString(sum[:])}
+
+	img, format, err := Decode(data)
+	if err != nil {
+		return fp
+	}
+
+	// Convert once and share the pixels between all hashes
+	rgba := toRGBA(img)
+	fp.Format = format
+	fp.Width = rgba.Rect.Dx()
+	fp.Height = rgba.Rect.Dy()
+	fp.PixelSHA256 = PixelHash(rgba)
+
+	dhash, phash := PerceptualHashes(rgba)
+	fp.DHash = FormatHash(dhash)
+	fp.PHash = FormatHash(phash)
+
+	return fp
+}
+
+// Decode decodes an image in any supported format. Animated WebP files,
+// which the WebP decoders reject, decode to their first frame.
+func Decode(data []byte) (image.Image, string, error) {
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err == nil {
+		return img, format, nil
+	}
+
+	if frame, ok := firstWebPFrame(data); ok {
+		if img, _, ferr := image.Decode(bytes.NewReader(frame)); ferr == nil {
+			return img, "webp", nil
+		}
+	}
+
+	return nil, "", err
+}
+
+// PixelHash returns the hex SHA-256 of the image dimensions and its 8-bit
+// RGBA pixels, so identical pixels hash equally regardless of the format
+// they were decoded from
+func PixelHash(img image.Image) string {
+	rgba := toRGBA(img)
+
+	h := sha256.New()
+	_ = binary.Write(h, binary.BigEndian, [2]uint32{uint32(rgba.Rect.Dx()), uint32(rgba.Rect.Dy())})
+	h.Write(rgba.Pix)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// toRGBA returns the image as a tightly packed *image.RGBA with its origin
+// at (0, 0), converting only when needed
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok && rgba.Rect.Min == (image.Point{}) && rgba.Stride == 4*rgba.Rect.Dx() {
+		return rgba
+	}
+
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
+
+// FormatHash formats a 64-bit perceptual hash as 16 hex digits
+func FormatHash(hash uint64) string {
+	return fmt.Sprintf("%016x", hash)
+}
+
+// ParseHash parses a hash formatted by FormatHash
+func ParseHash(s string) (uint64, error) {
+	var hash uint64
+	if _, err := fmt.Sscanf(s, "%016x", &hash); err != nil || len(s) != 16 {
+		return 0, fmt.Errorf("invalid hash %q", s)
+	}
+	return hash, nil
+}
+
+// Distance returns the Hamming distance between two perceptual hashes;
+// 0 means identical and values up to about 10 mean visually similar
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// firstWebPFrame rewraps the bitstream of the first ANMF frame of an
+// animated WebP file as a standalone WebP file
+func firstWebPFrame(data []byte) ([]byte, bool) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, false
+	}
+
+	for pos := 12; pos+8 <= len(data); {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		start := pos + 8
+		if start+size > len(data) {
+			return nil, false
+		}
+
+		if fourCC == "ANMF" && size > 16 {
+			payload := data[start : start+size]
+
+			// Keep the frame size and alpha flag in a VP8X header
+			var flags byte
+			if bytes.Contains(payload[16:], []byte("ALPH")) {
+				flags |= 0x10
+			}
+			header := make([]byte, 10)
+			header[0] = flags
+			copy(header[4:10], payload[6:12]) // Frame width-1 and height-1
+
+			var body bytes.Buffer
+			body.WriteString("WEBPVP8X")
+			_ = binary.Write(&body, binary.LittleEndian, uint32(len(header)))
+			body.Write(header)
+			body.Write(payload[16:])
+
+			var out bytes.Buffer
+			out.WriteString("RIFF")
+			_ = binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+			out.Write(body.Bytes())
+			return out.Bytes(), true
+		}
+
+		pos = start + size + size%2
+	}
+
+	return nil, false
+}
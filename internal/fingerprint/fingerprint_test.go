@@ -0,0 +1,174 @@
+package fingerprint_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"reflect"
+	"testing"
+
+	"golang.org/x/image/bmp"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+	"github.com/gruz0/futuage-test-image-generator/pkg/testimages"
+)
+
+func TestCompute(t *testing.T) {
+	data := testimages.Bytes(t, "png", 300, 200)
+	fp := fingerprint.Compute(data)
+
+	sum := sha256.Sum256(data)
+	if fp.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("SHA256 = %s, want checksum of the file bytes", fp.SHA256)
+	}
+	if fp.Format != "png" || fp.Width != 300 || fp.Height != 200 {
+		t.Errorf("Decoded = %s %dx%d, want png 300x200", fp.Format, fp.Width, fp.Height)
+	}
+	if len(fp.PixelSHA256) != 64 || len(fp.DHash) != 16 || len(fp.PHash) != 16 {
+		t.Errorf("Hashes = %q %q %q, want 64 and 16 hex digits", fp.PixelSHA256, fp.DHash, fp.PHash)
+	}
+}
+
+func TestCompute_PixelHashIgnoresContainer(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	for y := 0; y < 48; y++ {
+		for x := 0; x < 64; x++ {
+			img.Set(x, y, color.RGBA{R: uint8(x * 4), G: uint8(y * 5), B: 128, A: 255})
+		}
+	}
+
+	// Lossless encodings of the same pixels share the pixel hash but not
+	// the file checksum
+	var pngData, bmpData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := bmp.Encode(&bmpData, img); err != nil {
+		t.Fatal(err)
+	}
+
+	fromPNG := fingerprint.Compute(pngData.Bytes())
+	fromBMP := fingerprint.Compute(bmpData.Bytes())
+
+	if fromPNG.SHA256 == fromBMP.SHA256 {
+		t.Error("PNG and BMP files have the same checksum")
+	}
+	if fromPNG.PixelSHA256 != fromBMP.PixelSHA256 || fromPNG.PixelSHA256 != fingerprint.PixelHash(img) {
+		t.Errorf("Pixel hashes differ: png %s, bmp %s", fromPNG.PixelSHA256, fromBMP.PixelSHA256)
+	}
+	if fromPNG.PHash != fromBMP.PHash || fromPNG.DHash != fromBMP.DHash {
+		t.Error("Perceptual hashes differ for identical pixels")
+	}
+}
+
+func TestPerceptualHashes_Similarity(t *testing.T) {
+	distance := func(a, b string) int {
+		t.Helper()
+		x, err := fingerprint.ParseHash(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		y, err := fingerprint.ParseHash(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fingerprint.Distance(x, y)
+	}
+
+	// Recompress the decoded original, since a fresh render at another
+	// quality carries a different spec label
+	originalData := testimages.Bytes(t, "jpeg", 1080, 1350, testimages.Quality(95))
+	decoded, err := jpeg.Decode(bytes.NewReader(originalData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recompressedData bytes.Buffer
+	if err := jpeg.Encode(&recompressedData, decoded, &jpeg.Options{Quality: 60}); err != nil {
+		t.Fatal(err)
+	}
+	original := fingerprint.Compute(originalData)
+	recompressed := fingerprint.Compute(recompressedData.Bytes())
+	different := fingerprint.Compute(testimages.Bytes(t, "jpeg", 1350, 1080, testimages.Quality(95)))
+
+	if d := distance(original.PHash, recompressed.PHash); d > 10 {
+		t.Errorf("pHash distance after recompression = %d, want <= 10", d)
+	}
+	if d := distance(original.PHash, different.PHash); d <= 10 {
+		t.Errorf("pHash distance between different images = %d, want > 10", d)
+	}
+	if original.PixelSHA256 == recompressed.PixelSHA256 {
+		t.Error("Pixel hash should change when JPEG quality changes")
+	}
+}
+
+func TestCompute_AnimatedWebP(t *testing.T) {
+	fp := fingerprint.Compute(testimages.Bytes(t, "webp", 100, 100, testimages.Animated(3, 100)))
+
+	if fp.Format != "webp" || fp.Width != 100 || fp.PHash == "" {
+		t.Errorf("Animated WebP = %s %dx%d phash %q, want first frame decoded", fp.Format, fp.Width, fp.Height, fp.PHash)
+	}
+}
+
+func TestCompute_Undecodable(t *testing.T) {
+	fp := fingerprint.Compute([]byte("not an image"))
+
+	if fp.SHA256 == "" {
+		t.Error("SHA256 should be set for any data")
+	}
+	if fp.PixelSHA256 != "" || fp.PHash != "" {
+		t.Error("Pixel hashes should be empty for undecodable data")
+	}
+}
+
+func TestParseHash(t *testing.T) {
+	if hash, err := fingerprint.ParseHash(fingerprint.FormatHash(0xdeadbeef)); err != nil || hash != 0xdeadbeef {
+		t.Errorf("ParseHash(FormatHash()) = %x, %v", hash, err)
+	}
+	for _, s := range []string{"", "xyz", "0123"} {
+		if _, err := fingerprint.ParseHash(s); err == nil {
+			t.Errorf("ParseHash(%q) error = nil, want error", s)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	plainJPEG := testimages.Bytes(t, "jpeg", 32, 32)
+
+	// Splice APP1 (EXIF), APP2 (ICC) and COM segments in after SOI
+	segment := func(marker byte, payload string) []byte {
+		n := len(payload) + 2
+		return append([]byte{0xFF, marker, byte(n >> 8), byte(n)}, payload...)
+	}
+	var taggedJPEG []byte
+	taggedJPEG = append(taggedJPEG, plainJPEG[:2]...)
+	taggedJPEG = append(taggedJPEG, segment(0xE1, "Exif\x00\x00II*\x00")...)
+	taggedJPEG = append(taggedJPEG, segment(0xE2, "ICC_PROFILE\x00\x01\x01")...)
+	taggedJPEG = append(taggedJPEG, segment(0xFE, "hello")...)
+	taggedJPEG = append(taggedJPEG, plainJPEG[2:]...)
+
+	tests := []struct {
+		name string
+		data []byte
+		want []string
+	}{
+		{"plain jpeg", plainJPEG, []string{}},
+		{"tagged jpeg", taggedJPEG, []string{"comment", "exif", "icc"}},
+		{"plain png", testimages.Bytes(t, "png", 32, 32), []string{}},
+		{"plain webp", testimages.Bytes(t, "webp", 32, 32), []string{}},
+		{"webp with metadata", testimages.Bytes(t, "webp", 32, 32, testimages.Metadata("exif", "xmp", "icc")), []string{"exif", "icc", "xmp"}},
+		{"plain tiff", testimages.Bytes(t, "tiff", 32, 32), []string{}},
+		{"garbage", []byte("nope"), []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := fingerprint.Metadata(tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Metadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package fingerprint
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sort"
+)
+
+// Metadata kinds reported by Metadata
+const (
+	MetadataEXIF    = "exif"
+	MetadataXMP     = "xmp"
+	MetadataICC     = "icc"
+	MetadataComment = "comment" // JPEG comments, PNG text chunks
+)
+
+// xmpKeyword identifies XMP packets in PNG iTXt chunks and GIF
+// application extensions
+const xmpKeyword = "XML:com.adobe.xmp"
+
+// Metadata returns the sorted kinds of metadata embedded in an image file,
+// e.g. to check that a pipeline strips them. Formats that are not
+// recognized report none.
+func Metadata(data []byte) []string {
+	found := make(map[string]bool)
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		jpegMetadata(data, found)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		pngMetadata(data, found)
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WEBP":
+		webpMetadata(data, found)
+	case bytes.HasPrefix(data, []byte("GIF8")):
+		if bytes.Contains(data, []byte("XMP DataXMP")) {
+			found[MetadataXMP] = true
+		}
+	case bytes.HasPrefix(data, []byte("II*\x00")), bytes.HasPrefix(data, []byte("MM\x00*")):
+		tiffMetadata(data, found)
+	}
+
+	kinds := make([]string, 0, len(found))
+	for kind := range found {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+// jpegMetadata scans the marker segments before the image data
+func jpegMetadata(data []byte, found map[string]bool) {
+	for pos := 2; pos+4 <= len(data); {
+		if data[pos] != 0xFF {
+			return
+		}
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan, end of image
+			return
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return
+		}
+		payload := data[pos+4 : end]
+
+		switch {
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("Exif\x00\x00")):
+			found[MetadataEXIF] = true
+		case marker == 0xE1 && bytes.HasPrefix(payload, []byte("http://ns.adobe.com/xap/1.0/\x00")):
+			found[MetadataXMP] = true
+		case marker == 0xE2 && bytes.HasPrefix(payload, []byte("ICC_PROFILE\x00")):
+			found[MetadataICC] = true
+		case marker == 0xFE:
+			found[MetadataComment] = true
+		}
+		pos = end
+	}
+}
+
+// pngMetadata scans the chunk list, which also covers APNG
+func pngMetadata(data []byte, found map[string]bool) {
+	for pos := 8; pos+8 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return
+		}
+		payload := data[pos+8 : pos+8+length]
+
+		switch string(data[pos+4 : pos+8]) {
+		case "eXIf":
+			found[MetadataEXIF] = true
+		case "iCCP":
+			found[MetadataICC] = true
+		case "iTXt":
+			if bytes.HasPrefix(payload, []byte(xmpKeyword+"\x00")) {
+				found[MetadataXMP] = true
+			} else {
+				found[MetadataComment] = true
+			}
+		case "tEXt", "zTXt":
+			found[MetadataComment] = true
+		case "IEND":
+			return
+		}
+		pos = end
+	}
+}
+
+// webpMetadata scans the RIFF chunks of an extended WebP
+func webpMetadata(data []byte, found map[string]bool) {
+	for pos := 12; pos+8 <= len(data); {
+		length := int(binary.LittleEndian.Uint32(data[pos+4:]))
+		switch string(data[pos : pos+4]) {
+		case "EXIF":
+			found[MetadataEXIF] = true
+		case "XMP ":
+			found[MetadataXMP] = true
+		case "ICCP":
+			found[MetadataICC] = true
+		}
+		pos += 8 + length + length%2
+	}
+}
+
+// tiffMetadata looks for EXIF, XMP and ICC tags in the first IFD
+func tiffMetadata(data []byte, found map[string]bool) {
+	var order binary.ByteOrder = binary.LittleEndian
+	if data[0] == 'M' {
+		order = binary.BigEndian
+	}
+	if len(data) < 8 {
+		return
+	}
+
+	ifd := int(order.Uint32(data[4:]))
+	if ifd+2 > len(data) {
+		return
+	}
+	count := int(order.Uint16(data[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(data) {
+			return
+		}
+		switch order.Uint16(data[entry:]) {
+		case 34665: // ExifIFD
+			found[MetadataEXIF] = true
+		case 700: // XMP
+			found[MetadataXMP] = true
+		case 34675: // InterColorProfile
+			found[MetadataICC] = true
+		}
+	}
+}
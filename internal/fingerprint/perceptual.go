@@ -0,0 +1,147 @@
+package fingerprint
+
+import (
+	"image"
+	"math"
+	"sort"
+)
+
+// Grid sizes for the perceptual hashes
+const (
+	dhashWidth  = 9
+	dhashHeight = 8
+	phashSize   = 32
+	phashBlock  = 8
+)
+
+// PerceptualHashes returns the difference hash (dHash) and DCT perceptual
+// hash (pHash) of an image. Both are computed from box-averaged luminance,
+// so re-encoding or mild compression changes only a few bits.
+func PerceptualHashes(img image.Image) (dhash, phash uint64) {
+	small, large := luminanceGrids(img)
+
+	// dHash: each bit tells whether a cell is brighter than its right neighbor
+	bit := 0
+	for y := 0; y < dhashHeight; y++ {
+		for x := 0; x < dhashWidth-1; x++ {
+			if small[y*dhashWidth+x] > small[y*dhashWidth+x+1] {
+				dhash |= 1 << uint(bit)
+			}
+			bit++
+		}
+	}
+
+	// pHash: each bit tells whether a low-frequency DCT coefficient is above
+	// the median, ignoring the DC term
+	coeffs := dct2D(large, phashSize)
+	block := make([]float64, 0, phashBlock*phashBlock)
+	for y := 0; y < phashBlock; y++ {
+		block = append(block, coeffs[y*phashSize:y*phashSize+phashBlock]...)
+	}
+	sorted := append([]float64(nil), block[1:]...)
+	sort.Float64s(sorted)
+	median := (sorted[len(sorted)/2-1] + sorted[len(sorted)/2]) / 2
+
+	for i, c := range block {
+		if c > median {
+			phash |= 1 << uint(i)
+		}
+	}
+
+	return dhash, phash
+}
+
+// luminanceGrids box-averages the image luminance into the dHash and pHash
+// grids. Transparent pixels count as black, matching the premultiplied RGBA
+// pixels.
+//
+// Pixels are summed once into a fine grid whose cells nest exactly inside
+// the cells of both hash grids (x*fine/width rounds down to the same hash
+// column as x*hash/width), which is then folded into each hash grid.
+func luminanceGrids(img image.Image) (small, large []float64) {
+	rgba := toRGBA(img)
+	width, height := rgba.Rect.Dx(), rgba.Rect.Dy()
+
+	const fineWidth = dhashWidth * phashSize
+	const fineHeight = dhashHeight * phashSize
+
+	fineCol := make([]int, width)
+	colCount := make([]uint64, fineWidth)
+	for x := 0; x < width; x++ {
+		fineCol[x] = x * fineWidth / width
+		colCount[fineCol[x]]++
+	}
+	rowCount := make([]uint64, fineHeight)
+	for y := 0; y < height; y++ {
+		rowCount[y*fineHeight/height]++
+	}
+
+	fine := make([]uint64, fineWidth*fineHeight)
+	for y := 0; y < height; y++ {
+		cells := fine[y*fineHeight/height*fineWidth:][:fineWidth]
+		row := rgba.Pix[y*rgba.Stride : y*rgba.Stride+width*4]
+		for x := 0; x < width; x++ {
+			// BT.601 luma scaled by 1000
+			p := row[x*4 : x*4+3 : x*4+3]
+			cells[fineCol[x]] += 299*uint64(p[0]) + 587*uint64(p[1]) + 114*uint64(p[2])
+		}
+	}
+
+	fold := func(gridWidth, gridHeight int) []float64 {
+		sums := make([]uint64, gridWidth*gridHeight)
+		counts := make([]uint64, gridWidth*gridHeight)
+		for fy := 0; fy < fineHeight; fy++ {
+			gy := fy * gridHeight / fineHeight
+			for fx := 0; fx < fineWidth; fx++ {
+				cell := gy*gridWidth + fx*gridWidth/fineWidth
+				sums[cell] += fine[fy*fineWidth+fx]
+				counts[cell] += rowCount[fy] * colCount[fx]
+			}
+		}
+
+		grid := make([]float64, len(sums))
+		for i := range sums {
+			if counts[i] > 0 {
+				grid[i] = float64(sums[i]) / float64(counts[i]) / 1000
+			}
+		}
+		return grid
+	}
+
+	return fold(dhashWidth, dhashHeight), fold(phashSize, phashSize)
+}
+
+// dct2D computes the 2D DCT-II of a size×size matrix
+func dct2D(input []float64, size int) []float64 {
+	cos := make([]float64, size*size)
+	for k := 0; k < size; k++ {
+		for n := 0; n < size; n++ {
+			cos[k*size+n] = math.Cos(math.Pi / float64(size) * (float64(n) + 0.5) * float64(k))
+		}
+	}
+
+	// Transform rows, then columns
+	rows := make([]float64, size*size)
+	for y := 0; y < size; y++ {
+		for k := 0; k < size; k++ {
+			var sum float64
+			for n := 0; n < size; n++ {
+				sum += input[y*size+n] * cos[k*size+n]
+			}
+			rows[y*size+k] = sum
+		}
+	}
+
+	output := make([]float64, size*size)
+	for x := 0; x < size; x++ {
+		for k := 0; k < size; k++ {
+			var sum float64
+			for n := 0; n < size; n++ {
+				sum += rows[n*size+x] * cos[k*size+n]
+			}
+			output[k*size+x] = sum
+		}
+	}
+
+	return output
+}
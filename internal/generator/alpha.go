@@ -0,0 +1,38 @@
+package generator
+
+import (
+	"image"
+)
+
+// Alpha levels used by ApplyAlphaMask
+const (
+	alphaTransparent = 0
+	alphaTranslucent = 128
+)
+
+// ApplyAlphaMask punches transparency into the image using the grid cells:
+// cells alternate between fully transparent and half transparent in a
+// checkerboard. Anything drawn afterwards (border, text, markers) stays
+// opaque, so every image covers all three alpha classes.
+func ApplyAlphaMask(img *image.RGBA) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	gridSize := GetGridSize(width, height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			alpha := uint32(alphaTranslucent)
+			if (x/gridSize+y/gridSize)%2 == 1 {
+				alpha = alphaTransparent
+			}
+
+			// image.RGBA stores premultiplied color, so scale every channel
+			i := img.PixOffset(x, y)
+			for c := 0; c < 4; c++ {
+				img.Pix[i+c] = uint8(uint32(img.Pix[i+c]) * alpha / 255)
+			}
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// DefaultFrameDelay is the delay between animation frames when none is configured
+const DefaultFrameDelay = 100 // milliseconds
+
+// MaxFrameDelay is the longest frame delay every animated format can store,
+// limited by the 16-bit millisecond delay of APNG frames
+const MaxFrameDelay = 65535 // milliseconds
+
+// MaxLoopCount is the largest loop count every animated format can store,
+// limited by the 16-bit loop counts of GIF and WebP
+const MaxLoopCount = 65535
+
+// Frame disposal operations
+const (
+	DisposeNone       = "none"
+	DisposeBackground = "background"
+	DisposePrevious   = "previous"
+)
+
+// Frame blend operations
+const (
+	BlendSource = "source"
+	BlendOver   = "over"
+)
+
+// IsValidDisposeOp checks if op is a supported disposal operation
+func IsValidDisposeOp(op string) bool {
+	switch op {
+	case "", DisposeNone, DisposeBackground, DisposePrevious:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValidBlendOp checks if op is a supported blend operation
+func IsValidBlendOp(op string) bool {
+	switch op {
+	case "", BlendSource, BlendOver:
+		return true
+	default:
+		return false
+	}
+}
+
+// Animation holds the rendered frames of an animated image. It implements
+// image.Image by delegating to the first frame, so encoders without
+// animation support still produce a valid still image.
+type Animation struct {
+	Frames    []*image.RGBA
+	Delays    []int       // Per-frame delay in milliseconds
+	LoopCount int         // 0 loops forever
+	Default   *image.RGBA // Optional still image outside the animation
+}
+
+// ColorModel returns the color model of the first frame
+func (a *Animation) ColorModel() color.Model {
+	return a.Frames[0].ColorModel()
+}
+
+// Bounds returns the bounds of the first frame
+func (a *Animation) Bounds() image.Rectangle {
+	return a.Frames[0].Bounds()
+}
+
+// At returns the color of the first frame at (x, y)
+func (a *Animation) At(x, y int) color.Color {
+	return a.Frames[0].At(x, y)
+}
+
+// RenderAnimation renders every frame of an animated spec. Each frame shows
+// its number and timestamp in the text overlay and a progress bar along the
+// bottom edge, so consumers can tell which frame they received.
+func RenderAnimation(spec ImageSpec) (*Animation, error) {
+	frameCount := spec.Options.Frames
+	if frameCount < 1 {
+		frameCount = 1
+	}
+
+	delay := spec.Options.FrameDelayMs()
+
+	anim := &Animation{
+		Frames:    make([]*image.RGBA, 0, frameCount),
+		Delays:    make([]int, 0, frameCount),
+		LoopCount: spec.Options.LoopCount,
+	}
+
+	for i := 0; i < frameCount; i++ {
+		lines := []string{
+			fmt.Sprintf("Frame %d/%d", i+1, frameCount),
+			fmt.Sprintf("t=%dms", i*delay),
+		}
+
+		frame, err := renderFrame(spec, lines)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render frame %d: %w", i+1, err)
+		}
+		drawFrameProgress(frame, i, frameCount)
+
+		anim.Frames = append(anim.Frames, frame)
+		anim.Delays = append(anim.Delays, delay)
+	}
+
+	if spec.Options.SeparateDefault {
+		lines := []string{"DEFAULT IMAGE", "not an animation frame"}
+
+		frame, err := renderFrame(spec, lines)
+		if err != nil {
+			return nil, fmt.Errorf("failed to render default image: %w", err)
+		}
+		anim.Default = frame
+	}
+
+	return anim, nil
+}
+
+// drawFrameProgress draws a white progress bar above the bottom border
+// proportional to the frame position
+func drawFrameProgress(img *image.RGBA, index, total int) {
+	bounds := img.Bounds()
+	width := bounds.Dx()
+	height := bounds.Dy()
+
+	barHeight := GetGridSize(width, height) / 10
+	if barHeight < 2 {
+		barHeight = 2
+	}
+
+	barWidth := width * (index + 1) / total
+	bar := image.Rect(0, height-2-barHeight, barWidth, height-2)
+	draw.Draw(img, bar, &image.Uniform{color.RGBA{R: 255, G: 255, B: 255, A: 255}}, image.Point{}, draw.Src)
+}
@@ -0,0 +1,209 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"io"
+)
+
+// pngSignature is the 8-byte signature starting every PNG file
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+// pngChunk is a single chunk of a PNG file
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+// encodeAPNG encodes an animation to APNG by encoding every frame as a PNG
+// and re-packaging its IDAT data into fcTL/fdAT chunks. Still images are
+// written as a single-frame animation.
+func encodeAPNG(file io.Writer, img image.Image, opts EncodeOptions) error {
+	anim, ok := img.(*Animation)
+	if !ok {
+		anim = &Animation{
+			Frames: []*image.RGBA{toRGBA(img)},
+			Delays: []int{opts.FrameDelayMs()},
+		}
+	}
+
+	data, err := buildAPNG(anim, opts)
+	if err != nil {
+		return fmt.Errorf("failed to encode APNG: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write APNG: %w", err)
+	}
+
+	return nil
+}
+
+// buildAPNG assembles the APNG byte stream. When the animation has a
+// separate default image, its IDAT precedes the first fcTL and is skipped by
+// APNG-aware decoders; otherwise frame 1 doubles as the default image.
+func buildAPNG(anim *Animation, opts EncodeOptions) ([]byte, error) {
+	var header []byte
+	var sequence uint32
+	var chunks []pngChunk
+
+	// encode returns the IDAT payloads of a frame, checking that every frame
+	// shares the header of the first one
+	encode := func(frame image.Image) ([][]byte, error) {
+		ihdr, idat, err := encodePNGChunks(frame)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil {
+			header = ihdr
+		} else if !bytes.Equal(header[8:], ihdr[8:]) {
+			return nil, fmt.Errorf("frames have different color types")
+		}
+		return idat, nil
+	}
+
+	if anim.Default != nil {
+		idat, err := encode(anim.Default)
+		if err != nil {
+			return nil, fmt.Errorf("default image: %w", err)
+		}
+		for _, data := range idat {
+			chunks = append(chunks, pngChunk{typ: "IDAT", data: data})
+		}
+	}
+
+	for i, frame := range anim.Frames {
+		idat, err := encode(frame)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i+1, err)
+		}
+
+		chunks = append(chunks, pngChunk{typ: "fcTL", data: frameControl(sequence, frame.Bounds(), anim.Delays[i], opts)})
+		sequence++
+
+		for _, data := range idat {
+			// Frame 1 uses IDAT when it is also the default image
+			if i == 0 && anim.Default == nil {
+				chunks = append(chunks, pngChunk{typ: "IDAT", data: data})
+				continue
+			}
+			fdat := make([]byte, 4, 4+len(data))
+			binary.BigEndian.PutUint32(fdat, sequence)
+			chunks = append(chunks, pngChunk{typ: "fdAT", data: append(fdat, data...)})
+			sequence++
+		}
+	}
+
+	actl := make([]byte, 8)
+	binary.BigEndian.PutUint32(actl[0:4], uint32(len(anim.Frames)))
+	binary.BigEndian.PutUint32(actl[4:8], uint32(anim.LoopCount))
+
+	var buf bytes.Buffer
+	buf.Write(pngSignature)
+	writePNGChunk(&buf, pngChunk{typ: "IHDR", data: header})
+	writePNGChunk(&buf, pngChunk{typ: "acTL", data: actl})
+	for _, chunk := range chunks {
+		writePNGChunk(&buf, chunk)
+	}
+	writePNGChunk(&buf, pngChunk{typ: "IEND"})
+
+	return buf.Bytes(), nil
+}
+
+// frameControl builds an fcTL payload for a full-canvas frame
+func frameControl(sequence uint32, bounds image.Rectangle, delayMs int, opts EncodeOptions) []byte {
+	fctl := make([]byte, 26)
+	binary.BigEndian.PutUint32(fctl[0:4], sequence)
+	binary.BigEndian.PutUint32(fctl[4:8], uint32(bounds.Dx()))
+	binary.BigEndian.PutUint32(fctl[8:12], uint32(bounds.Dy()))
+	// X and Y offsets stay zero
+	binary.BigEndian.PutUint16(fctl[20:22], uint16(delayMs))
+	binary.BigEndian.PutUint16(fctl[22:24], 1000) // Delay denominator: milliseconds
+
+	switch opts.DisposeOp {
+	case DisposeBackground:
+		fctl[24] = 1
+	case DisposePrevious:
+		fctl[24] = 2
+	}
+	if opts.BlendOp == BlendOver {
+		fctl[25] = 1
+	}
+
+	return fctl
+}
+
+// encodePNGChunks encodes an image as PNG and returns its IHDR payload and
+// the payloads of its IDAT chunks
+func encodePNGChunks(img image.Image) (ihdr []byte, idat [][]byte, err error) {
+	var buf bytes.Buffer
+	if err := encodePNG(&buf, img, EncodeOptions{}); err != nil {
+		return nil, nil, err
+	}
+
+	chunks, err := parsePNGChunks(buf.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, chunk := range chunks {
+		switch chunk.typ {
+		case "IHDR":
+			ihdr = chunk.data
+		case "IDAT":
+			idat = append(idat, chunk.data)
+		}
+	}
+
+	return ihdr, idat, nil
+}
+
+// parsePNGChunks splits a PNG file into its chunks
+func parsePNGChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, fmt.Errorf("not a PNG file")
+	}
+
+	var chunks []pngChunk
+	for pos := len(pngSignature); pos+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[pos : pos+4]))
+		typ := string(data[pos+4 : pos+8])
+		end := pos + 8 + length
+		if end+4 > len(data) {
+			return nil, fmt.Errorf("truncated %q chunk", typ)
+		}
+
+		chunks = append(chunks, pngChunk{typ: typ, data: data[pos+8 : end]})
+		pos = end + 4 // Skip CRC
+	}
+
+	return chunks, nil
+}
+
+// writePNGChunk writes a chunk with its length and CRC
+func writePNGChunk(buf *bytes.Buffer, chunk pngChunk) {
+	_ = binary.Write(buf, binary.BigEndian, uint32(len(chunk.data)))
+
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(chunk.typ))
+	crc.Write(chunk.data)
+
+	buf.WriteString(chunk.typ)
+	buf.Write(chunk.data)
+	_ = binary.Write(buf, binary.BigEndian, crc.Sum32())
+}
+
+// toRGBA converts an image to *image.RGBA
+func toRGBA(img image.Image) *image.RGBA {
+	if rgba, ok := img.(*image.RGBA); ok {
+		return rgba
+	}
+	bounds := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, bounds.Min, draw.Src)
+	return rgba
+}
@@ -28,22 +28,17 @@ func DrawGridBackground(img *image.RGBA, category string) error {
 	// Draw grid lines (semi-transparent white)
 	gridColor := color.RGBA{R: 255, G: 255, B: 255, A: 51} // 20% opacity (51/255)
 
-	// Draw vertical grid lines
+	// Draw vertical grid lines, blending over the background so the image
+	// stays fully opaque
 	for x := gridSize; x < width; x += gridSize {
-		for y := 0; y < height; y++ {
-			if x < width {
-				img.Set(x, y, gridColor)
-			}
-		}
+		line := image.Rect(x, 0, x+1, height)
+		draw.Draw(img, line, &image.Uniform{gridColor}, image.Point{}, draw.Over)
 	}
 
 	// Draw horizontal grid lines
 	for y := gridSize; y < height; y += gridSize {
-		for x := 0; x < width; x++ {
-			if y < height {
-				img.Set(x, y, gridColor)
-			}
-		}
+		line := image.Rect(0, y, width, y+1)
+		draw.Draw(img, line, &image.Uniform{gridColor}, image.Point{}, draw.Over)
 	}
 
 	return nil
@@ -0,0 +1,91 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"golang.org/x/image/bmp"
+)
+
+// BMP header sizes
+const (
+	bmpFileHeaderLen = 14
+	bmpV4HeaderLen   = 108
+)
+
+// encodeBMP encodes image to BMP format. Opaque images are written as 24-bit
+// by golang.org/x/image/bmp. Images with transparency are written as 32-bit
+// with a BITMAPV4HEADER alpha mask, as decoders ignore the alpha byte of
+// 32-bit files with the plain BITMAPINFOHEADER that x/image writes.
+func encodeBMP(file io.Writer, img image.Image, _ EncodeOptions) error {
+	if opaque, ok := img.(interface{ Opaque() bool }); !ok || opaque.Opaque() {
+		if err := bmp.Encode(file, img); err != nil {
+			return fmt.Errorf("failed to encode BMP: %w", err)
+		}
+		return nil
+	}
+
+	if err := encodeBMPWithAlpha(file, img); err != nil {
+		return fmt.Errorf("failed to encode BMP: %w", err)
+	}
+	return nil
+}
+
+// encodeBMPWithAlpha writes a bottom-up 32-bit BGRA BMP with straight alpha
+func encodeBMPWithAlpha(w io.Writer, img image.Image) error {
+	pix := toStraightRGBA(img)
+	width := pix.Rect.Dx()
+	height := pix.Rect.Dy()
+	imageSize := width * height * 4
+	pixOffset := bmpFileHeaderLen + bmpV4HeaderLen
+
+	var buf bytes.Buffer
+	buf.Grow(pixOffset + imageSize)
+
+	// File header
+	buf.WriteString("BM")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(pixOffset+imageSize))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(0)) // Reserved
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(pixOffset))
+
+	// BITMAPV4HEADER
+	for _, v := range []interface{}{
+		uint32(bmpV4HeaderLen),
+		int32(width),
+		int32(height), // Positive height stores rows bottom-up
+		uint16(1),     // Planes
+		uint16(32),    // Bits per pixel
+		uint32(3),     // BI_BITFIELDS
+		uint32(imageSize),
+		int32(2835), // 72 DPI in pixels per meter
+		int32(2835),
+		uint32(0),           // Colors used
+		uint32(0),           // Important colors
+		uint32(0x00FF0000),  // Red mask
+		uint32(0x0000FF00),  // Green mask
+		uint32(0x000000FF),  // Blue mask
+		uint32(0xFF000000),  // Alpha mask
+		[]byte("BGRs"),      // LCS_sRGB color space
+		make([]byte, 36+12), // Unused endpoints and gamma
+	} {
+		_ = binary.Write(&buf, binary.LittleEndian, v)
+	}
+
+	row := make([]byte, width*4)
+	for y := height - 1; y >= 0; y-- {
+		src := pix.Pix[y*pix.Stride : y*pix.Stride+width*4]
+		for x := 0; x < width*4; x += 4 {
+			row[x+0] = src[x+2]
+			row[x+1] = src[x+1]
+			row[x+2] = src[x+0]
+			row[x+3] = src[x+3]
+		}
+		buf.Write(row)
+	}
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -5,15 +5,97 @@ import (
 	"image"
 	"image/jpeg"
 	"image/png"
+	"io"
 	"os"
 	"path/filepath"
-	"strings"
+)
 
-	"github.com/chai2010/webp"
+// Variant options shared by the built-in encoders
+var (
+	optionLossless     = OptionSpec{Name: "lossless", Type: "bool", Description: "Use lossless compression"}
+	optionNearLossless = OptionSpec{Name: "near_lossless", Type: "int", Description: "Near-lossless preprocessing level 1-99"}
+	optionAlpha        = OptionSpec{Name: "alpha", Type: "bool", Description: "Render with a transparency mask"}
+	optionInterlaced   = OptionSpec{Name: "interlaced", Type: "bool", Description: "Write interlaced rows"}
+	optionMetadata     = OptionSpec{Name: "metadata", Type: "[]string", Description: "Embed exif, xmp and/or icc metadata"}
+	optionFrames       = OptionSpec{Name: "frames", Type: "int", Description: "Number of animation frames"}
+	optionFrameDelay   = OptionSpec{Name: "frame_delay_ms", Type: "int", Description: "Delay between frames in milliseconds"}
+	optionLoopCount    = OptionSpec{Name: "loop_count", Type: "int", Description: "Number of plays, 0 loops forever"}
+	optionDisposeOp    = OptionSpec{Name: "dispose_op", Type: "string", Description: "Frame disposal: none, background, previous"}
+	optionBlendOp      = OptionSpec{Name: "blend_op", Type: "string", Description: "Frame blending: source, over"}
+	optionSeparate     = OptionSpec{Name: "separate_default_image", Type: "bool", Description: "Add a still default image outside the animation"}
+	optionCompression  = OptionSpec{Name: "compression", Type: "string", Description: "Compression scheme: none, deflate"}
+	optionPages        = OptionSpec{Name: "pages", Type: "int", Description: "Number of pages"}
 )
 
-// EncodeImage encodes an image to the specified format and quality
-func EncodeImage(img image.Image, outputPath, format string, quality int) error {
+// init registers the built-in encoders
+func init() {
+	RegisterEncoder(&FuncEncoder{
+		FormatName:     "jpeg",
+		FileExtensions: []string{".jpg", ".jpeg"},
+		Mime:           "image/jpeg",
+		EncodeFunc:     encodeJPEG,
+	}, "jpg")
+	RegisterEncoder(&FuncEncoder{
+		FormatName:     "png",
+		FileExtensions: []string{".png"},
+		Mime:           "image/png",
+		OptionSpecs:    []OptionSpec{optionAlpha},
+		EncodeFunc:     encodePNG,
+	})
+	RegisterEncoder(&FuncEncoder{
+		FormatName:     "webp",
+		FileExtensions: []string{".webp"},
+		Mime:           "image/webp",
+		OptionSpecs: []OptionSpec{
+			optionLossless, optionNearLossless, optionAlpha, optionMetadata,
+			optionFrames, optionFrameDelay, optionLoopCount,
+		},
+		EncodeFunc: encodeWebP,
+	})
+	RegisterEncoder(&FuncEncoder{
+		FormatName:     "gif",
+		FileExtensions: []string{".gif"},
+		Mime:           "image/gif",
+		OptionSpecs: []OptionSpec{
+			optionAlpha, optionInterlaced,
+			optionFrames, optionFrameDelay, optionLoopCount, optionDisposeOp,
+		},
+		EncodeFunc: encodeGIF,
+	})
+	RegisterEncoder(&FuncEncoder{
+		FormatName:     "apng",
+		FileExtensions: []string{".png", ".apng"},
+		Mime:           "image/apng",
+		OptionSpecs: []OptionSpec{
+			optionAlpha,
+			optionFrames, optionFrameDelay, optionLoopCount, optionDisposeOp, optionBlendOp, optionSeparate,
+		},
+		EncodeFunc: encodeAPNG,
+	})
+	RegisterEncoder(&FuncEncoder{
+		FormatName:     "bmp",
+		FileExtensions: []string{".bmp"},
+		Mime:           "image/bmp",
+		OptionSpecs:    []OptionSpec{optionAlpha},
+		EncodeFunc:     encodeBMP,
+	})
+	RegisterEncoder(&FuncEncoder{
+		FormatName:     "tiff",
+		FileExtensions: []string{".tiff", ".tif"},
+		Mime:           "image/tiff",
+		OptionSpecs:    []OptionSpec{optionAlpha, optionCompression, optionPages},
+		EncodeFunc:     encodeTIFF,
+	}, "tif")
+}
+
+// EncodeImage encodes an image to the specified format and writes it to
+// outputPath. opts.Quality sets the quality of lossy encoders.
+func EncodeImage(img image.Image, outputPath, format string, opts EncodeOptions) (err error) {
+	enc, ok := LookupEncoder(format)
+	if !ok {
+		return fmt.Errorf("unsupported format: %s", format)
+	}
+
 	// Ensure output directory exists
 	dir := filepath.Dir(outputPath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
@@ -31,33 +113,32 @@ func EncodeImage(img image.Image, outputPath, format string, quality int) error
 		}
 	}()
 
-	// Encode based on format
-	format = strings.ToLower(format)
-	switch format {
-	case "jpeg", "jpg":
-		return encodeJPEG(file, img, quality)
-	case "png":
-		return encodePNG(file, img)
-	case "webp":
-		return encodeWebP(file, img, quality)
-	default:
+	return enc.Encode(file, img, opts)
+}
+
+// EncodeTo encodes an image to w using the registered encoder for format.
+// opts.Quality sets the quality of lossy encoders.
+func EncodeTo(w io.Writer, img image.Image, format string, opts EncodeOptions) error {
+	enc, ok := LookupEncoder(format)
+	if !ok {
 		return fmt.Errorf("unsupported format: %s", format)
 	}
+	return enc.Encode(w, img, opts)
 }
 
 // encodeJPEG encodes image to JPEG format
-func encodeJPEG(file *os.File, img image.Image, quality int) error {
-	opts := &jpeg.Options{
-		Quality: quality,
+func encodeJPEG(file io.Writer, img image.Image, opts EncodeOptions) error {
+	jpegOpts := &jpeg.Options{
+		Quality: opts.Quality,
 	}
-	if err := jpeg.Encode(file, img, opts); err != nil {
+	if err := jpeg.Encode(file, img, jpegOpts); err != nil {
 		return fmt.Errorf("failed to encode JPEG: %w", err)
 	}
 	return nil
 }
 
 // encodePNG encodes image to PNG format
-func encodePNG(file *os.File, img image.Image) error {
+func encodePNG(file io.Writer, img image.Image, _ EncodeOptions) error {
 	encoder := &png.Encoder{
 		CompressionLevel: png.BestCompression,
 	}
@@ -67,32 +148,12 @@ func encodePNG(file *os.File, img image.Image) error {
 	return nil
 }
 
-// encodeWebP encodes image to WebP format
-func encodeWebP(file *os.File, img image.Image, quality int) error {
-	// Convert quality to WebP quality (0-100 scale, but use float32)
-	opts := &webp.Options{
-		Lossless: false,
-		Quality:  float32(quality),
-	}
-
-	if err := webp.Encode(file, img, opts); err != nil {
-		return fmt.Errorf("failed to encode WebP: %w", err)
-	}
-
-	return nil
-}
-
-// GetFileExtension returns the appropriate file extension for a format
+// GetFileExtension returns the default file extension of a format's
+// encoder, or ".jpg" for unknown formats
 func GetFileExtension(format string) string {
-	format = strings.ToLower(format)
-	switch format {
-	case "jpeg", "jpg":
-		return ".jpg"
-	case "png":
-		return ".png"
-	case "webp":
-		return ".webp"
-	default:
+	enc, ok := LookupEncoder(format)
+	if !ok {
 		return ".jpg"
 	}
+	return enc.Extensions()[0]
 }
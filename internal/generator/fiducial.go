@@ -0,0 +1,121 @@
+package generator
+
+import (
+	"image"
+	"image/color"
+)
+
+// Fiducial marker names
+const (
+	FiducialTopLeft     = "top-left"
+	FiducialTop         = "top"
+	FiducialTopRight    = "top-right"
+	FiducialRight       = "right"
+	FiducialBottomRight = "bottom-right"
+	FiducialBottom      = "bottom"
+	FiducialBottomLeft  = "bottom-left"
+	FiducialLeft        = "left"
+	FiducialCenter      = "center"
+)
+
+// FiducialMinSize is the shortest side an image needs to carry fiducials
+const FiducialMinSize = 200
+
+// fiducialMargin is the distance of edge markers from the image edge, and
+// fiducialLabelClearance keeps corner markers clear of the corner labels
+const (
+	fiducialMargin         = 10
+	fiducialLabelClearance = 28
+)
+
+// FiducialColors identifies each marker by a saturated color that is far
+// from the category backgrounds, white, black and every other marker
+var FiducialColors = map[string]color.RGBA{
+	FiducialTopLeft:     {R: 255, A: 255},
+	FiducialTop:         {R: 128, A: 255},
+	FiducialTopRight:    {G: 255, A: 255},
+	FiducialRight:       {G: 128, A: 255},
+	FiducialBottomRight: {B: 255, A: 255},
+	FiducialBottom:      {B: 128, A: 255},
+	FiducialBottomLeft:  {R: 255, B: 255, A: 255},
+	FiducialLeft:        {R: 128, B: 255, A: 255},
+	FiducialCenter:      {G: 255, B: 255, A: 255},
+}
+
+// Fiducial is a machine-detectable marker at a known position
+type Fiducial struct {
+	Name  string
+	Color color.RGBA
+	Rect  image.Rectangle // solid colored square, framed in white
+}
+
+// Center returns the center of the marker in continuous pixel coordinates
+func (f Fiducial) Center() (x, y float64) {
+	return float64(f.Rect.Min.X+f.Rect.Max.X) / 2, float64(f.Rect.Min.Y+f.Rect.Max.Y) / 2
+}
+
+// FiducialSize returns the side of the fiducial markers of an image
+func FiducialSize(width, height int) int {
+	return max(8, min(width, height)/25)
+}
+
+// Fiducials returns the markers of an image: one at each corner, one at
+// the middle of each edge and one at the center. Images shorter than
+// FiducialMinSize carry none.
+func Fiducials(width, height int) []Fiducial {
+	if min(width, height) < FiducialMinSize {
+		return nil
+	}
+
+	s := FiducialSize(width, height)
+	left, right := fiducialMargin, width-fiducialMargin-s
+	top, bottom := fiducialMargin, height-fiducialMargin-s
+	cornerTop, cornerBottom := fiducialLabelClearance, height-fiducialLabelClearance-s
+	midX, midY := (width-s)/2, (height-s)/2
+
+	positions := []struct {
+		name string
+		x, y int
+	}{
+		{FiducialTopLeft, left, cornerTop},
+		{FiducialTop, midX, top},
+		{FiducialTopRight, right, cornerTop},
+		{FiducialRight, right, midY},
+		{FiducialBottomRight, right, cornerBottom},
+		{FiducialBottom, midX, bottom},
+		{FiducialBottomLeft, left, cornerBottom},
+		{FiducialLeft, left, midY},
+		{FiducialCenter, midX, midY},
+	}
+
+	fiducials := make([]Fiducial, len(positions))
+	for i, p := range positions {
+		fiducials[i] = Fiducial{
+			Name:  p.name,
+			Color: FiducialColors[p.name],
+			Rect:  image.Rect(p.x, p.y, p.x+s, p.y+s),
+		}
+	}
+	return fiducials
+}
+
+// centerFiducial returns the rectangle of the center marker, if the image
+// carries fiducials
+func centerFiducial(width, height int) (image.Rectangle, bool) {
+	for _, f := range Fiducials(width, height) {
+		if f.Name == FiducialCenter {
+			return f.Rect, true
+		}
+	}
+	return image.Rectangle{}, false
+}
+
+// DrawFiducials draws the fiducial markers of an image
+func DrawFiducials(img *image.RGBA, width, height int) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	for _, f := range Fiducials(width, height) {
+		frame := max(1, f.Rect.Dx()/8)
+		fillRect(img, f.Rect.Inset(-frame), white)
+		fillRect(img, f.Rect, f.Color)
+	}
+}
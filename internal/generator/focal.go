@@ -0,0 +1,134 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FocalPoint is the position of the salient subject of an image in
+// normalized coordinates, 0,0 being the top-left and 1,1 the bottom-right
+// corner. Name is set for named positions.
+type FocalPoint struct {
+	Name string
+	X    float64
+	Y    float64
+}
+
+// focalPositions are the named focal points: the intersections and edge
+// midpoints of the rule-of-thirds grid, and the center
+var focalPositions = map[string]FocalPoint{
+	"top-left-third":     {X: 1.0 / 3, Y: 1.0 / 3},
+	"top-third":          {X: 0.5, Y: 1.0 / 3},
+	"top-right-third":    {X: 2.0 / 3, Y: 1.0 / 3},
+	"left-third":         {X: 1.0 / 3, Y: 0.5},
+	"center":             {X: 0.5, Y: 0.5},
+	"right-third":        {X: 2.0 / 3, Y: 0.5},
+	"bottom-left-third":  {X: 1.0 / 3, Y: 2.0 / 3},
+	"bottom-third":       {X: 0.5, Y: 2.0 / 3},
+	"bottom-right-third": {X: 2.0 / 3, Y: 2.0 / 3},
+}
+
+// FocalPositionNames returns the names of all named focal points, sorted
+func FocalPositionNames() []string {
+	names := make([]string, 0, len(focalPositions))
+	for name := range focalPositions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseFocalPoint parses a named position such as "top-left-third" or
+// normalized coordinates such as "0.8,0.25"
+func ParseFocalPoint(s string) (FocalPoint, error) {
+	if p, ok := focalPositions[s]; ok {
+		p.Name = s
+		return p, nil
+	}
+
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return FocalPoint{}, fmt.Errorf("unknown focal point %q (use x,y or one of: %s)",
+			s, strings.Join(FocalPositionNames(), ", "))
+	}
+	x, errX := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if errX != nil || errY != nil {
+		return FocalPoint{}, fmt.Errorf("invalid focal point coordinates %q", s)
+	}
+	if x < 0 || x > 1 || y < 0 || y > 1 {
+		return FocalPoint{}, fmt.Errorf("focal point coordinates %q must be between 0 and 1", s)
+	}
+	return FocalPoint{X: x, Y: y}, nil
+}
+
+// Pixel returns the focal point in a width x height image
+func (f FocalPoint) Pixel(width, height int) image.Point {
+	x := min(int(math.Round(f.X*float64(width))), width-1)
+	y := min(int(math.Round(f.Y*float64(height))), height-1)
+	return image.Pt(max(x, 0), max(y, 0))
+}
+
+// FocalSubjectRadius returns the radius of the subject drawn at the focal
+// point, a tenth of the shorter side
+func FocalSubjectRadius(width, height int) int {
+	return max(4, min(width, height)/10)
+}
+
+// focalSubjectColors are the rings of the subject from the outside in.
+// Black, white and saturated yellow stand out from every category
+// background without matching a fiducial color.
+var focalSubjectColors = []color.RGBA{
+	{A: 255},                         // black
+	{R: 255, G: 220, A: 255},         // yellow
+	{A: 255},                         // black
+	{R: 255, G: 255, B: 255, A: 255}, // white
+	{R: 255, G: 220, A: 255},         // yellow
+}
+
+// DrawFocalSubject draws a high-contrast bullseye centered on the spec's
+// focal point, with a crosshair marking the exact pixel
+func DrawFocalSubject(img *image.RGBA, spec ImageSpec) {
+	if spec.FocalPoint == nil {
+		return
+	}
+	center := spec.FocalPoint.Pixel(spec.Width, spec.Height)
+	radius := FocalSubjectRadius(spec.Width, spec.Height)
+	bounds := img.Bounds()
+
+	// 1. Concentric rings, each a fifth of the radius
+	area := image.Rect(center.X-radius, center.Y-radius, center.X+radius+1, center.Y+radius+1).Intersect(bounds)
+	for y := area.Min.Y; y < area.Max.Y; y++ {
+		for x := area.Min.X; x < area.Max.X; x++ {
+			d := math.Hypot(float64(x-center.X), float64(y-center.Y))
+			if d > float64(radius) {
+				continue
+			}
+			ring := min(int((1-d/float64(radius))*float64(len(focalSubjectColors))), len(focalSubjectColors)-1)
+			img.SetRGBA(x, y, focalSubjectColors[ring])
+		}
+	}
+
+	// 2. Crosshair through the focal pixel
+	arm := radius / 2
+	line := max(1, radius/40)
+	black := color.RGBA{A: 255}
+	fillRect(img, image.Rect(center.X-arm, center.Y-line/2, center.X+arm+1, center.Y-line/2+line), black)
+	fillRect(img, image.Rect(center.X-line/2, center.Y-arm, center.X-line/2+line, center.Y+arm+1), black)
+}
+
+// SmartCrop returns the window a subject-aware crop to ratioWidth:ratioHeight
+// keeps: the same size as CenterCrop, moved to be centered on the focus
+// point as far as the image bounds allow
+func SmartCrop(width, height int, focus image.Point, ratioWidth, ratioHeight int) image.Rectangle {
+	window := CenterCrop(width, height, ratioWidth, ratioHeight)
+	w, h := window.Dx(), window.Dy()
+	x := min(max(focus.X-w/2, 0), width-w)
+	y := min(max(focus.Y-h/2, 0), height-h)
+	return image.Rect(x, y, x+w, y+h)
+}
@@ -1,9 +1,14 @@
 package generator
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"image"
 	"image/color"
+
+	"golang.org/x/image/font"
 )
 
 // ImageSpec defines the specification for generating a test image
@@ -18,6 +23,67 @@ type ImageSpec struct {
 	Category     string // platform, common, edge
 	OutputPath   string
 	Filename     string
+	MimeType     string        // MIME type of the encoded file, from the format config
+	Variant      string        // optional encoder variant name, e.g. "lossless"
+	Options      EncodeOptions // format-specific encoder settings
+	Overlays     []string      // optional overlay layers, e.g. "rulers"
+	CropGuides   []CropGuide   // target crops outlined by the crop-guides overlay
+	SafeAreas    []SafeArea    // regions clear of platform UI, hatched by the safe-zones overlay
+	FocalPoint   *FocalPoint   // optional position of a high-salience subject
+	Scene        *Scene        // optional synthetic scene of annotated objects
+}
+
+// Fingerprint returns a SHA-256 identifying everything that determines the
+// generated image. The output directory is excluded, so the same spec
+// written elsewhere has the same fingerprint.
+func (s ImageSpec) Fingerprint() (string, error) {
+	s.OutputPath = ""
+	data, err := json.Marshal(s)
+	if err != nil {
+		// Only non-finite floats, e.g. a NaN ratio or focal point, fail
+		return "", fmt.Errorf("failed to fingerprint spec: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// EncodeOptions holds format-specific encoder settings beyond quality
+type EncodeOptions struct {
+	Quality      int      // Quality 0-100 for lossy encoders, set from ImageSpec.Quality
+	Lossless     bool     // Use lossless compression (WebP)
+	NearLossless int      // Near-lossless preprocessing level 1-99, lower is stronger, 0 disables (WebP)
+	Alpha        bool     // Render with a transparency mask
+	Interlaced   bool     // Write interlaced rows (GIF)
+	Metadata     []string // Metadata chunks to embed: exif, xmp, icc
+	Frames       int      // Number of animation frames, 0 or 1 for still images
+	FrameDelay   int      // Delay between animation frames in milliseconds
+	LoopCount    int      // Animation loop count, 0 loops forever
+	DisposeOp    string   // Frame disposal: none, background, previous (GIF, APNG)
+	BlendOp      string   // Frame blending: source, over (APNG)
+	Compression  string   // Compression scheme: none, deflate (TIFF)
+	Pages        int      // Number of pages, 0 or 1 for single-page images (TIFF)
+
+	// SeparateDefault renders a still default image that is not part of the
+	// animation (APNG), so consumers reading only the first image are detectable
+	SeparateDefault bool
+}
+
+// IsAnimated returns true if the options describe a multi-frame image
+func (o EncodeOptions) IsAnimated() bool {
+	return o.Frames > 1
+}
+
+// IsMultiPage returns true if the options describe a multi-page image
+func (o EncodeOptions) IsMultiPage() bool {
+	return o.Pages > 1
+}
+
+// FrameDelayMs returns the configured frame delay or DefaultFrameDelay
+func (o EncodeOptions) FrameDelayMs() int {
+	if o.FrameDelay <= 0 {
+		return DefaultFrameDelay
+	}
+	return o.FrameDelay
 }
 
 // CategoryColors defines the background colors for each category
@@ -27,39 +93,149 @@ var CategoryColors = map[string]color.RGBA{
 	"edge":     {R: 245, G: 166, B: 35, A: 255}, // Orange #F5A623
 }
 
-// Generate creates a test image based on the provided specification
+// Generate creates a test image based on the provided specification and
+// writes it to spec.OutputPath
 func Generate(spec ImageSpec) error {
+	// 1. Render the image in memory
+	img, err := Render(spec)
+	if err != nil {
+		return err
+	}
+
+	// 2. Encode to target format
+	opts := spec.Options
+	opts.Quality = spec.Quality
+	if err := EncodeImage(img, spec.OutputPath, spec.Format, opts); err != nil {
+		return fmt.Errorf("failed to encode image: %w", err)
+	}
+
+	return nil
+}
+
+// Render draws the image described by spec without touching disk. Animated
+// specs return an *Animation and multi-page specs a *PageSet, both of which
+// can be passed to EncodeTo.
+func Render(spec ImageSpec) (image.Image, error) {
+	if spec.Width <= 0 || spec.Height <= 0 {
+		return nil, fmt.Errorf("invalid dimensions %dx%d", spec.Width, spec.Height)
+	}
+
+	switch {
+	case spec.Options.IsAnimated():
+		return RenderAnimation(spec)
+	case spec.Options.IsMultiPage():
+		return RenderPages(spec)
+	default:
+		return renderFrame(spec, nil)
+	}
+}
+
+// renderFrame draws a single image for the spec, appending extraLines to the
+// centered text overlay
+func renderFrame(spec ImageSpec, extraLines []string) (*image.RGBA, error) {
 	// 1. Create blank image canvas
 	img := image.NewRGBA(image.Rect(0, 0, spec.Width, spec.Height))
 
 	// 2. Draw grid pattern background
 	if err := DrawGridBackground(img, spec.Category); err != nil {
-		return fmt.Errorf("failed to draw grid background: %w", err)
+		return nil, fmt.Errorf("failed to draw grid background: %w", err)
+	}
+
+	// 3. Punch transparency into the background if requested
+	if spec.Options.Alpha {
+		ApplyAlphaMask(img)
 	}
 
-	// 3. Draw 2px border
+	// 4. Draw 2px border
 	DrawBorder(img, spec.Category, 2)
 
-	// 4. Render centered text overlay
+	// 5. Draw the selected overlay layers
+	if err := DrawOverlays(img, spec); err != nil {
+		return nil, err
+	}
+
+	// 6. Draw the focal-point subject and scene objects
+	DrawFocalSubject(img, spec)
+	objects, err := SceneObjects(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to place scene objects: %w", err)
+	}
+	DrawScene(img, objects)
+
+	// 7. Render centered text overlay with the spec label QR code below it
+	lines := labelLines(spec, extraLines)
+	label, layout, err := specLabel(spec, lines)
+	if err != nil {
+		return nil, err
+	}
+	if layout.ModuleSize > 0 {
+		label.Draw(img, layout.Origin.X, layout.Origin.Y, layout.ModuleSize)
+	}
+	drawTextBlock(img, lines, spec.Width, layout.TextCenterY)
+
+	// 8. Draw corner markers
+	DrawCornerMarkers(img, spec.Width, spec.Height)
+
+	// 9. Draw fiducial markers
+	DrawFiducials(img, spec.Width, spec.Height)
+
+	return img, nil
+}
+
+// labelLines returns the lines of the centered text overlay
+func labelLines(spec ImageSpec, extraLines []string) []string {
+	formatLine := fmt.Sprintf("%s Q%d", spec.Format, spec.Quality)
+	if spec.Variant != "" {
+		formatLine += " " + spec.Variant
+	}
 	lines := []string{
 		fmt.Sprintf("%d×%d", spec.Width, spec.Height),
 		fmt.Sprintf("%s (%.3f)", spec.Ratio, spec.RatioDecimal),
-		fmt.Sprintf("%s Q%d", spec.Format, spec.Quality),
+		formatLine,
 		spec.SizeCategory,
 	}
-	if err := DrawTextOverlay(img, lines, spec.Width, spec.Height); err != nil {
-		return fmt.Errorf("failed to draw text overlay: %w", err)
+	return append(lines, extraLines...)
+}
+
+// specLabel encodes the spec label and places it together with the text
+// lines
+func specLabel(spec ImageSpec, lines []string) (*QRCode, LabelLayout, error) {
+	payload, err := SpecPayload(spec)
+	if err != nil {
+		return nil, LabelLayout{}, err
+	}
+	label, err := EncodeQR(payload)
+	if err != nil {
+		return nil, LabelLayout{}, fmt.Errorf("failed to encode spec label: %w", err)
 	}
+	textHeight := len(lines) * int(GetFontSize(spec.Width, spec.Height)*1.5)
+	return label, LayoutLabel(spec.Width, spec.Height, label.Size, textHeight), nil
+}
 
-	// 5. Draw corner markers
-	DrawCornerMarkers(img, spec.Width, spec.Height)
+// labelBounds returns the area covered by the text block and spec label of
+// a still image, including their outlines and quiet zone
+func labelBounds(spec ImageSpec) (image.Rectangle, error) {
+	lines := labelLines(spec, nil)
+	_, layout, err := specLabel(spec, lines)
+	if err != nil {
+		return image.Rectangle{}, err
+	}
 
-	// 6. Encode to target format
-	if err := EncodeImage(img, spec.OutputPath, spec.Format, spec.Quality); err != nil {
-		return fmt.Errorf("failed to encode image: %w", err)
+	// Text block as laid out by drawTextBlock
+	face := getFontFace(GetFontSize(spec.Width, spec.Height))
+	lineHeight := int(GetFontSize(spec.Width, spec.Height) * 1.5)
+	textWidth := 0
+	for _, line := range lines {
+		textWidth = max(textWidth, font.MeasureString(face, line).Ceil())
 	}
+	top := layout.TextCenterY - len(lines)*lineHeight/2
+	x0 := (spec.Width - textWidth) / 2
+	bounds := image.Rect(x0, top, x0+textWidth, top+len(lines)*lineHeight+face.Metrics().Descent.Ceil()).Inset(-2)
 
-	return nil
+	if layout.ModuleSize > 0 {
+		bounds = bounds.Union(image.Rect(layout.Origin.X, layout.Origin.Y, layout.Origin.X+layout.Side, layout.Origin.Y+layout.Side))
+	}
+	return bounds, nil
 }
 
 // GetFontSize returns adaptive font size based on image dimensions
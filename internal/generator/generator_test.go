@@ -1,9 +1,30 @@
 package generator
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+
+	xdraw "golang.org/x/image/draw"
+
+	xwebp "golang.org/x/image/webp"
 )
 
 func TestGenerate(t *testing.T) {
@@ -191,3 +212,1310 @@ func TestCategoryColors(t *testing.T) {
 	}
 }
 
+func TestGenerate_WebPVariants(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		options   EncodeOptions
+		wantAlpha bool
+		wantVP8X  bool
+	}{
+		{"lossless", EncodeOptions{Lossless: true}, false, false},
+		{"lossless alpha", EncodeOptions{Lossless: true, Alpha: true}, true, false},
+		{"lossy alpha", EncodeOptions{Alpha: true}, true, true},
+		{"near-lossless", EncodeOptions{NearLossless: 60}, false, false},
+		{"extended metadata", EncodeOptions{Metadata: []string{"exif", "xmp", "icc"}}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spec := ImageSpec{
+				Width:        200,
+				Height:       200,
+				Ratio:        "1:1",
+				RatioDecimal: 1.0,
+				Format:       "webp",
+				Quality:      82,
+				SizeCategory: "Tiny",
+				Category:     "platform",
+				OutputPath:   filepath.Join(tmpDir, tt.name+".webp"),
+				Variant:      tt.name,
+				Options:      tt.options,
+			}
+
+			if err := Generate(spec); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			data, err := os.ReadFile(spec.OutputPath)
+			if err != nil {
+				t.Fatalf("Failed to read generated file: %v", err)
+			}
+
+			if got := string(data[12:16]) == "VP8X"; got != tt.wantVP8X {
+				t.Errorf("VP8X container = %v, want %v", got, tt.wantVP8X)
+			}
+
+			img, err := xwebp.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Failed to decode WebP: %v", err)
+			}
+
+			if img.Bounds().Dx() != 200 || img.Bounds().Dy() != 200 {
+				t.Errorf("Decoded size = %v, want 200x200", img.Bounds().Size())
+			}
+
+			// Top-left grid cell interior is translucent when alpha is on
+			_, _, _, a := img.At(5, 5).RGBA()
+			if gotAlpha := a>>8 != 255; gotAlpha != tt.wantAlpha {
+				t.Errorf("Pixel alpha = %d, want translucent = %v", a>>8, tt.wantAlpha)
+			}
+		})
+	}
+}
+
+func TestGenerate_AnimatedWebP(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	spec := ImageSpec{
+		Width:        120,
+		Height:       80,
+		Ratio:        "3:2",
+		RatioDecimal: 1.5,
+		Format:       "webp",
+		Quality:      82,
+		SizeCategory: "Tiny",
+		Category:     "common",
+		OutputPath:   filepath.Join(tmpDir, "animated.webp"),
+		Variant:      "animated",
+		Options:      EncodeOptions{Frames: 4, FrameDelay: 250, LoopCount: 3},
+	}
+
+	if err := Generate(spec); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	data, err := os.ReadFile(spec.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	chunks, err := parseWebPChunks(data)
+	if err != nil {
+		t.Fatalf("parseWebPChunks() error = %v", err)
+	}
+
+	if chunks[0].fourCC != "VP8X" || chunks[0].data[0]&vp8xFlagAnimation == 0 {
+		t.Fatalf("First chunk = %q flags %#x, want VP8X with animation flag", chunks[0].fourCC, chunks[0].data[0])
+	}
+
+	var frames int
+	for _, chunk := range chunks {
+		switch chunk.fourCC {
+		case "ANIM":
+			if loops := binary.LittleEndian.Uint16(chunk.data[4:6]); loops != 3 {
+				t.Errorf("ANIM loop count = %d, want 3", loops)
+			}
+		case "ANMF":
+			frames++
+			duration := uint32(chunk.data[12]) | uint32(chunk.data[13])<<8 | uint32(chunk.data[14])<<16
+			if duration != 250 {
+				t.Errorf("ANMF duration = %d, want 250", duration)
+			}
+
+			// Re-wrap the frame bitstream as a simple file to check it decodes
+			var body bytes.Buffer
+			body.WriteString("WEBP")
+			body.Write(chunk.data[16:])
+			var file bytes.Buffer
+			file.WriteString("RIFF")
+			_ = binary.Write(&file, binary.LittleEndian, uint32(body.Len()))
+			file.Write(body.Bytes())
+
+			img, err := xwebp.Decode(&file)
+			if err != nil {
+				t.Fatalf("Frame %d does not decode: %v", frames, err)
+			}
+			if img.Bounds() != image.Rect(0, 0, 120, 80) {
+				t.Errorf("Frame %d bounds = %v, want 120x80", frames, img.Bounds())
+			}
+		}
+	}
+
+	if frames != 4 {
+		t.Errorf("ANMF frame count = %d, want 4", frames)
+	}
+}
+
+func TestBuildICCProfile(t *testing.T) {
+	profile := BuildICCProfile()
+
+	if size := binary.BigEndian.Uint32(profile[0:4]); int(size) != len(profile) {
+		t.Errorf("ICC profile size field = %d, want %d", size, len(profile))
+	}
+
+	if string(profile[36:40]) != "acsp" {
+		t.Errorf("ICC profile signature = %q, want %q", profile[36:40], "acsp")
+	}
+}
+
+func TestGenerate_GIF(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	newSpec := func(name string, opts EncodeOptions) ImageSpec {
+		return ImageSpec{
+			Width:        200,
+			Height:       100,
+			Ratio:        "2:1",
+			RatioDecimal: 2.0,
+			Format:       "gif",
+			Quality:      100,
+			SizeCategory: "Tiny",
+			Category:     "edge",
+			OutputPath:   filepath.Join(tmpDir, name+".gif"),
+			Options:      opts,
+		}
+	}
+
+	decode := func(t *testing.T, spec ImageSpec) *gif.GIF {
+		t.Helper()
+		if err := Generate(spec); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		f, err := os.Open(spec.OutputPath)
+		if err != nil {
+			t.Fatalf("Failed to open generated file: %v", err)
+		}
+		defer f.Close()
+		g, err := gif.DecodeAll(f)
+		if err != nil {
+			t.Fatalf("Failed to decode GIF: %v", err)
+		}
+		return g
+	}
+
+	plain := decode(t, newSpec("plain", EncodeOptions{}))
+
+	t.Run("transparent", func(t *testing.T) {
+		g := decode(t, newSpec("transparent", EncodeOptions{Alpha: true}))
+
+		// Grid cell (1, 0) is fully transparent, cell (0, 0) translucent and kept opaque
+		if _, _, _, a := g.Image[0].At(25, 5).RGBA(); a != 0 {
+			t.Errorf("Transparent cell alpha = %d, want 0", a)
+		}
+		if _, _, _, a := g.Image[0].At(5, 5).RGBA(); a == 0 {
+			t.Error("Translucent cell became transparent")
+		}
+	})
+
+	t.Run("interlaced", func(t *testing.T) {
+		g := decode(t, newSpec("interlaced", EncodeOptions{Interlaced: true}))
+
+		// The decoder de-interlaces, so pixels must match the plain image
+		for y := 0; y < 100; y++ {
+			for x := 0; x < 200; x++ {
+				if g.Image[0].At(x, y) != plain.Image[0].At(x, y) {
+					t.Fatalf("Pixel (%d, %d) = %v, want %v", x, y, g.Image[0].At(x, y), plain.Image[0].At(x, y))
+				}
+			}
+		}
+	})
+
+	t.Run("animated", func(t *testing.T) {
+		g := decode(t, newSpec("animated", EncodeOptions{Frames: 3, FrameDelay: 200, LoopCount: 2}))
+
+		if len(g.Image) != 3 {
+			t.Fatalf("Frame count = %d, want 3", len(g.Image))
+		}
+		if g.LoopCount != 1 {
+			t.Errorf("LoopCount = %d, want 1 (two plays)", g.LoopCount)
+		}
+		for i, d := range g.Delay {
+			if d != 20 {
+				t.Errorf("Delay[%d] = %d, want 20", i, d)
+			}
+		}
+	})
+}
+
+func TestSetGIFInterlaced(t *testing.T) {
+	var buf bytes.Buffer
+	img := image.NewPaletted(image.Rect(0, 0, 4, 4), color.Palette{color.Black, color.White})
+	if err := gif.Encode(&buf, img, nil); err != nil {
+		t.Fatalf("gif.Encode() error = %v", err)
+	}
+
+	data := buf.Bytes()
+	if err := setGIFInterlaced(data); err != nil {
+		t.Fatalf("setGIFInterlaced() error = %v", err)
+	}
+
+	// Header (6) + screen descriptor (7) + 2-entry global color table (6)
+	descriptor := 19
+	if data[descriptor] != 0x2C {
+		t.Fatalf("Byte %d = %#x, want image descriptor", descriptor, data[descriptor])
+	}
+	if data[descriptor+9]&0x40 == 0 {
+		t.Error("Interlace flag not set")
+	}
+}
+
+func TestQuantizePalette(t *testing.T) {
+	histogram := make(map[color.RGBA]int)
+	for i := 0; i < 1000; i++ {
+		histogram[color.RGBA{R: uint8(i), G: uint8(i / 4), B: uint8(i * 7), A: 255}]++
+	}
+
+	palette := QuantizePalette(histogram, 16)
+	if len(palette) != 16 {
+		t.Errorf("len(palette) = %d, want 16", len(palette))
+	}
+
+	few := map[color.RGBA]int{{R: 1, A: 255}: 5, {G: 2, A: 255}: 10}
+	palette = QuantizePalette(few, 256)
+	if len(palette) != 2 || palette[0] != (color.RGBA{G: 2, A: 255}) {
+		t.Errorf("QuantizePalette() = %v, want exact colors by popularity", palette)
+	}
+}
+
+func TestGenerate_APNG(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	spec := ImageSpec{
+		Width:        120,
+		Height:       120,
+		Ratio:        "1:1",
+		RatioDecimal: 1.0,
+		Format:       "apng",
+		Quality:      100,
+		SizeCategory: "Tiny",
+		Category:     "platform",
+		OutputPath:   filepath.Join(tmpDir, "animated.png"),
+		Variant:      "animated",
+		Options: EncodeOptions{
+			Frames:          3,
+			FrameDelay:      150,
+			LoopCount:       2,
+			DisposeOp:       DisposeBackground,
+			BlendOp:         BlendOver,
+			SeparateDefault: true,
+		},
+	}
+
+	if err := Generate(spec); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	data, err := os.ReadFile(spec.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+
+	chunks, err := parsePNGChunks(data)
+	if err != nil {
+		t.Fatalf("parsePNGChunks() error = %v", err)
+	}
+
+	if chunks[1].typ != "acTL" {
+		t.Fatalf("Second chunk = %q, want acTL", chunks[1].typ)
+	}
+	if frames := binary.BigEndian.Uint32(chunks[1].data[0:4]); frames != 3 {
+		t.Errorf("acTL num_frames = %d, want 3", frames)
+	}
+	if plays := binary.BigEndian.Uint32(chunks[1].data[4:8]); plays != 2 {
+		t.Errorf("acTL num_plays = %d, want 2", plays)
+	}
+
+	// Sequence numbers must be contiguous across fcTL and fdAT; the default
+	// image IDAT must precede the first fcTL
+	var sequence uint32
+	var fctls int
+	var firstFrame [][]byte
+	seenIDAT := false
+	for _, chunk := range chunks {
+		switch chunk.typ {
+		case "IDAT":
+			if fctls > 0 {
+				t.Error("IDAT after fcTL, default image should not be a frame")
+			}
+			seenIDAT = true
+		case "fcTL", "fdAT":
+			if got := binary.BigEndian.Uint32(chunk.data[0:4]); got != sequence {
+				t.Fatalf("%s sequence = %d, want %d", chunk.typ, got, sequence)
+			}
+			sequence++
+			if chunk.typ == "fcTL" {
+				fctls++
+				if chunk.data[24] != 1 || chunk.data[25] != 1 {
+					t.Errorf("fcTL dispose/blend = %d/%d, want 1/1", chunk.data[24], chunk.data[25])
+				}
+			} else if fctls == 1 {
+				firstFrame = append(firstFrame, chunk.data[4:])
+			}
+		}
+	}
+	if !seenIDAT || fctls != 3 {
+		t.Fatalf("IDAT seen = %v, fcTL count = %d, want true and 3", seenIDAT, fctls)
+	}
+
+	// A plain PNG decoder shows the default image
+	defaultImg, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("png.Decode() error = %v", err)
+	}
+
+	// Rebuild frame 1 as a standalone PNG from its fdAT payloads
+	var frame bytes.Buffer
+	frame.Write(pngSignature)
+	writePNGChunk(&frame, chunks[0])
+	for _, payload := range firstFrame {
+		writePNGChunk(&frame, pngChunk{typ: "IDAT", data: payload})
+	}
+	writePNGChunk(&frame, pngChunk{typ: "IEND"})
+	frameImg, err := png.Decode(&frame)
+	if err != nil {
+		t.Fatalf("Frame 1 does not decode: %v", err)
+	}
+
+	if imagesEqual(defaultImg, frameImg) {
+		t.Error("Default image is identical to frame 1")
+	}
+}
+
+// imagesEqual compares two images pixel by pixel
+func TestGenerate_BMPAndTIFF(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tests := []struct {
+		name   string
+		format string
+		ext    string
+		opts   EncodeOptions
+		decode func([]byte) (image.Image, error)
+	}{
+		{"bmp", "bmp", ".bmp", EncodeOptions{}, func(b []byte) (image.Image, error) { return bmp.Decode(bytes.NewReader(b)) }},
+		{"bmp alpha", "bmp", ".bmp", EncodeOptions{Alpha: true}, func(b []byte) (image.Image, error) { return bmp.Decode(bytes.NewReader(b)) }},
+		{"tiff uncompressed", "tiff", ".tiff", EncodeOptions{Compression: TIFFCompressionNone}, func(b []byte) (image.Image, error) { return tiff.Decode(bytes.NewReader(b)) }},
+		{"tiff deflate alpha", "tiff", ".tiff", EncodeOptions{Compression: TIFFCompressionDeflate, Alpha: true}, func(b []byte) (image.Image, error) { return tiff.Decode(bytes.NewReader(b)) }},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ext := GetFileExtension(tt.format); ext != tt.ext {
+				t.Errorf("GetFileExtension(%q) = %q, want %q", tt.format, ext, tt.ext)
+			}
+
+			spec := ImageSpec{
+				Width:        120,
+				Height:       90,
+				Ratio:        "4:3",
+				RatioDecimal: 1.333,
+				Format:       tt.format,
+				Quality:      100,
+				SizeCategory: "Tiny",
+				Category:     "common",
+				OutputPath:   filepath.Join(tmpDir, string(rune('a'+i))+tt.ext),
+				Options:      tt.opts,
+			}
+			if err := Generate(spec); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			data, err := os.ReadFile(spec.OutputPath)
+			if err != nil {
+				t.Fatalf("Failed to read generated file: %v", err)
+			}
+			img, err := tt.decode(data)
+			if err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+
+			if b := img.Bounds(); b.Dx() != 120 || b.Dy() != 90 {
+				t.Errorf("Decoded size = %dx%d, want 120x90", b.Dx(), b.Dy())
+			}
+			if _, _, _, a := img.At(0, 0).RGBA(); a != 0xffff {
+				t.Errorf("Border alpha = %d, want opaque", a)
+			}
+			// Transparent cell at grid (1, 0), inside the border
+			gridSize := GetGridSize(120, 90)
+			_, _, _, a := img.At(gridSize+gridSize/2, gridSize/2).RGBA()
+			if tt.opts.Alpha && a != 0 {
+				t.Errorf("Transparent cell alpha = %d, want 0", a)
+			}
+			if !tt.opts.Alpha && a != 0xffff {
+				t.Errorf("Cell alpha = %d, want opaque", a)
+			}
+		})
+	}
+}
+
+func TestGenerate_MultiPageTIFF(t *testing.T) {
+	for _, compression := range []string{TIFFCompressionNone, TIFFCompressionDeflate} {
+		t.Run(compression, func(t *testing.T) {
+			spec := ImageSpec{
+				Width:        100,
+				Height:       80,
+				Ratio:        "5:4",
+				RatioDecimal: 1.25,
+				Format:       "tiff",
+				Quality:      100,
+				SizeCategory: "Tiny",
+				Category:     "common",
+				OutputPath:   filepath.Join(t.TempDir(), "pages.tiff"),
+				Options:      EncodeOptions{Compression: compression, Pages: 3, Alpha: true},
+			}
+			if err := Generate(spec); err != nil {
+				t.Fatalf("Generate() error = %v", err)
+			}
+
+			data, err := os.ReadFile(spec.OutputPath)
+			if err != nil {
+				t.Fatalf("Failed to read generated file: %v", err)
+			}
+
+			// Walk the IFD chain and decode each page by pointing the header
+			// at its IFD, as x/image/tiff only reads the first page
+			var pages []image.Image
+			for offset := binary.LittleEndian.Uint32(data[4:8]); offset != 0; {
+				page := append([]byte{}, data...)
+				binary.LittleEndian.PutUint32(page[4:8], offset)
+				img, err := tiff.Decode(bytes.NewReader(page))
+				if err != nil {
+					t.Fatalf("Failed to decode page %d: %v", len(pages)+1, err)
+				}
+				pages = append(pages, img)
+
+				entries := int(binary.LittleEndian.Uint16(data[offset:]))
+				offset = binary.LittleEndian.Uint32(data[int(offset)+2+entries*12:])
+			}
+
+			if len(pages) != 3 {
+				t.Fatalf("Page count = %d, want 3", len(pages))
+			}
+			for i, page := range pages {
+				if b := page.Bounds(); b.Dx() != 100 || b.Dy() != 80 {
+					t.Errorf("Page %d size = %dx%d, want 100x80", i+1, b.Dx(), b.Dy())
+				}
+			}
+			if imagesEqual(pages[0], pages[1]) {
+				t.Error("Pages 1 and 2 are identical, want distinct page labels")
+			}
+		})
+	}
+}
+
+func TestRender_EncodeTo(t *testing.T) {
+	spec := ImageSpec{
+		Width:        150,
+		Height:       100,
+		Ratio:        "3:2",
+		RatioDecimal: 1.5,
+		Format:       "png",
+		Quality:      95,
+		SizeCategory: "Tiny",
+		Category:     "common",
+	}
+
+	img, err := Render(spec)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 150 || b.Dy() != 100 {
+		t.Errorf("Render() size = %dx%d, want 150x100", b.Dx(), b.Dy())
+	}
+
+	var buf bytes.Buffer
+	if err := EncodeTo(&buf, img, "png", EncodeOptions{Quality: spec.Quality}); err != nil {
+		t.Fatalf("EncodeTo() error = %v", err)
+	}
+
+	// The in-memory bytes match what Generate writes to disk
+	spec.OutputPath = filepath.Join(t.TempDir(), "image.png")
+	if err := Generate(spec); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+	data, err := os.ReadFile(spec.OutputPath)
+	if err != nil {
+		t.Fatalf("Failed to read generated file: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), data) {
+		t.Error("EncodeTo() output differs from Generate() output")
+	}
+
+	if err := EncodeTo(&buf, img, "heic", EncodeOptions{}); err == nil {
+		t.Error("EncodeTo() with unknown format should return an error")
+	}
+
+	spec.Width = 0
+	if _, err := Render(spec); err == nil {
+		t.Error("Render() with zero width should return an error")
+	}
+}
+
+func TestEncoderRegistry(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantExt string
+		wantOK  bool
+	}{
+		{"jpeg", ".jpg", true},
+		{"JPG", ".jpg", true},
+		{"apng", ".png", true},
+		{"tif", ".tiff", true},
+		{"heic", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			enc, ok := LookupEncoder(tt.name)
+			if ok != tt.wantOK {
+				t.Fatalf("LookupEncoder(%q) ok = %v, want %v", tt.name, ok, tt.wantOK)
+			}
+			if ok && enc.Extensions()[0] != tt.wantExt {
+				t.Errorf("LookupEncoder(%q) extension = %q, want %q", tt.name, enc.Extensions()[0], tt.wantExt)
+			}
+		})
+	}
+
+	gifEncoder, _ := LookupEncoder("gif")
+	if !SupportsOption(gifEncoder, "interlaced") || SupportsOption(gifEncoder, "lossless") {
+		t.Error("gif encoder options should include interlaced and exclude lossless")
+	}
+}
+
+func TestRegisterEncoder_Custom(t *testing.T) {
+	// Raw writes the pixel bytes, standing in for an in-house encoder
+	var gotQuality int
+	RegisterEncoder(&FuncEncoder{
+		FormatName:     "test-raw",
+		FileExtensions: []string{".raw"},
+		Mime:           "application/octet-stream",
+		EncodeFunc: func(w io.Writer, img image.Image, opts EncodeOptions) error {
+			gotQuality = opts.Quality
+			_, err := w.Write(toStraightRGBA(img).Pix)
+			return err
+		},
+	})
+
+	if ext := GetFileExtension("test-raw"); ext != ".raw" {
+		t.Errorf("GetFileExtension() = %q, want .raw", ext)
+	}
+
+	spec := ImageSpec{
+		Width:        40,
+		Height:       30,
+		Ratio:        "4:3",
+		RatioDecimal: 1.333,
+		Format:       "TEST-RAW",
+		Quality:      77,
+		SizeCategory: "Tiny",
+		Category:     "edge",
+		OutputPath:   filepath.Join(t.TempDir(), "custom.raw"),
+	}
+	if err := Generate(spec); err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	info, err := os.Stat(spec.OutputPath)
+	if err != nil {
+		t.Fatalf("Output file not created: %v", err)
+	}
+	if info.Size() != 40*30*4 {
+		t.Errorf("Output size = %d, want %d", info.Size(), 40*30*4)
+	}
+	if gotQuality != 77 {
+		t.Errorf("Encoder quality = %d, want 77", gotQuality)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("RegisterEncoder() with a duplicate name did not panic")
+		}
+	}()
+	RegisterEncoder(&FuncEncoder{FormatName: "test-raw", FileExtensions: []string{".raw"}})
+}
+
+func imagesEqual(a, b image.Image) bool {
+	if a.Bounds() != b.Bounds() {
+		return false
+	}
+	for y := a.Bounds().Min.Y; y < a.Bounds().Max.Y; y++ {
+		for x := a.Bounds().Min.X; x < a.Bounds().Max.X; x++ {
+			r1, g1, b1, a1 := a.At(x, y).RGBA()
+			r2, g2, b2, a2 := b.At(x, y).RGBA()
+			if r1 != r2 || g1 != g2 || b1 != b2 || a1 != a2 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func TestImageSpec_Fingerprint(t *testing.T) {
+	spec := ImageSpec{Width: 100, Height: 100, Format: "webp", Quality: 82, OutputPath: "/a/ratios/1-1/x.webp"}
+	fingerprint := func(s ImageSpec) string {
+		t.Helper()
+		f, err := s.Fingerprint()
+		if err != nil {
+			t.Fatalf("Fingerprint() error = %v", err)
+		}
+		return f
+	}
+
+	moved := spec
+	moved.OutputPath = "/b/ratios/1-1/x.webp"
+	if fingerprint(spec) != fingerprint(moved) {
+		t.Error("Fingerprint() depends on the output directory")
+	}
+
+	lossless := spec
+	lossless.Options.Lossless = true
+	if fingerprint(spec) == fingerprint(lossless) {
+		t.Error("Fingerprint() ignores encoder options")
+	}
+
+	if len(fingerprint(spec)) != 64 {
+		t.Errorf("Fingerprint() = %q, want a SHA-256", fingerprint(spec))
+	}
+
+	invalid := spec
+	invalid.FocalPoint = &FocalPoint{X: math.NaN(), Y: 0.5}
+	if _, err := invalid.Fingerprint(); err == nil {
+		t.Error("Fingerprint() with a NaN focal point: expected error")
+	}
+	if _, err := Render(invalid); err == nil {
+		t.Error("Render() with a NaN focal point: expected error")
+	}
+}
+
+func TestRSCorrect(t *testing.T) {
+	data := []byte("reed-solomon test block")
+	ec := rsEncode(data, rsGenerator(10))
+	block := append(append([]byte{}, data...), ec...)
+
+	tests := []struct {
+		name    string
+		corrupt []int
+		wantErr bool
+	}{
+		{"clean", nil, false},
+		{"one error", []int{3}, false},
+		{"error in ec", []int{len(data) + 2}, false},
+		{"max errors", []int{0, 5, 9, 20, 30}, false},
+		{"too many errors", []int{0, 4, 8, 12, 16, 20}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received := append([]byte{}, block...)
+			for _, i := range tt.corrupt {
+				received[i] ^= 0x5A
+			}
+			err := rsCorrect(received, len(ec))
+			if tt.wantErr {
+				if err == nil && bytes.Equal(received, block) {
+					t.Error("rsCorrect() recovered more errors than the code allows")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("rsCorrect() error = %v", err)
+			}
+			if !bytes.Equal(received, block) {
+				t.Error("rsCorrect() did not restore the block")
+			}
+		})
+	}
+}
+
+func TestEncodeQR_RoundTrip(t *testing.T) {
+	tests := []struct {
+		text        string
+		wantVersion int
+	}{
+		{"FTIG1", 1},
+		{"FTIG1:0123456789abcdef:1000x1500:jpeg:q82", 3},
+		{"FTIG1:0123456789abcdef:1000x1500:webp:q82:near-lossless-60", 4},
+		{string(bytes.Repeat([]byte("x"), 100)), 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text[:5], func(t *testing.T) {
+			qr, err := EncodeQR(tt.text)
+			if err != nil {
+				t.Fatalf("EncodeQR() error = %v", err)
+			}
+			if qr.Version != tt.wantVersion {
+				t.Errorf("Version = %d, want %d", qr.Version, tt.wantVersion)
+			}
+
+			side := (qr.Size + 2*qrQuietZone) * 3
+			img := image.NewRGBA(image.Rect(0, 0, side+20, side+20))
+			qr.Draw(img, 10, 10, 3)
+
+			got, err := DecodeQR(img)
+			if err != nil {
+				t.Fatalf("DecodeQR() error = %v", err)
+			}
+			if got != tt.text {
+				t.Errorf("DecodeQR() = %q, want %q", got, tt.text)
+			}
+		})
+	}
+
+	if _, err := EncodeQR(string(bytes.Repeat([]byte("x"), 107))); err == nil {
+		t.Error("EncodeQR() accepted a payload beyond version 6")
+	}
+}
+
+func TestDecodeQR_ProcessedImage(t *testing.T) {
+	spec := ImageSpec{
+		Width: 1200, Height: 1500, Ratio: "4:5", RatioDecimal: 0.8,
+		Format: "jpeg", Quality: 85, SizeCategory: "medium", Category: "platform",
+	}
+	rendered, err := Render(spec)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+
+	scaled := func(img image.Image, w, h int) image.Image {
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+		return dst
+	}
+	cropped := func(img image.Image, r image.Rectangle) image.Image {
+		return img.(interface {
+			SubImage(image.Rectangle) image.Image
+		}).SubImage(r)
+	}
+	mirrored := func(img image.Image) image.Image {
+		b := img.Bounds()
+		dst := image.NewRGBA(b)
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				dst.Set(b.Max.X-1-x+b.Min.X, y, img.At(x, y))
+			}
+		}
+		return dst
+	}
+	recompressed := func(img image.Image, quality int) image.Image {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := jpeg.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return decoded
+	}
+
+	tests := []struct {
+		name string
+		img  image.Image
+	}{
+		{"original", rendered},
+		{"story crop", cropped(rendered, image.Rect(178, 0, 1022, 1500))},
+		{"landscape crop", cropped(rendered, image.Rect(0, 436, 1200, 1064))},
+		{"downscaled", scaled(rendered, 480, 600)},
+		{"recompressed", recompressed(scaled(rendered, 600, 750), 60)},
+		{"mirrored", mirrored(rendered)},
+	}
+
+	want, err := SpecPayload(spec)
+	if err != nil {
+		t.Fatalf("SpecPayload() error = %v", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeQR(tt.img)
+			if err != nil {
+				t.Fatalf("DecodeQR() error = %v", err)
+			}
+			if got != want {
+				t.Errorf("DecodeQR() = %q, want %q", got, want)
+			}
+		})
+	}
+
+	blank := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	if _, err := DecodeQR(blank); !errors.Is(err, ErrNoQRCode) {
+		t.Errorf("DecodeQR(blank) error = %v, want ErrNoQRCode", err)
+	}
+}
+
+func TestSpecPayload(t *testing.T) {
+	spec := ImageSpec{Width: 1080, Height: 1350, Format: "WEBP", Quality: 90, Variant: "lossless"}
+
+	payload, err := SpecPayload(spec)
+	if err != nil {
+		t.Fatalf("SpecPayload() error = %v", err)
+	}
+	label, err := ParseSpecPayload(payload)
+	if err != nil {
+		t.Fatalf("ParseSpecPayload() error = %v", err)
+	}
+	id, _ := spec.ID()
+	want := SpecLabel{ID: id, Width: 1080, Height: 1350, Format: "webp", Quality: 90, Variant: "lossless"}
+	if label != want {
+		t.Errorf("ParseSpecPayload() = %+v, want %+v", label, want)
+	}
+
+	for _, payload := range []string{"", "https://example.com", "FTIG1:abc:wide:png:q0", "FTIG1:abc:10x10:png:best"} {
+		if _, err := ParseSpecPayload(payload); err == nil {
+			t.Errorf("ParseSpecPayload(%q) succeeded, want error", payload)
+		}
+	}
+}
+
+func TestLayoutLabel(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		wantLabel     bool
+	}{
+		{"tiny", 100, 100, false},
+		{"square", 1080, 1080, true},
+		{"portrait", 1080, 1920, true},
+		{"landscape", 1920, 1080, true},
+		{"extreme wide", 3000, 300, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			layout := LayoutLabel(tt.width, tt.height, qrSize(3), 4*int(GetFontSize(tt.width, tt.height)*1.5))
+			if (layout.ModuleSize > 0) != tt.wantLabel {
+				t.Fatalf("ModuleSize = %d, want label %v", layout.ModuleSize, tt.wantLabel)
+			}
+			if !tt.wantLabel {
+				return
+			}
+			label := image.Rect(layout.Origin.X, layout.Origin.Y, layout.Origin.X+layout.Side, layout.Origin.Y+layout.Side)
+			if safe := LabelSafeRect(tt.width, tt.height); !label.In(safe) {
+				t.Errorf("label %v outside crop-safe region %v", label, safe)
+			}
+		})
+	}
+}
+
+func TestFiducials(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		want          int
+	}{
+		{"too small", 150, 400, 0},
+		{"square", 1080, 1080, 9},
+		{"wide", 1200, 628, 9},
+		{"tall", 281, 500, 9},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fiducials := Fiducials(tt.width, tt.height)
+			if len(fiducials) != tt.want {
+				t.Fatalf("len(Fiducials()) = %d, want %d", len(fiducials), tt.want)
+			}
+
+			bounds := image.Rect(0, 0, tt.width, tt.height)
+			colors := make(map[color.RGBA]bool)
+			for i, f := range fiducials {
+				if !f.Rect.In(bounds) {
+					t.Errorf("%s at %v outside %v", f.Name, f.Rect, bounds)
+				}
+				for _, other := range fiducials[i+1:] {
+					if f.Rect.Overlaps(other.Rect) {
+						t.Errorf("%s overlaps %s", f.Name, other.Name)
+					}
+				}
+				colors[f.Color] = true
+			}
+			if len(colors) != len(fiducials) {
+				t.Error("fiducial colors are not unique")
+			}
+		})
+	}
+
+	for _, f := range Fiducials(1000, 600) {
+		if f.Name != FiducialCenter {
+			continue
+		}
+		if x, y := f.Center(); x != 500 || y != 300 {
+			t.Errorf("center fiducial at (%v, %v), want (500, 300)", x, y)
+		}
+	}
+}
+
+func TestDrawOverlays(t *testing.T) {
+	spec := ImageSpec{
+		Width: 1000, Height: 600, Ratio: "5:3", RatioDecimal: 1.667,
+		Format: "png", Quality: 95, SizeCategory: "medium", Category: "common",
+	}
+	plain, err := renderFrame(spec, nil)
+	if err != nil {
+		t.Fatalf("renderFrame() error = %v", err)
+	}
+
+	spec.Overlays = []string{OverlayRulers}
+	ruled, err := renderFrame(spec, nil)
+	if err != nil {
+		t.Fatalf("renderFrame() error = %v", err)
+	}
+
+	// Major ticks every grid cell (75px here) reach into the image, the
+	// center is untouched
+	for _, p := range []image.Point{{150, 5}, {450, 597}, {5, 300}, {995, 225}} {
+		if plain.RGBAAt(p.X, p.Y) == ruled.RGBAAt(p.X, p.Y) {
+			t.Errorf("no ruler tick at %v", p)
+		}
+	}
+	if plain.RGBAAt(300, 200) != ruled.RGBAAt(300, 200) {
+		t.Error("rulers changed pixels away from the edges")
+	}
+
+	spec.Overlays = []string{"watermark"}
+	if _, err := renderFrame(spec, nil); err == nil {
+		t.Error("renderFrame() accepted an unknown overlay")
+	}
+}
+
+func TestCenterCrop(t *testing.T) {
+	tests := []struct {
+		name                  string
+		width, height, rw, rh int
+		want                  image.Rectangle
+	}{
+		{"same ratio", 1080, 1350, 4, 5, image.Rect(0, 0, 1080, 1350)},
+		{"portrait from landscape", 1500, 1000, 4, 5, image.Rect(350, 0, 1150, 1000)},
+		{"landscape from portrait", 1000, 1500, 1200, 628, image.Rect(0, 488, 1000, 1011)},
+		{"odd leftover goes right", 1001, 1000, 1, 1, image.Rect(0, 0, 1000, 1000)},
+		{"never empty", 1000, 10, 1, 100, image.Rect(499, 0, 500, 10)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CenterCrop(tt.width, tt.height, tt.rw, tt.rh); got != tt.want {
+				t.Errorf("CenterCrop() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrawCropGuides(t *testing.T) {
+	spec := ImageSpec{
+		Width: 1500, Height: 1000, Ratio: "3:2", RatioDecimal: 1.5,
+		Format: "png", Quality: 95, SizeCategory: "medium", Category: "common",
+		Overlays: []string{OverlayCropGuides},
+		CropGuides: []CropGuide{
+			{Label: "4:5 IG_FEED_4_5", RatioWidth: 4, RatioHeight: 5},
+			{Label: "3:2 SAME", RatioWidth: 3, RatioHeight: 2},
+		},
+	}
+	img, err := renderFrame(spec, nil)
+	if err != nil {
+		t.Fatalf("renderFrame() error = %v", err)
+	}
+
+	// The 4:5 window spans x 350-1150; its left edge is outlined in the
+	// first guide color
+	if got := img.RGBAAt(351, 500); got != cropGuideColors[0] {
+		t.Errorf("pixel on 4:5 outline = %v, want %v", got, cropGuideColors[0])
+	}
+
+	// The full-frame 3:2 crop is not drawn over the border
+	if got := img.RGBAAt(0, 500); got == cropGuideColors[1] {
+		t.Error("full-frame crop guide was drawn")
+	}
+}
+
+func TestDrawSafeZones(t *testing.T) {
+	spec := ImageSpec{
+		Width: 1080, Height: 1920, Ratio: "9:16", RatioDecimal: 0.5625,
+		Format: "jpeg", Quality: 85, SizeCategory: "large", Category: "platform",
+		SafeAreas: []SafeArea{{Name: "ui", Rect: image.Rect(0, 250, 1080, 1670)}},
+	}
+	plain, err := renderFrame(spec, nil)
+	if err != nil {
+		t.Fatalf("renderFrame() error = %v", err)
+	}
+	spec.Overlays = []string{OverlaySafeZones}
+	img, err := renderFrame(spec, nil)
+	if err != nil {
+		t.Fatalf("renderFrame() error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		x, y    int
+		changed bool
+	}{
+		{"stripe above safe area", 110, 110, true},
+		{"gap between stripes", 120, 110, false},
+		{"stripe below safe area", 110, 1810, true},
+		{"inside safe area", 110, 1000, false},
+		{"outline", 500, 251, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, want := img.RGBAAt(tt.x, tt.y), plain.RGBAAt(tt.x, tt.y)
+			if (got != want) != tt.changed {
+				t.Errorf("pixel (%d, %d) = %v, plain %v, want changed %v", tt.x, tt.y, got, want, tt.changed)
+			}
+		})
+	}
+	if got := img.RGBAAt(500, 251); got != safeZoneColors[0] {
+		t.Errorf("outline pixel = %v, want %v", got, safeZoneColors[0])
+	}
+}
+
+func TestParseFocalPoint(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    FocalPoint
+		wantErr bool
+	}{
+		{"top-left-third", FocalPoint{Name: "top-left-third", X: 1.0 / 3, Y: 1.0 / 3}, false},
+		{"center", FocalPoint{Name: "center", X: 0.5, Y: 0.5}, false},
+		{"0.85,0.2", FocalPoint{X: 0.85, Y: 0.2}, false},
+		{"0, 1", FocalPoint{X: 0, Y: 1}, false},
+		{"1.5,0.5", FocalPoint{}, true},
+		{"0.5", FocalPoint{}, true},
+		{"a,b", FocalPoint{}, true},
+		{"middle", FocalPoint{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ParseFocalPoint(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFocalPoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseFocalPoint() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSmartCrop(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		focus         image.Point
+		rw, rh        int
+		want          image.Rectangle
+	}{
+		{"centered on focus", 1600, 900, image.Pt(533, 300), 1, 1, image.Rect(83, 0, 983, 900)},
+		{"clamped left", 1600, 900, image.Pt(100, 450), 1, 1, image.Rect(0, 0, 900, 900)},
+		{"clamped right", 1600, 900, image.Pt(1500, 450), 1, 1, image.Rect(700, 0, 1600, 900)},
+		{"clamped top", 900, 1600, image.Pt(450, 100), 4, 5, image.Rect(0, 0, 900, 1125)},
+		{"same ratio", 1200, 1200, image.Pt(1020, 240), 1, 1, image.Rect(0, 0, 1200, 1200)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SmartCrop(tt.width, tt.height, tt.focus, tt.rw, tt.rh); got != tt.want {
+				t.Errorf("SmartCrop() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDrawFocalSubject(t *testing.T) {
+	spec := ImageSpec{
+		Width: 1600, Height: 900, Ratio: "16:9", RatioDecimal: 1.778,
+		Format: "png", Quality: 95, SizeCategory: "large", Category: "common",
+		FocalPoint: &FocalPoint{Name: "bottom-right-third", X: 2.0 / 3, Y: 2.0 / 3},
+	}
+	img, err := renderFrame(spec, nil)
+	if err != nil {
+		t.Fatalf("renderFrame() error = %v", err)
+	}
+
+	// Subject centered on (1067, 600) with radius 90
+	tests := []struct {
+		name string
+		x, y int
+		want color.RGBA
+	}{
+		{"crosshair", 1067, 600, color.RGBA{A: 255}},
+		{"inner ring", 1067 + 10, 600 + 10, focalSubjectColors[4]},
+		{"outer ring", 1067 + 85, 600, focalSubjectColors[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := img.RGBAAt(tt.x, tt.y); got != tt.want {
+				t.Errorf("pixel (%d, %d) = %v, want %v", tt.x, tt.y, got, tt.want)
+			}
+		})
+	}
+
+	// The subject must not hide the spec label
+	if _, err := DecodeQR(img); err != nil {
+		t.Errorf("DecodeQR() error = %v", err)
+	}
+}
+
+func TestSceneObjects(t *testing.T) {
+	spec := ImageSpec{
+		Width: 1600, Height: 900, Ratio: "16:9", RatioDecimal: 1.778,
+		Format: "png", Quality: 95, SizeCategory: "large", Category: "common",
+		Scene: &Scene{Seed: 1, Objects: 8},
+	}
+	objects, err := SceneObjects(spec)
+	if err != nil {
+		t.Fatalf("SceneObjects() error = %v", err)
+	}
+	if len(objects) != 8 {
+		t.Fatalf("SceneObjects() placed %d objects, want 8", len(objects))
+	}
+
+	again, _ := SceneObjects(spec)
+	if !reflect.DeepEqual(objects, again) {
+		t.Error("SceneObjects() is not deterministic for the same seed")
+	}
+
+	label, err := labelBounds(spec)
+	if err != nil {
+		t.Fatalf("labelBounds() error = %v", err)
+	}
+	reserved := []image.Rectangle{label}
+	for _, f := range Fiducials(spec.Width, spec.Height) {
+		reserved = append(reserved, f.Rect)
+	}
+	area := image.Rect(0, 0, spec.Width, spec.Height).Inset(sceneEdgeClearance)
+	for i, o := range objects {
+		r := objectRect(o)
+		if !r.In(area) {
+			t.Errorf("object %d %s at %v is outside %v", i, o.Class, r, area)
+		}
+		if overlapsAny(r, reserved) {
+			t.Errorf("object %d %s at %v overlaps the label, a fiducial or another object", i, o.Class, r)
+		}
+		if o.Area() <= 0 {
+			t.Errorf("object %d %s has area %v", i, o.Class, o.Area())
+		}
+		reserved = append(reserved, r)
+	}
+
+	spec.Scene = &Scene{Seed: 1, Objects: 8, Classes: []string{"star"}}
+	objects, err = SceneObjects(spec)
+	if err != nil {
+		t.Fatalf("SceneObjects() error = %v", err)
+	}
+	for _, o := range objects {
+		if o.Class != "star" {
+			t.Errorf("object class = %q, want star", o.Class)
+		}
+	}
+
+	spec.Scene = &Scene{Seed: 1, Objects: 1, Classes: []string{"cloud"}}
+	if _, err := SceneObjects(spec); err == nil {
+		t.Error("SceneObjects() with an unknown class: expected error")
+	}
+}
+
+func TestSceneObject_Area(t *testing.T) {
+	square := SceneObject{Polygon: [][2]float64{{10, 10}, {30, 10}, {30, 40}, {10, 40}}}
+	if got := square.Area(); got != 600 {
+		t.Errorf("Area() = %v, want 600", got)
+	}
+	x, y, w, h := square.Bounds()
+	if x != 10 || y != 10 || w != 20 || h != 30 {
+		t.Errorf("Bounds() = %v, %v, %v, %v, want 10, 10, 20, 30", x, y, w, h)
+	}
+}
+
+func TestDrawScene(t *testing.T) {
+	spec := ImageSpec{
+		Width: 1200, Height: 1200, Ratio: "1:1", RatioDecimal: 1,
+		Format: "png", Quality: 95, SizeCategory: "medium", Category: "common",
+		Scene: &Scene{Seed: 2, Objects: 10},
+	}
+	img, err := renderFrame(spec, nil)
+	if err != nil {
+		t.Fatalf("renderFrame() error = %v", err)
+	}
+
+	objects, err := SceneObjects(spec)
+	if err != nil {
+		t.Fatalf("SceneObjects() error = %v", err)
+	}
+	for i, o := range objects {
+		// Every pixel whose center is inside the polygon is filled
+		r := objectRect(o)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if !insidePolygon(o.Polygon, float64(x)+0.5, float64(y)+0.5) {
+					continue
+				}
+				if got := img.RGBAAt(x, y); got != o.Color {
+					t.Fatalf("object %d %s pixel (%d, %d) = %v, want %v", i, o.Class, x, y, got, o.Color)
+				}
+			}
+		}
+	}
+
+	// Objects must not hide the spec label or the fiducials
+	if _, err := DecodeQR(img); err != nil {
+		t.Errorf("DecodeQR() error = %v", err)
+	}
+	for _, f := range Fiducials(spec.Width, spec.Height) {
+		c := f.Rect.Min.Add(f.Rect.Size().Div(2))
+		if got := img.RGBAAt(c.X, c.Y); got != f.Color {
+			t.Errorf("fiducial %s center = %v, want %v", f.Name, got, f.Color)
+		}
+	}
+}
+
+func TestFrameControl_MaxDelay(t *testing.T) {
+	fctl := frameControl(0, image.Rect(0, 0, 10, 10), MaxFrameDelay, EncodeOptions{})
+	if got := binary.BigEndian.Uint16(fctl[20:22]); int(got) != MaxFrameDelay {
+		t.Errorf("fcTL delay = %d, want %d", got, MaxFrameDelay)
+	}
+	if got := binary.BigEndian.Uint16(fctl[22:24]); got != 1000 {
+		t.Errorf("fcTL delay denominator = %d, want 1000", got)
+	}
+}
+
+func TestOrchestrator_GenerateAll_StopsProgress(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	specs := make([]ImageSpec, 3)
+	for i := range specs {
+		specs[i] = ImageSpec{
+			Width:        40,
+			Height:       40,
+			Ratio:        "1:1",
+			RatioDecimal: 1,
+			Format:       "png",
+			Quality:      95,
+			SizeCategory: "Tiny",
+			Category:     "common",
+			OutputPath:   filepath.Join(tmpDir, fmt.Sprintf("progress_%d.png", i)),
+			Filename:     fmt.Sprintf("progress_%d.png", i),
+		}
+	}
+
+	var calls, lastCompleted atomic.Int32
+	o := NewOrchestrator(2)
+	o.SetProgressCallback(func(completed, total int, elapsed time.Duration) {
+		calls.Add(1)
+		lastCompleted.Store(int32(completed))
+	})
+
+	if _, err := o.GenerateAll(specs); err != nil {
+		t.Fatalf("GenerateAll() error = %v", err)
+	}
+
+	// The final update is made before GenerateAll returns
+	after := calls.Load()
+	if after == 0 || lastCompleted.Load() != int32(len(specs)) {
+		t.Fatalf("final progress = %d after %d calls, want %d", lastCompleted.Load(), after, len(specs))
+	}
+	time.Sleep(300 * time.Millisecond)
+	if got := calls.Load(); got != after {
+		t.Errorf("progress callback called %d times after GenerateAll returned", got-after)
+	}
+}
@@ -0,0 +1,213 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"io"
+)
+
+// gifAlphaThreshold is the alpha below which pixels become transparent, as
+// GIF only supports a single fully transparent palette entry
+const gifAlphaThreshold = 128
+
+// encodeGIF encodes image to GIF format, quantizing every frame to a
+// palette and honoring transparency, interlacing and animation options
+func encodeGIF(file io.Writer, img image.Image, opts EncodeOptions) error {
+	anim := &gif.GIF{}
+
+	if a, ok := img.(*Animation); ok {
+		for i, frame := range a.Frames {
+			anim.Image = append(anim.Image, quantizeGIFFrame(frame, opts.Alpha, opts.Interlaced))
+			anim.Delay = append(anim.Delay, a.Delays[i]/10) // GIF delays are in 1/100s
+			anim.Disposal = append(anim.Disposal, gifDisposal(opts.DisposeOp))
+		}
+		anim.LoopCount = gifLoopCount(a.LoopCount)
+	} else {
+		anim.Image = []*image.Paletted{quantizeGIFFrame(img, opts.Alpha, opts.Interlaced)}
+		anim.Delay = []int{0}
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return fmt.Errorf("failed to encode GIF: %w", err)
+	}
+
+	data := buf.Bytes()
+	if opts.Interlaced {
+		if err := setGIFInterlaced(data); err != nil {
+			return fmt.Errorf("failed to interlace GIF: %w", err)
+		}
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write GIF: %w", err)
+	}
+
+	return nil
+}
+
+// gifDisposal maps a disposal operation to its GIF code, restoring to the
+// background when unset
+func gifDisposal(op string) byte {
+	switch op {
+	case DisposeNone:
+		return gif.DisposalNone
+	case DisposePrevious:
+		return gif.DisposalPrevious
+	default:
+		return gif.DisposalBackground
+	}
+}
+
+// gifLoopCount converts a total play count (0 loops forever) into the
+// image/gif convention, where N repeats the animation N+1 times and -1
+// plays it once
+func gifLoopCount(loops int) int {
+	switch {
+	case loops <= 0:
+		return 0
+	case loops == 1:
+		return -1
+	default:
+		return loops - 1
+	}
+}
+
+// quantizeGIFFrame converts an image to a paletted frame. Pixels below the
+// alpha threshold map to a transparent entry when transparency is on; the
+// remaining colors are quantized to fit the palette.
+func quantizeGIFFrame(img image.Image, transparent, interlaced bool) *image.Paletted {
+	bounds := img.Bounds()
+	nrgba := toStraightRGBA(img)
+
+	maxColors := 256
+	if transparent {
+		maxColors = 255
+	}
+
+	// Build a histogram of opaque colors for the palette
+	isTransparent := func(i int) bool {
+		return transparent && nrgba.Pix[i+3] < gifAlphaThreshold
+	}
+	histogram := make(map[color.RGBA]int)
+	for i := 0; i < len(nrgba.Pix); i += 4 {
+		if !isTransparent(i) {
+			histogram[color.RGBA{R: nrgba.Pix[i], G: nrgba.Pix[i+1], B: nrgba.Pix[i+2], A: 255}]++
+		}
+	}
+
+	opaque := QuantizePalette(histogram, maxColors)
+	pal := append(color.Palette{}, opaque...)
+	transparentIndex := uint8(len(pal))
+	if transparent {
+		pal = append(pal, color.RGBA{})
+	}
+
+	paletted := image.NewPaletted(image.Rect(0, 0, bounds.Dx(), bounds.Dy()), pal)
+	cache := make(map[uint32]uint8, len(histogram))
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			i := nrgba.PixOffset(x, y)
+			if isTransparent(i) {
+				paletted.SetColorIndex(x, y, transparentIndex)
+				continue
+			}
+
+			key := uint32(nrgba.Pix[i])<<16 | uint32(nrgba.Pix[i+1])<<8 | uint32(nrgba.Pix[i+2])
+			idx, ok := cache[key]
+			if !ok {
+				idx = uint8(opaque.Index(color.RGBA{R: nrgba.Pix[i], G: nrgba.Pix[i+1], B: nrgba.Pix[i+2], A: 255}))
+				cache[key] = idx
+			}
+			paletted.Pix[paletted.PixOffset(x, y)] = idx
+		}
+	}
+
+	if interlaced {
+		return interlaceRows(paletted)
+	}
+	return paletted
+}
+
+// interlaceRows reorders rows into GIF interlace pass order (every 8th row
+// from 0, every 8th from 4, every 4th from 2, every 2nd from 1), so the
+// encoder's sequential output matches the interlaced layout
+func interlaceRows(src *image.Paletted) *image.Paletted {
+	height := src.Rect.Dy()
+	dst := image.NewPaletted(src.Rect, src.Palette)
+
+	passes := []struct{ start, step int }{{0, 8}, {4, 8}, {2, 4}, {1, 2}}
+	row := 0
+	for _, pass := range passes {
+		for y := pass.start; y < height; y += pass.step {
+			copy(dst.Pix[row*dst.Stride:(row+1)*dst.Stride], src.Pix[y*src.Stride:(y+1)*src.Stride])
+			row++
+		}
+	}
+
+	return dst
+}
+
+// setGIFInterlaced walks the GIF block structure and sets the interlace flag
+// on every image descriptor
+func setGIFInterlaced(data []byte) error {
+	const (
+		flagColorTable = 0x80
+		flagInterlace  = 0x40
+	)
+
+	if len(data) < 13 {
+		return fmt.Errorf("truncated header")
+	}
+
+	pos := 13 // Header and logical screen descriptor
+	if data[10]&flagColorTable != 0 {
+		pos += 3 << (uint(data[10]&0x07) + 1)
+	}
+
+	// skipSubBlocks advances past a sequence of data sub-blocks
+	skipSubBlocks := func(p int) (int, error) {
+		for {
+			if p >= len(data) {
+				return 0, fmt.Errorf("truncated sub-block")
+			}
+			size := int(data[p])
+			p++
+			if size == 0 {
+				return p, nil
+			}
+			p += size
+		}
+	}
+
+	for pos < len(data) {
+		var err error
+		switch data[pos] {
+		case 0x21: // Extension
+			pos, err = skipSubBlocks(pos + 2)
+		case 0x2C: // Image descriptor
+			if pos+10 > len(data) {
+				return fmt.Errorf("truncated image descriptor")
+			}
+			packed := &data[pos+9]
+			*packed |= flagInterlace
+			pos += 10
+			if *packed&flagColorTable != 0 {
+				pos += 3 << (uint(*packed&0x07) + 1)
+			}
+			pos, err = skipSubBlocks(pos + 1) // LZW minimum code size, then image data
+		case 0x3B: // Trailer
+			return nil
+		default:
+			return fmt.Errorf("unexpected block 0x%02x at offset %d", data[pos], pos)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return fmt.Errorf("missing trailer")
+}
@@ -0,0 +1,100 @@
+package generator
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// OverlayCropGuides outlines the center crops of the platform targets on
+// ratio images
+const OverlayCropGuides = "crop-guides"
+
+// CropGuide is a target aspect ratio whose center crop is outlined on an
+// image, labeled with the ratio and the targets sharing it
+type CropGuide struct {
+	Label       string
+	RatioWidth  int
+	RatioHeight int
+}
+
+// cropGuideColors are cycled through in guide order. They contrast with
+// all category backgrounds and stay clear of the fiducial colors so
+// outlines are never mistaken for markers.
+var cropGuideColors = []color.RGBA{
+	{R: 255, G: 255, A: 255},         // yellow
+	{R: 255, G: 105, B: 180, A: 255}, // pink
+	{R: 255, G: 255, B: 255, A: 255}, // white
+	{A: 255},                         // black
+	{R: 75, B: 130, A: 255},          // indigo
+	{R: 160, G: 82, B: 45, A: 255},   // brown
+}
+
+// CenterCrop returns the largest centered window of a width x height image
+// with the aspect ratio ratioWidth:ratioHeight. The window keeps the full
+// extent of the constraining side; the other side is rounded to the nearest
+// pixel and centered, with odd leftover pixels going to the right or
+// bottom.
+func CenterCrop(width, height, ratioWidth, ratioHeight int) image.Rectangle {
+	crop := image.Rect(0, 0, width, height)
+
+	// Compare w/h with rw/rh without floating point
+	if width*ratioHeight > height*ratioWidth {
+		w := max(1, (2*height*ratioWidth+ratioHeight)/(2*ratioHeight))
+		crop.Min.X = (width - w) / 2
+		crop.Max.X = crop.Min.X + w
+	} else if width*ratioHeight < height*ratioWidth {
+		h := max(1, (2*width*ratioHeight+ratioWidth)/(2*ratioWidth))
+		crop.Min.Y = (height - h) / 2
+		crop.Max.Y = crop.Min.Y + h
+	}
+	return crop
+}
+
+// DrawCropGuides outlines the center crop of every crop guide of the spec
+// in its own color, labeled inside the top-left corner. Crops that keep the
+// whole image are not drawn.
+func DrawCropGuides(img *image.RGBA, spec ImageSpec) {
+	thickness := max(2, GetGridSize(spec.Width, spec.Height)/25)
+	face := basicfont.Face7x13
+	ascent := face.Metrics().Ascent.Ceil()
+	lineHeight := face.Metrics().Height.Ceil() + 2
+
+	// Labels start below the top row of fiducials
+	labelTop := 0
+	if len(Fiducials(spec.Width, spec.Height)) > 0 {
+		labelTop = fiducialLabelClearance + FiducialSize(spec.Width, spec.Height) + 4
+	}
+
+	for i, guide := range spec.CropGuides {
+		r := CenterCrop(spec.Width, spec.Height, guide.RatioWidth, guide.RatioHeight)
+		if r == img.Bounds() {
+			continue
+		}
+		c := cropGuideColors[i%len(cropGuideColors)]
+		edge := color.RGBA{A: 255}
+		if c == edge {
+			edge = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+		}
+
+		// 1. Outline inside the crop window, edged for contrast
+		sides := []image.Rectangle{
+			image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+thickness),
+			image.Rect(r.Min.X, r.Max.Y-thickness, r.Max.X, r.Max.Y),
+			image.Rect(r.Min.X, r.Min.Y, r.Min.X+thickness, r.Max.Y),
+			image.Rect(r.Max.X-thickness, r.Min.Y, r.Max.X, r.Max.Y),
+		}
+		for _, side := range sides {
+			fillRect(img, side.Inset(-1), edge)
+		}
+		for _, side := range sides {
+			fillRect(img, side, c)
+		}
+
+		// 2. Label, staggered so boxes sharing an edge stay readable
+		x := r.Min.X + thickness + 3
+		y := max(r.Min.Y+thickness, labelTop) + ascent + 2 + i*lineHeight
+		drawTextWithOutline(img, guide.Label, x, y, face, c, edge)
+	}
+}
@@ -0,0 +1,237 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Supported metadata kinds for EncodeOptions.Metadata
+const (
+	MetadataEXIF = "exif"
+	MetadataXMP  = "xmp"
+	MetadataICC  = "icc"
+)
+
+// SoftwareName identifies the generator in embedded metadata
+const SoftwareName = "futuage-test-image-gen"
+
+// IsValidMetadataKind checks if kind is a supported metadata kind
+func IsValidMetadataKind(kind string) bool {
+	switch strings.ToLower(kind) {
+	case MetadataEXIF, MetadataXMP, MetadataICC:
+		return true
+	default:
+		return false
+	}
+}
+
+// hasMetadata checks if the options request the given metadata kind
+func (o EncodeOptions) hasMetadata(kind string) bool {
+	for _, m := range o.Metadata {
+		if strings.EqualFold(m, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// describeImage returns a one-line description of the image used in metadata
+func describeImage(width, height int) string {
+	return fmt.Sprintf("%s test image %dx%d", SoftwareName, width, height)
+}
+
+// BuildEXIF returns a minimal little-endian TIFF/EXIF block containing
+// ImageDescription, Orientation (top-left) and Software tags. The block has
+// no "Exif\0\0" prefix, as expected by the WebP EXIF chunk.
+func BuildEXIF(width, height int) []byte {
+	type entry struct {
+		tag   uint16
+		typ   uint16
+		count uint32
+		data  []byte
+	}
+
+	const (
+		typeASCII = 2
+		typeShort = 3
+	)
+
+	ascii := func(s string) []byte { return append([]byte(s), 0) }
+	short := func(v uint16) []byte {
+		b := make([]byte, 4)
+		binary.LittleEndian.PutUint16(b, v)
+		return b
+	}
+
+	description := ascii(describeImage(width, height))
+	software := ascii(SoftwareName)
+
+	// Tags must be sorted in ascending order
+	entries := []entry{
+		{tag: 0x010E, typ: typeASCII, count: uint32(len(description)), data: description}, // ImageDescription
+		{tag: 0x0112, typ: typeShort, count: 1, data: short(1)},                           // Orientation
+		{tag: 0x0131, typ: typeASCII, count: uint32(len(software)), data: software},       // Software
+	}
+
+	const headerSize = 8
+	ifdSize := 2 + len(entries)*12 + 4
+	dataOffset := headerSize + ifdSize
+
+	var buf bytes.Buffer
+	buf.WriteString("II")
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(42))
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(headerSize))
+	_ = binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+
+	var values bytes.Buffer
+	for _, e := range entries {
+		_ = binary.Write(&buf, binary.LittleEndian, e.tag)
+		_ = binary.Write(&buf, binary.LittleEndian, e.typ)
+		_ = binary.Write(&buf, binary.LittleEndian, e.count)
+		if len(e.data) <= 4 {
+			// Value fits inline in the offset field
+			inline := make([]byte, 4)
+			copy(inline, e.data)
+			buf.Write(inline)
+			continue
+		}
+		_ = binary.Write(&buf, binary.LittleEndian, uint32(dataOffset+values.Len()))
+		values.Write(e.data)
+		if values.Len()%2 == 1 {
+			values.WriteByte(0) // Keep offsets word-aligned
+		}
+	}
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(0)) // No next IFD
+	buf.Write(values.Bytes())
+
+	return buf.Bytes()
+}
+
+// BuildXMP returns an XMP packet describing the image
+func BuildXMP(width, height int) []byte {
+	return []byte(fmt.Sprintf(`<?xpacket begin="%s" id="W5M0MpCehiHzreSzNTczkc9d"?>
+<x:xmpmeta xmlns:x="adobe:ns:meta/">
+ <rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+  <rdf:Description rdf:about=""
+    xmlns:xmp="http://ns.adobe.com/xap/1.0/"
+    xmlns:dc="http://purl.org/dc/elements/1.1/"
+    xmlns:tiff="http://ns.adobe.com/tiff/1.0/">
+   <xmp:CreatorTool>%s</xmp:CreatorTool>
+   <dc:description>
+    <rdf:Alt>
+     <rdf:li xml:lang="x-default">%s</rdf:li>
+    </rdf:Alt>
+   </dc:description>
+   <tiff:ImageWidth>%d</tiff:ImageWidth>
+   <tiff:ImageLength>%d</tiff:ImageLength>
+  </rdf:Description>
+ </rdf:RDF>
+</x:xmpmeta>
+<?xpacket end="w"?>`, "\uFEFF", SoftwareName, describeImage(width, height), width, height))
+}
+
+// BuildICCProfile returns a minimal ICC v2 display profile approximating
+// sRGB (sRGB primaries adapted to D50, gamma 2.2 tone curves)
+func BuildICCProfile() []byte {
+	s15Fixed16 := func(v float64) uint32 {
+		return uint32(int32(math.Round(v * 65536)))
+	}
+
+	xyz := func(x, y, z float64) []byte {
+		var b bytes.Buffer
+		b.WriteString("XYZ ")
+		b.Write(make([]byte, 4))
+		for _, v := range []float64{x, y, z} {
+			_ = binary.Write(&b, binary.BigEndian, s15Fixed16(v))
+		}
+		return b.Bytes()
+	}
+
+	textDescription := func(s string) []byte {
+		var b bytes.Buffer
+		b.WriteString("desc")
+		b.Write(make([]byte, 4))
+		_ = binary.Write(&b, binary.BigEndian, uint32(len(s)+1))
+		b.WriteString(s)
+		b.WriteByte(0)
+		b.Write(make([]byte, 4+4+2+1+67)) // Empty Unicode and ScriptCode descriptions
+		return b.Bytes()
+	}
+
+	text := func(s string) []byte {
+		var b bytes.Buffer
+		b.WriteString("text")
+		b.Write(make([]byte, 4))
+		b.WriteString(s)
+		b.WriteByte(0)
+		return b.Bytes()
+	}
+
+	curve := func(gamma float64) []byte {
+		var b bytes.Buffer
+		b.WriteString("curv")
+		b.Write(make([]byte, 4))
+		_ = binary.Write(&b, binary.BigEndian, uint32(1))
+		_ = binary.Write(&b, binary.BigEndian, uint16(math.Round(gamma*256))) // u8Fixed8
+		return b.Bytes()
+	}
+
+	trc := curve(2.2)
+	tags := []struct {
+		sig  string
+		data []byte
+	}{
+		{"desc", textDescription("sRGB (" + SoftwareName + ")")},
+		{"cprt", text("No copyright, use freely")},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4361, 0.2225, 0.0139)},
+		{"gXYZ", xyz(0.3851, 0.7169, 0.0971)},
+		{"bXYZ", xyz(0.1431, 0.0606, 0.7141)},
+		{"rTRC", trc},
+		{"gTRC", trc},
+		{"bTRC", trc},
+	}
+
+	const headerSize = 128
+	tableSize := 4 + len(tags)*12
+
+	// Lay out tag data after the tag table, 4-byte aligned
+	var data bytes.Buffer
+	offsets := make([]uint32, len(tags))
+	for i, tag := range tags {
+		offsets[i] = uint32(headerSize + tableSize + data.Len())
+		data.Write(tag.data)
+		for data.Len()%4 != 0 {
+			data.WriteByte(0)
+		}
+	}
+	totalSize := headerSize + tableSize + data.Len()
+
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, uint32(totalSize))
+	buf.Write(make([]byte, 4))                                   // Preferred CMM
+	_ = binary.Write(&buf, binary.BigEndian, uint32(0x02100000)) // Version 2.1
+	buf.WriteString("mntrRGB XYZ ")                              // Class, color space, PCS
+	for _, v := range []uint16{2025, 12, 4, 0, 0, 0} {           // Creation date
+		_ = binary.Write(&buf, binary.BigEndian, v)
+	}
+	buf.WriteString("acsp")
+	buf.Write(make([]byte, 4+4+4+4+8+4))               // Platform, flags, manufacturer, model, attributes, intent
+	for _, v := range []float64{0.9642, 1.0, 0.8249} { // D50 illuminant
+		_ = binary.Write(&buf, binary.BigEndian, s15Fixed16(v))
+	}
+	buf.Write(make([]byte, headerSize-buf.Len())) // Creator and reserved bytes
+
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(tags)))
+	for i, tag := range tags {
+		buf.WriteString(tag.sig)
+		_ = binary.Write(&buf, binary.BigEndian, offsets[i])
+		_ = binary.Write(&buf, binary.BigEndian, uint32(len(tag.data)))
+	}
+	buf.Write(data.Bytes())
+
+	return buf.Bytes()
+}
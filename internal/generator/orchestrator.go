@@ -6,13 +6,16 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
 )
 
 // GenerationResult represents the result of generating a single image
 type GenerationResult struct {
-	Spec     ImageSpec
-	FileSize int64
-	Error    error
+	Spec        ImageSpec
+	FileSize    int64
+	Fingerprint fingerprint.Fingerprint
+	Error       error
 }
 
 // GenerationStats tracks statistics during generation
@@ -72,7 +75,12 @@ func (o *Orchestrator) GenerateAll(specs []ImageSpec) ([]GenerationResult, error
 
 	// Start progress reporter
 	stopProgress := make(chan struct{})
-	go o.reportProgress(stopProgress)
+	var progressWg sync.WaitGroup
+	progressWg.Add(1)
+	go func() {
+		defer progressWg.Done()
+		o.reportProgress(stopProgress)
+	}()
 
 	// Launch generation goroutines
 	for _, spec := range specs {
@@ -102,7 +110,11 @@ func (o *Orchestrator) GenerateAll(specs []ImageSpec) ([]GenerationResult, error
 	// Wait for all goroutines to complete
 	wg.Wait()
 	close(resultsChan)
+
+	// Stop the progress reporter before returning so its final update
+	// cannot interleave with the caller's output
 	close(stopProgress)
+	progressWg.Wait()
 
 	o.Stats.EndTime = time.Now()
 
@@ -132,14 +144,15 @@ func (o *Orchestrator) generateOne(spec ImageSpec) GenerationResult {
 		return result
 	}
 
-	// Get file size
-	fileSize, err := getFileSize(spec.OutputPath)
+	// Read the file back for its size and hashes
+	data, err := os.ReadFile(spec.OutputPath)
 	if err != nil {
-		result.Error = fmt.Errorf("failed to get file size for %s: %w", spec.Filename, err)
+		result.Error = fmt.Errorf("failed to read %s: %w", spec.Filename, err)
 		return result
 	}
 
-	result.FileSize = fileSize
+	result.FileSize = int64(len(data))
+	result.Fingerprint = fingerprint.Compute(data)
 	return result
 }
 
@@ -184,14 +197,3 @@ func (s *GenerationStats) ImagesPerSecond() float64 {
 	}
 	return float64(s.Completed) / duration.Seconds()
 }
-
-// getFileSize returns the size of a file in bytes
-func getFileSize(path string) (int64, error) {
-	// Import moved to avoid circular dependency
-	// Use the filesystem package helper instead
-	info, err := os.Stat(path)
-	if err != nil {
-		return 0, err
-	}
-	return info.Size(), nil
-}
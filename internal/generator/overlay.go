@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"sort"
+	"strconv"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+)
+
+// Overlay layers drawn on top of the background when selected in the
+// config. All are off by default since they add detail that changes
+// compression results.
+const (
+	OverlayRulers = "rulers"
+)
+
+// overlays maps each overlay layer to its drawing function
+var overlays = map[string]func(img *image.RGBA, spec ImageSpec){
+	OverlayRulers:     func(img *image.RGBA, spec ImageSpec) { DrawRulers(img, spec.Width, spec.Height) },
+	OverlayCropGuides: DrawCropGuides,
+	OverlaySafeZones:  DrawSafeZones,
+}
+
+// OverlayNames returns the names of all overlay layers, sorted
+func OverlayNames() []string {
+	names := make([]string, 0, len(overlays))
+	for name := range overlays {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsValidOverlay reports whether name is a known overlay layer
+func IsValidOverlay(name string) bool {
+	_, ok := overlays[name]
+	return ok
+}
+
+// DrawOverlays draws the overlay layers selected in the spec, in order
+func DrawOverlays(img *image.RGBA, spec ImageSpec) error {
+	for _, name := range spec.Overlays {
+		draw, ok := overlays[name]
+		if !ok {
+			return fmt.Errorf("unknown overlay: %s", name)
+		}
+		draw(img, spec)
+	}
+	return nil
+}
+
+// rulerCornerClearance keeps ruler labels clear of the corner markers
+const rulerCornerClearance = 40
+
+// DrawRulers draws rulers along all four edges with tick marks every tenth,
+// half and full grid cell (10, 50 and 100px on large images) and labels at
+// each full cell in source pixel coordinates
+func DrawRulers(img *image.RGBA, width, height int) {
+	grid := GetGridSize(width, height)
+	long := max(6, grid/6)
+
+	drawRuler(img, width, grid, long, func(pos, length int) image.Rectangle {
+		return image.Rect(pos, 0, pos+1, length) // top
+	})
+	drawRuler(img, width, grid, long, func(pos, length int) image.Rectangle {
+		return image.Rect(pos, height-length, pos+1, height) // bottom
+	})
+	drawRuler(img, height, grid, long, func(pos, length int) image.Rectangle {
+		return image.Rect(0, pos, length, pos+1) // left
+	})
+	drawRuler(img, height, grid, long, func(pos, length int) image.Rectangle {
+		return image.Rect(width-length, pos, width, pos+1) // right
+	})
+
+	// Label every full grid cell away from the corners and fiducials
+	face := basicfont.Face7x13
+	ascent := face.Metrics().Ascent.Ceil()
+	fiducials := Fiducials(width, height)
+	drawLabel := func(label string, x, y int) {
+		r := image.Rect(x, y-ascent, x+font.MeasureString(face, label).Ceil(), y+face.Metrics().Descent.Ceil())
+		for _, f := range fiducials {
+			if r.Overlaps(f.Rect.Inset(-f.Rect.Dx() / 4)) {
+				return
+			}
+		}
+		drawTextWithOutline(img, label, x, y, face, color.RGBA{R: 255, G: 255, B: 255, A: 255}, color.RGBA{A: 255})
+	}
+	for x := grid; x < width-rulerCornerClearance; x += grid {
+		if x < rulerCornerClearance {
+			continue
+		}
+		label := strconv.Itoa(x)
+		drawLabel(label, x+3, long+ascent)
+		drawLabel(label, x+3, height-long-2)
+	}
+	for y := grid; y < height-rulerCornerClearance; y += grid {
+		if y < rulerCornerClearance {
+			continue
+		}
+		label := strconv.Itoa(y)
+		drawLabel(label, long+3, y+ascent/2)
+		drawLabel(label, width-long-3-font.MeasureString(face, label).Ceil(), y+ascent/2)
+	}
+}
+
+// drawRuler draws the ticks of one edge of the given length. tick returns
+// the rectangle of a tick at a position along the edge.
+func drawRuler(img *image.RGBA, length, grid, long int, tick func(pos, length int) image.Rectangle) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	black := color.RGBA{A: 255}
+
+	// Minor ticks are dropped when they would be closer than 4px
+	divisions := 10
+	if grid/divisions < 4 {
+		divisions = 2
+	}
+
+	for i := 0; ; i++ {
+		pos := i * grid / divisions
+		if pos >= length {
+			break
+		}
+
+		size := long / 3
+		switch {
+		case i%divisions == 0:
+			size = long
+		case divisions == 10 && i%5 == 0:
+			size = long * 2 / 3
+		}
+
+		// Outline the tick so it shows on light and dark content
+		r := tick(pos, size)
+		fillRect(img, r.Inset(-1), white)
+		fillRect(img, r, black)
+	}
+}
@@ -0,0 +1,51 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+)
+
+// PageSet holds the rendered pages of a multi-page document. Like Animation
+// it implements image.Image by delegating to the first page, so encoders
+// without multi-page support still produce a valid single image.
+type PageSet struct {
+	Pages []*image.RGBA
+}
+
+// ColorModel returns the color model of the first page
+func (p *PageSet) ColorModel() color.Model {
+	return p.Pages[0].ColorModel()
+}
+
+// Bounds returns the bounds of the first page
+func (p *PageSet) Bounds() image.Rectangle {
+	return p.Pages[0].Bounds()
+}
+
+// At returns the color of the first page at (x, y)
+func (p *PageSet) At(x, y int) color.Color {
+	return p.Pages[0].At(x, y)
+}
+
+// RenderPages renders every page of a multi-page spec. Each page shows its
+// number in the text overlay and a progress bar along the bottom edge.
+func RenderPages(spec ImageSpec) (*PageSet, error) {
+	pageCount := spec.Options.Pages
+	if pageCount < 1 {
+		pageCount = 1
+	}
+
+	set := &PageSet{Pages: make([]*image.RGBA, 0, pageCount)}
+	for i := 0; i < pageCount; i++ {
+		page, err := renderFrame(spec, []string{fmt.Sprintf("Page %d/%d", i+1, pageCount)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to render page %d: %w", i+1, err)
+		}
+		drawFrameProgress(page, i, pageCount)
+
+		set.Pages = append(set.Pages, page)
+	}
+
+	return set, nil
+}
@@ -0,0 +1,535 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// QR codes are encoded in byte mode with error correction level M (15%
+// recovery) using versions 1-6, which hold up to 106 bytes. Larger
+// versions need version information blocks and are not supported.
+const (
+	qrMaxVersion  = 6
+	qrQuietZone   = 4 // modules of white border around the symbol
+	qrModeByte    = 0x4
+	qrFormatMaskM = 0 // format bits of error correction level M
+)
+
+// qrBlocks describes the error correction blocks of a version at level M
+type qrBlocks struct {
+	ecPerBlock   int
+	blocks       int
+	dataPerBlock int
+}
+
+// qrVersionsM is indexed by version
+var qrVersionsM = [qrMaxVersion + 1]qrBlocks{
+	{},
+	{10, 1, 16},
+	{16, 1, 28},
+	{26, 1, 44},
+	{18, 2, 32},
+	{24, 2, 43},
+	{16, 4, 27},
+}
+
+// qrSize returns the width in modules of a version
+func qrSize(version int) int {
+	return 17 + 4*version
+}
+
+// QRCode is an encoded QR symbol
+type QRCode struct {
+	Version int
+	Size    int      // modules per side, without quiet zone
+	Modules [][]bool // [y][x], true is dark
+}
+
+// EncodeQR encodes text as a QR code, choosing the smallest version that
+// fits
+func EncodeQR(text string) (*QRCode, error) {
+	data := []byte(text)
+
+	// 1. Pick the version
+	version := 0
+	for v := 1; v <= qrMaxVersion; v++ {
+		b := qrVersionsM[v]
+		if len(data) <= (b.blocks*b.dataPerBlock*8-12)/8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("QR payload of %d bytes is too long", len(data))
+	}
+	blocks := qrVersionsM[version]
+	capacity := blocks.blocks * blocks.dataPerBlock
+
+	// 2. Build the data bit stream: mode, length, bytes, terminator, padding
+	var bits qrBitBuffer
+	bits.append(qrModeByte, 4)
+	bits.append(len(data), 8)
+	for _, b := range data {
+		bits.append(int(b), 8)
+	}
+	bits.append(0, min(4, capacity*8-len(bits)))
+	bits.append(0, (8-len(bits)%8)%8)
+	codewords := bits.bytes()
+	for pad := 0; len(codewords) < capacity; pad++ {
+		codewords = append(codewords, []byte{0xEC, 0x11}[pad%2])
+	}
+
+	// 3. Add error correction and interleave the blocks
+	interleaved := qrInterleave(codewords, blocks)
+
+	// 4. Place function patterns and data, then pick the best mask
+	var best *QRCode
+	bestPenalty := 0
+	for mask := 0; mask < 8; mask++ {
+		qr := newQRMatrix(version)
+		qr.placeData(interleaved, mask)
+		qr.placeFormat(mask)
+		if penalty := qr.penalty(); best == nil || penalty < bestPenalty {
+			best, bestPenalty = qr.QRCode, penalty
+		}
+	}
+	return best, nil
+}
+
+// qrBitBuffer accumulates bits, most significant first
+type qrBitBuffer []bool
+
+func (b *qrBitBuffer) append(value, count int) {
+	for i := count - 1; i >= 0; i-- {
+		*b = append(*b, (value>>i)&1 == 1)
+	}
+}
+
+func (b qrBitBuffer) bytes() []byte {
+	out := make([]byte, len(b)/8)
+	for i, bit := range b {
+		if bit {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+// qrInterleave splits data into blocks, appends each block's error
+// correction codewords and interleaves them
+func qrInterleave(data []byte, b qrBlocks) []byte {
+	generator := rsGenerator(b.ecPerBlock)
+	dataBlocks := make([][]byte, b.blocks)
+	ecBlocks := make([][]byte, b.blocks)
+	for i := range dataBlocks {
+		dataBlocks[i] = data[i*b.dataPerBlock : (i+1)*b.dataPerBlock]
+		ecBlocks[i] = rsEncode(dataBlocks[i], generator)
+	}
+
+	out := make([]byte, 0, b.blocks*(b.dataPerBlock+b.ecPerBlock))
+	for i := 0; i < b.dataPerBlock; i++ {
+		for _, block := range dataBlocks {
+			out = append(out, block[i])
+		}
+	}
+	for i := 0; i < b.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			out = append(out, block[i])
+		}
+	}
+	return out
+}
+
+// qrMatrix is a QR code under construction, tracking which modules belong
+// to function patterns
+type qrMatrix struct {
+	*QRCode
+	function [][]bool
+}
+
+// newQRMatrix draws the function patterns of a version
+func newQRMatrix(version int) *qrMatrix {
+	size := qrSize(version)
+	m := &qrMatrix{
+		QRCode:   &QRCode{Version: version, Size: size, Modules: make([][]bool, size)},
+		function: make([][]bool, size),
+	}
+	for y := range m.Modules {
+		m.Modules[y] = make([]bool, size)
+		m.function[y] = make([]bool, size)
+	}
+
+	// Timing patterns
+	for i := 0; i < size; i++ {
+		m.set(6, i, i%2 == 0)
+		m.set(i, 6, i%2 == 0)
+	}
+
+	// Finder patterns with separators
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x < 0 || y < 0 || x >= size || y >= size {
+					continue
+				}
+				d := max(abs(dx), abs(dy))
+				m.set(x, y, d != 2 && d != 4)
+			}
+		}
+	}
+
+	// Single alignment pattern of versions 2-6
+	if version >= 2 {
+		c := size - 7
+		for dy := -2; dy <= 2; dy++ {
+			for dx := -2; dx <= 2; dx++ {
+				m.set(c+dx, c+dy, max(abs(dx), abs(dy)) != 1)
+			}
+		}
+	}
+
+	// Reserve the format areas; placeFormat fills them in
+	m.placeFormat(0)
+	return m
+}
+
+// set draws a function module
+func (m *qrMatrix) set(x, y int, dark bool) {
+	m.Modules[y][x] = dark
+	m.function[y][x] = true
+}
+
+// placeData writes codewords in the zigzag order, masked
+func (m *qrMatrix) placeData(data []byte, mask int) {
+	i := 0
+	qrDataOrder(m.Size, m.function, func(x, y int) {
+		dark := false
+		if i < len(data)*8 {
+			dark = data[i/8]&(0x80>>(i%8)) != 0
+		}
+		m.Modules[y][x] = dark != qrMask(mask, x, y)
+		i++
+	})
+}
+
+// placeFormat writes both copies of the format information
+func (m *qrMatrix) placeFormat(mask int) {
+	bits := qrFormatBits(mask)
+	for i, p := range qrFormatPositions(m.Size) {
+		dark := (bits>>(i%15))&1 == 1
+		m.set(p[0], p[1], dark)
+	}
+	m.set(8, m.Size-8, true) // always dark
+}
+
+// qrFormatBits returns the 15 BCH-protected format bits of level M and a
+// mask
+func qrFormatBits(mask int) int {
+	data := qrFormatMaskM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	return (data<<10 | rem) ^ 0x5412
+}
+
+// qrFormatPositions returns the module of each format bit: bits 0-14 of
+// the first copy, then bits 0-14 of the second copy
+func qrFormatPositions(size int) [][2]int {
+	positions := make([][2]int, 0, 30)
+	for i := 0; i <= 5; i++ {
+		positions = append(positions, [2]int{8, i})
+	}
+	positions = append(positions, [2]int{8, 7}, [2]int{8, 8}, [2]int{7, 8})
+	for i := 9; i < 15; i++ {
+		positions = append(positions, [2]int{14 - i, 8})
+	}
+	for i := 0; i < 8; i++ {
+		positions = append(positions, [2]int{size - 1 - i, 8})
+	}
+	for i := 8; i < 15; i++ {
+		positions = append(positions, [2]int{8, size - 15 + i})
+	}
+	return positions
+}
+
+// qrDataOrder calls fn for every data module in placement order: pairs of
+// columns from the right, alternating upwards and downwards, skipping the
+// vertical timing pattern
+func qrDataOrder(size int, function [][]bool, fn func(x, y int)) {
+	for right := size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < size; vert++ {
+			y := vert
+			if upward {
+				y = size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				if x := right - j; !function[y][x] {
+					fn(x, y)
+				}
+			}
+		}
+	}
+}
+
+// qrMask reports whether a mask pattern inverts module (x, y)
+func qrMask(mask, x, y int) bool {
+	switch mask {
+	case 0:
+		return (x+y)%2 == 0
+	case 1:
+		return y%2 == 0
+	case 2:
+		return x%3 == 0
+	case 3:
+		return (x+y)%3 == 0
+	case 4:
+		return (x/3+y/2)%2 == 0
+	case 5:
+		return x*y%2+x*y%3 == 0
+	case 6:
+		return (x*y%2+x*y%3)%2 == 0
+	default:
+		return ((x+y)%2+x*y%3)%2 == 0
+	}
+}
+
+// penalty scores a masked symbol by the four rules of ISO/IEC 18004;
+// lower is easier to read
+func (m *qrMatrix) penalty() int {
+	size := m.Size
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return m.Modules[x][y]
+		}
+		return m.Modules[y][x]
+	}
+
+	score, dark := 0, 0
+	finderLike := []string{"10111010000", "00001011101"}
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < size; y++ {
+			// Rule 1: runs of five or more same-colored modules
+			run := 1
+			var line strings.Builder
+			for x := 0; x < size; x++ {
+				if at(x, y, transpose) {
+					line.WriteByte('1')
+				} else {
+					line.WriteByte('0')
+				}
+				if x > 0 && at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					if run == 5 {
+						score += 3
+					} else if run > 5 {
+						score++
+					}
+				} else {
+					run = 1
+				}
+			}
+
+			// Rule 3: finder-like patterns
+			for _, pattern := range finderLike {
+				score += 40 * strings.Count(line.String(), pattern)
+			}
+		}
+	}
+
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if m.Modules[y][x] {
+				dark++
+			}
+			// Rule 2: 2x2 blocks of one color
+			if x > 0 && y > 0 {
+				c := m.Modules[y][x]
+				if c == m.Modules[y-1][x] && c == m.Modules[y][x-1] && c == m.Modules[y-1][x-1] {
+					score += 3
+				}
+			}
+		}
+	}
+
+	// Rule 4: balance of dark and light modules
+	percent := dark * 100 / (size * size)
+	score += abs(percent-50) / 5 * 10
+	return score
+}
+
+// Draw renders the symbol with its quiet zone at (x0, y0) with square
+// modules of the given size in pixels
+func (q *QRCode) Draw(img *image.RGBA, x0, y0, moduleSize int) {
+	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	black := color.RGBA{A: 255}
+
+	total := (q.Size + 2*qrQuietZone) * moduleSize
+	fillRect(img, image.Rect(x0, y0, x0+total, y0+total), white)
+	for y, row := range q.Modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			px := x0 + (x+qrQuietZone)*moduleSize
+			py := y0 + (y+qrQuietZone)*moduleSize
+			fillRect(img, image.Rect(px, py, px+moduleSize, py+moduleSize), black)
+		}
+	}
+}
+
+// fillRect fills the part of r inside img with a solid color
+func fillRect(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	r = r.Intersect(img.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// The spec label is kept inside the region that survives center crops to
+// any aspect ratio from 9:16 portrait to 1.91:1 landscape, which covers the
+// feed, story and link preview crops of the common platforms
+const (
+	labelSafeRatioMin = 9.0 / 16
+	labelSafeRatioMax = 1.91
+)
+
+// LabelSafeRect returns the centered region of an image kept by every
+// center crop between 9:16 and 1.91:1
+func LabelSafeRect(width, height int) image.Rectangle {
+	w := min(width, int(float64(height)*labelSafeRatioMin))
+	h := min(height, int(float64(width)/labelSafeRatioMax))
+	x0, y0 := (width-w)/2, (height-h)/2
+	return image.Rect(x0, y0, x0+w, y0+h)
+}
+
+// LabelLayout is the placement of the text block and spec label QR code
+type LabelLayout struct {
+	ModuleSize  int         // pixels per module, 0 if the label does not fit
+	Origin      image.Point // top-left corner of the quiet zone
+	Side        int         // width of the label including the quiet zone
+	TextCenterY int         // vertical center of the text block
+}
+
+// LayoutLabel places the text block and a QR symbol of symbolSize modules
+// in the crop-safe region. Images with fiducials get the text above the
+// center marker and the label below it; smaller images stack both around
+// the center. The label takes at most half of the region's shorter side and
+// is left out when not even one pixel per module fits.
+func LayoutLabel(width, height, symbolSize, textHeight int) LabelLayout {
+	safe := LabelSafeRect(width, height)
+	gap := int(GetFontSize(width, height) * 0.75)
+	modules := symbolSize + 2*qrQuietZone
+	limit := min(safe.Dx(), safe.Dy()) / 2
+
+	// 1. Split around the center marker
+	if center, ok := centerFiducial(width, height); ok {
+		layout := LabelLayout{TextCenterY: center.Min.Y - gap - textHeight/2}
+		top := center.Max.Y + gap
+		moduleSize := min(safe.Dx(), safe.Max.Y-top, limit) / modules
+		if moduleSize < 1 {
+			return layout
+		}
+		layout.ModuleSize = moduleSize
+		layout.Side = moduleSize * modules
+		layout.Origin = image.Pt((width-layout.Side)/2, top)
+		return layout
+	}
+
+	// 2. Stack text and label around the center
+	layout := LabelLayout{TextCenterY: height / 2}
+	moduleSize := min(safe.Dx(), safe.Dy()-textHeight-gap, limit) / modules
+	if moduleSize < 1 {
+		return layout
+	}
+
+	side := moduleSize * modules
+	top := (height - (textHeight + gap + side)) / 2
+	return LabelLayout{
+		ModuleSize:  moduleSize,
+		Origin:      image.Pt((width-side)/2, top+textHeight+gap),
+		Side:        side,
+		TextCenterY: top + textHeight/2,
+	}
+}
+
+// SpecIDLength is the number of hex digits of the spec fingerprint used as
+// a short, stable image ID
+const SpecIDLength = 16
+
+// specPayloadPrefix marks QR payloads written by the generator
+const specPayloadPrefix = "FTIG1"
+
+// ID returns the short, stable ID of the spec
+func (s ImageSpec) ID() (string, error) {
+	fingerprint, err := s.Fingerprint()
+	if err != nil {
+		return "", err
+	}
+	return fingerprint[:SpecIDLength], nil
+}
+
+// SpecLabel is the information carried by an image's QR code
+type SpecLabel struct {
+	ID      string
+	Width   int
+	Height  int
+	Format  string
+	Quality int
+	Variant string
+}
+
+// SpecPayload returns the QR payload of a spec, e.g.
+// "FTIG1:0123456789abcdef:1000x1500:jpeg:q82" with ":<variant>" appended
+// for variants
+func SpecPayload(spec ImageSpec) (string, error) {
+	id, err := spec.ID()
+	if err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%s:%s:%dx%d:%s:q%d", specPayloadPrefix, id,
+		spec.Width, spec.Height, strings.ToLower(spec.Format), spec.Quality)
+	if spec.Variant != "" {
+		payload += ":" + spec.Variant
+	}
+	return payload, nil
+}
+
+// ParseSpecPayload parses a QR payload written by SpecPayload
+func ParseSpecPayload(payload string) (SpecLabel, error) {
+	parts := strings.Split(payload, ":")
+	if len(parts) < 5 || len(parts) > 6 || parts[0] != specPayloadPrefix {
+		return SpecLabel{}, fmt.Errorf("not a test image label: %q", payload)
+	}
+
+	label := SpecLabel{ID: parts[1], Format: parts[3]}
+	if _, err := fmt.Sscanf(parts[2], "%dx%d", &label.Width, &label.Height); err != nil {
+		return SpecLabel{}, fmt.Errorf("invalid dimensions in label: %q", parts[2])
+	}
+	quality, err := strconv.Atoi(strings.TrimPrefix(parts[4], "q"))
+	if err != nil {
+		return SpecLabel{}, fmt.Errorf("invalid quality in label: %q", parts[4])
+	}
+	label.Quality = quality
+	if len(parts) == 6 {
+		label.Variant = parts[5]
+	}
+	return label, nil
+}
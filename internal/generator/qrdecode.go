@@ -0,0 +1,402 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// ErrNoQRCode is returned when no readable QR code is found in an image
+var ErrNoQRCode = errors.New("no QR code found")
+
+// qrMaxCandidates limits how many finder pattern candidates are combined
+// into symbol guesses
+const qrMaxCandidates = 8
+
+// DecodeQR finds a QR code written by EncodeQR in img and returns its text.
+// The symbol may be scaled, cropped around, rotated by multiples of 90
+// degrees, mirrored and lossily compressed, but not skewed in perspective.
+func DecodeQR(img image.Image) (string, error) {
+	// 1. Binarize the image composited over white
+	bin := newBinaryImage(img)
+
+	// 2. Locate finder patterns
+	finders := bin.findFinders()
+	if len(finders) < 3 {
+		return "", ErrNoQRCode
+	}
+
+	// 3. Try each plausible triple of finders, strongest first
+	var lastErr error = ErrNoQRCode
+	for i := 0; i < len(finders); i++ {
+		for j := i + 1; j < len(finders); j++ {
+			for k := j + 1; k < len(finders); k++ {
+				text, err := bin.decodeTriple(finders[i], finders[j], finders[k])
+				if err == nil {
+					return text, nil
+				}
+				if !errors.Is(err, ErrNoQRCode) {
+					lastErr = err
+				}
+			}
+		}
+	}
+	return "", lastErr
+}
+
+// binaryImage is a thresholded image; true is dark
+type binaryImage struct {
+	width, height int
+	luma          []uint8
+}
+
+func newBinaryImage(img image.Image) *binaryImage {
+	b := img.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Over)
+
+	bin := &binaryImage{width: b.Dx(), height: b.Dy(), luma: make([]uint8, b.Dx()*b.Dy())}
+	for i := range bin.luma {
+		p := rgba.Pix[i*4 : i*4+3]
+		bin.luma[i] = uint8((299*int(p[0]) + 587*int(p[1]) + 114*int(p[2])) / 1000)
+	}
+	return bin
+}
+
+func (b *binaryImage) dark(x, y int) bool {
+	if x < 0 || y < 0 || x >= b.width || y >= b.height {
+		return false
+	}
+	return b.luma[y*b.width+x] < 128
+}
+
+// sample reports whether the area around (x, y) is mostly dark
+func (b *binaryImage) sample(x, y float64, radius int) bool {
+	cx, cy := int(math.Floor(x)), int(math.Floor(y))
+	dark, total := 0, 0
+	for dy := -radius; dy <= radius; dy++ {
+		for dx := -radius; dx <= radius; dx++ {
+			total++
+			if b.dark(cx+dx, cy+dy) {
+				dark++
+			}
+		}
+	}
+	return dark*2 > total
+}
+
+// finder is a finder pattern candidate
+type finder struct {
+	x, y       float64
+	moduleSize float64
+	hits       int
+}
+
+// findFinders scans rows for the 1:1:3:1:1 dark/light run pattern of
+// finder patterns and confirms each hit along the column through its
+// center
+func (b *binaryImage) findFinders() []finder {
+	var finders []finder
+	for y := 0; y < b.height; y++ {
+		var runs []int
+		runDark, run := b.dark(0, y), 0
+		for x := 0; x <= b.width; x++ {
+			if x < b.width && b.dark(x, y) == runDark {
+				run++
+				continue
+			}
+			runs = append(runs, run)
+			if runDark && len(runs) >= 5 {
+				last := runs[len(runs)-5:]
+				if finderRatio(last) {
+					centerX := float64(x) - float64(last[4]+last[3]) - float64(last[2])/2
+					if f, ok := b.confirmFinder(centerX, y, last); ok {
+						finders = mergeFinder(finders, f)
+					}
+				}
+			}
+			runDark, run = !runDark, 1
+		}
+	}
+
+	// Keep candidates seen on several rows, strongest first
+	sort.SliceStable(finders, func(i, j int) bool { return finders[i].hits > finders[j].hits })
+	kept := finders[:0]
+	for _, f := range finders {
+		if f.hits >= 2 && len(kept) < qrMaxCandidates {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// finderRatio checks five run lengths against the 1:1:3:1:1 pattern
+func finderRatio(runs []int) bool {
+	total := 0
+	for _, r := range runs {
+		if r == 0 {
+			return false
+		}
+		total += r
+	}
+	if total < 7 {
+		return false
+	}
+	unit := float64(total) / 7
+	variance := unit / 2
+	return math.Abs(unit-float64(runs[0])) < variance &&
+		math.Abs(unit-float64(runs[1])) < variance &&
+		math.Abs(3*unit-float64(runs[2])) < 3*variance &&
+		math.Abs(unit-float64(runs[3])) < variance &&
+		math.Abs(unit-float64(runs[4])) < variance
+}
+
+// confirmFinder checks the column through a horizontal hit and returns the
+// refined center
+func (b *binaryImage) confirmFinder(centerX float64, y int, rowRuns []int) (finder, bool) {
+	x := int(centerX)
+	if !b.dark(x, y) {
+		return finder{}, false
+	}
+
+	// Walk up and down from the center collecting the five runs
+	var runs [5]int
+	top := y
+	for top >= 0 && b.dark(x, top) {
+		runs[2]++
+		top--
+	}
+	bottom := y + 1
+	for bottom < b.height && b.dark(x, bottom) {
+		runs[2]++
+		bottom++
+	}
+	walk := func(from, step int, dark bool, limit int) (int, int) {
+		n := 0
+		for from >= 0 && from < b.height && b.dark(x, from) == dark && n <= limit {
+			n++
+			from += step
+		}
+		return n, from
+	}
+	limit := runs[2] * 2
+	var pos int
+	runs[1], pos = walk(top, -1, false, limit)
+	runs[0], _ = walk(pos, -1, true, limit)
+	runs[3], pos = walk(bottom, 1, false, limit)
+	runs[4], _ = walk(pos, 1, true, limit)
+	if !finderRatio(runs[:]) {
+		return finder{}, false
+	}
+
+	rowTotal, columnTotal := 0, 0
+	for i := range runs {
+		rowTotal += rowRuns[i]
+		columnTotal += runs[i]
+	}
+	if columnTotal*2 < rowTotal || rowTotal*2 < columnTotal {
+		return finder{}, false
+	}
+
+	return finder{
+		x:          centerX,
+		y:          float64(top+1+bottom) / 2,
+		moduleSize: float64(rowTotal+columnTotal) / 14,
+		hits:       1,
+	}, true
+}
+
+// mergeFinder folds a candidate into a nearby existing one or appends it
+func mergeFinder(finders []finder, f finder) []finder {
+	for i, e := range finders {
+		if math.Abs(e.x-f.x) <= e.moduleSize*2 && math.Abs(e.y-f.y) <= e.moduleSize*2 &&
+			math.Abs(e.moduleSize-f.moduleSize) <= e.moduleSize/2 {
+			n := float64(e.hits)
+			finders[i] = finder{
+				x:          (e.x*n + f.x) / (n + 1),
+				y:          (e.y*n + f.y) / (n + 1),
+				moduleSize: (e.moduleSize*n + f.moduleSize) / (n + 1),
+				hits:       e.hits + 1,
+			}
+			return finders
+		}
+	}
+	return append(finders, f)
+}
+
+// decodeTriple treats three finders as the corners of a symbol
+func (b *binaryImage) decodeTriple(p, q, r finder) (string, error) {
+	moduleSize := (p.moduleSize + q.moduleSize + r.moduleSize) / 3
+	for _, f := range []finder{p, q, r} {
+		if math.Abs(f.moduleSize-moduleSize) > moduleSize/3 {
+			return "", ErrNoQRCode
+		}
+	}
+
+	// 1. The top-left finder is opposite the longest side
+	dist := func(a, c finder) float64 { return math.Hypot(a.x-c.x, a.y-c.y) }
+	pq, qr, rp := dist(p, q), dist(q, r), dist(r, p)
+	topLeft, right, bottom := p, q, r
+	switch {
+	case pq >= qr && pq >= rp:
+		topLeft, right, bottom = r, p, q
+	case rp >= qr && rp >= pq:
+		topLeft, right, bottom = q, r, p
+	}
+
+	// 2. Require a right angle between two legs of similar length
+	ux, uy := right.x-topLeft.x, right.y-topLeft.y
+	vx, vy := bottom.x-topLeft.x, bottom.y-topLeft.y
+	legU, legV := math.Hypot(ux, uy), math.Hypot(vx, vy)
+	if legU/legV > 1.4 || legV/legU > 1.4 || math.Abs(ux*vx+uy*vy)/(legU*legV) > 0.2 {
+		return "", ErrNoQRCode
+	}
+	if ux*vy-uy*vx < 0 {
+		right, bottom = bottom, right
+	}
+
+	// 3. Estimate the size and read the grid; mirrored symbols read
+	// transposed, so retry with the legs swapped
+	estimate := int(math.Round((legU+legV)/2/moduleSize)) + 7
+	var lastErr error = ErrNoQRCode
+	for _, size := range qrSizeGuesses(estimate) {
+		for _, legs := range [][2]finder{{right, bottom}, {bottom, right}} {
+			text, err := b.readSymbol(topLeft, legs[0], legs[1], size)
+			if err == nil {
+				return text, nil
+			}
+			lastErr = err
+		}
+	}
+	return "", lastErr
+}
+
+// qrSizeGuesses returns the supported symbol sizes nearest to an estimate
+func qrSizeGuesses(estimate int) []int {
+	var sizes []int
+	for v := 1; v <= qrMaxVersion; v++ {
+		if abs(qrSize(v)-estimate) <= 4 {
+			sizes = append(sizes, qrSize(v))
+		}
+	}
+	sort.Slice(sizes, func(i, j int) bool {
+		return abs(sizes[i]-estimate) < abs(sizes[j]-estimate)
+	})
+	return sizes
+}
+
+// readSymbol samples a grid of size modules whose finder centers are at
+// topLeft, right and bottom, then decodes it
+func (b *binaryImage) readSymbol(topLeft, right, bottom finder, size int) (string, error) {
+	version := (size - 17) / 4
+	span := float64(size - 7)
+	radius := int(topLeft.moduleSize / 4)
+
+	// 1. Sample every module
+	modules := make([][]bool, size)
+	for y := range modules {
+		modules[y] = make([]bool, size)
+		for x := range modules[y] {
+			u := (float64(x) - 3) / span
+			v := (float64(y) - 3) / span
+			px := topLeft.x + u*(right.x-topLeft.x) + v*(bottom.x-topLeft.x)
+			py := topLeft.y + u*(right.y-topLeft.y) + v*(bottom.y-topLeft.y)
+			modules[y][x] = b.sample(px, py, radius)
+		}
+	}
+
+	// 2. Read the format information
+	mask, err := qrReadFormat(modules)
+	if err != nil {
+		return "", err
+	}
+
+	// 3. Read and unmask the codewords
+	blocks := qrVersionsM[version]
+	total := blocks.blocks * (blocks.dataPerBlock + blocks.ecPerBlock)
+	codewords := make([]byte, total)
+	i := 0
+	qrDataOrder(size, newQRMatrix(version).function, func(x, y int) {
+		if i < total*8 && modules[y][x] != qrMask(mask, x, y) {
+			codewords[i/8] |= 0x80 >> (i % 8)
+		}
+		i++
+	})
+
+	// 4. De-interleave and correct each block
+	data := make([]byte, 0, blocks.blocks*blocks.dataPerBlock)
+	perBlock := make([][]byte, blocks.blocks)
+	for n := range perBlock {
+		perBlock[n] = make([]byte, blocks.dataPerBlock+blocks.ecPerBlock)
+	}
+	k := 0
+	for pos := 0; pos < blocks.dataPerBlock+blocks.ecPerBlock; pos++ {
+		for n := range perBlock {
+			perBlock[n][pos] = codewords[k]
+			k++
+		}
+	}
+	for _, block := range perBlock {
+		if err := rsCorrect(block, blocks.ecPerBlock); err != nil {
+			return "", fmt.Errorf("QR code is unreadable: %w", err)
+		}
+		data = append(data, block[:blocks.dataPerBlock]...)
+	}
+
+	// 5. Parse the byte mode segment
+	return qrParseData(data)
+}
+
+// qrReadFormat returns the mask of the format information copy closest to
+// a valid level M code
+func qrReadFormat(modules [][]bool) (int, error) {
+	positions := qrFormatPositions(len(modules))
+	bestMask, bestDistance := -1, 4
+	for copyStart := 0; copyStart < len(positions); copyStart += 15 {
+		read := 0
+		for i := 0; i < 15; i++ {
+			p := positions[copyStart+i]
+			if modules[p[1]][p[0]] {
+				read |= 1 << i
+			}
+		}
+		for mask := 0; mask < 8; mask++ {
+			if d := bitCount(read ^ qrFormatBits(mask)); d < bestDistance {
+				bestMask, bestDistance = mask, d
+			}
+		}
+	}
+	if bestMask < 0 {
+		return 0, fmt.Errorf("QR format information is unreadable")
+	}
+	return bestMask, nil
+}
+
+// qrParseData decodes a single byte mode segment
+func qrParseData(data []byte) (string, error) {
+	if len(data) < 2 || data[0]>>4 != qrModeByte {
+		return "", fmt.Errorf("unsupported QR data mode")
+	}
+	length := int(data[0]&0x0F)<<4 | int(data[1]>>4)
+	if 2+length > len(data) {
+		return "", fmt.Errorf("QR data length %d exceeds capacity", length)
+	}
+	text := make([]byte, length)
+	for i := range text {
+		text[i] = data[1+i]<<4 | data[2+i]>>4
+	}
+	return string(text), nil
+}
+
+func bitCount(v int) int {
+	n := 0
+	for ; v != 0; v &= v - 1 {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,138 @@
+package generator
+
+import (
+	"image/color"
+	"sort"
+)
+
+// colorCount is a histogram entry used by the median-cut quantizer
+type colorCount struct {
+	color color.RGBA
+	count int
+}
+
+// QuantizePalette builds a palette of at most maxColors entries from a color
+// histogram. Images with few colors keep them exactly; otherwise the colors
+// are reduced with weighted median cut.
+func QuantizePalette(histogram map[color.RGBA]int, maxColors int) color.Palette {
+	entries := make([]colorCount, 0, len(histogram))
+	for c, n := range histogram {
+		entries = append(entries, colorCount{color: c, count: n})
+	}
+
+	// Sort by popularity for a deterministic palette order
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].count != entries[j].count {
+			return entries[i].count > entries[j].count
+		}
+		return packRGBA(entries[i].color) < packRGBA(entries[j].color)
+	})
+
+	if len(entries) <= maxColors {
+		palette := make(color.Palette, len(entries))
+		for i, e := range entries {
+			palette[i] = e.color
+		}
+		return palette
+	}
+
+	boxes := [][]colorCount{entries}
+	for len(boxes) < maxColors {
+		// Split the box with the widest channel range
+		widest, channel, spread := -1, 0, 0
+		for i, box := range boxes {
+			if len(box) < 2 {
+				continue
+			}
+			if ch, r := widestChannel(box); r > spread {
+				widest, channel, spread = i, ch, r
+			}
+		}
+		if widest < 0 {
+			break
+		}
+
+		low, high := splitBox(boxes[widest], channel)
+		boxes[widest] = low
+		boxes = append(boxes, high)
+	}
+
+	palette := make(color.Palette, len(boxes))
+	for i, box := range boxes {
+		palette[i] = averageColor(box)
+	}
+	return palette
+}
+
+// channelValue returns the R, G or B component of a color
+func channelValue(c color.RGBA, channel int) uint8 {
+	switch channel {
+	case 0:
+		return c.R
+	case 1:
+		return c.G
+	default:
+		return c.B
+	}
+}
+
+// widestChannel returns the channel with the largest value range in a box
+func widestChannel(box []colorCount) (channel, spread int) {
+	for ch := 0; ch < 3; ch++ {
+		lo, hi := 255, 0
+		for _, e := range box {
+			v := int(channelValue(e.color, ch))
+			if v < lo {
+				lo = v
+			}
+			if v > hi {
+				hi = v
+			}
+		}
+		if hi-lo > spread {
+			channel, spread = ch, hi-lo
+		}
+	}
+	return channel, spread
+}
+
+// splitBox splits a box at the pixel-weighted median of a channel
+func splitBox(box []colorCount, channel int) (low, high []colorCount) {
+	sorted := append([]colorCount(nil), box...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return channelValue(sorted[i].color, channel) < channelValue(sorted[j].color, channel)
+	})
+
+	total := 0
+	for _, e := range sorted {
+		total += e.count
+	}
+
+	cut, seen := 1, 0
+	for i, e := range sorted[:len(sorted)-1] {
+		seen += e.count
+		if seen*2 >= total {
+			cut = i + 1
+			break
+		}
+	}
+
+	return sorted[:cut], sorted[cut:]
+}
+
+// averageColor returns the pixel-weighted average color of a box
+func averageColor(box []colorCount) color.RGBA {
+	var r, g, b, n int
+	for _, e := range box {
+		r += int(e.color.R) * e.count
+		g += int(e.color.G) * e.count
+		b += int(e.color.B) * e.count
+		n += e.count
+	}
+	return color.RGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: 255}
+}
+
+// packRGBA packs a color into a sortable integer
+func packRGBA(c color.RGBA) uint32 {
+	return uint32(c.R)<<24 | uint32(c.G)<<16 | uint32(c.B)<<8 | uint32(c.A)
+}
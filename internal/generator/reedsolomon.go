@@ -0,0 +1,171 @@
+package generator
+
+import "fmt"
+
+// Reed-Solomon coding over GF(256) with the QR primitive polynomial
+// x^8 + x^4 + x^3 + x^2 + 1
+
+var gfExp, gfLog = gfTables()
+
+func gfTables() (exp [512]byte, log [256]byte) {
+	x := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(x)
+		log[x] = byte(i)
+		x <<= 1
+		if x&0x100 != 0 {
+			x ^= 0x11D
+		}
+	}
+	for i := 255; i < 512; i++ {
+		exp[i] = exp[i-255]
+	}
+	return exp, log
+}
+
+func gfMul(a, b byte) byte {
+	if a == 0 || b == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+int(gfLog[b])]
+}
+
+func gfDiv(a, b byte) byte {
+	if a == 0 {
+		return 0
+	}
+	return gfExp[int(gfLog[a])+255-int(gfLog[b])]
+}
+
+// gfPow returns alpha^n
+func gfPow(n int) byte {
+	n %= 255
+	if n < 0 {
+		n += 255
+	}
+	return gfExp[n]
+}
+
+// rsGenerator returns the generator polynomial (x - a^0)...(x - a^(n-1)),
+// highest degree first
+func rsGenerator(n int) []byte {
+	g := []byte{1}
+	for i := 0; i < n; i++ {
+		next := make([]byte, len(g)+1)
+		for j, c := range g {
+			next[j] ^= c
+			next[j+1] ^= gfMul(c, gfPow(i))
+		}
+		g = next
+	}
+	return g
+}
+
+// rsEncode returns the error correction codewords of data
+func rsEncode(data, generator []byte) []byte {
+	rem := make([]byte, len(generator)-1)
+	for _, d := range data {
+		factor := d ^ rem[0]
+		copy(rem, rem[1:])
+		rem[len(rem)-1] = 0
+		for i := range rem {
+			rem[i] ^= gfMul(generator[i+1], factor)
+		}
+	}
+	return rem
+}
+
+// rsCorrect corrects up to ecCount/2 byte errors in a block of data
+// followed by ecCount error correction codewords, in place
+func rsCorrect(block []byte, ecCount int) error {
+	n := len(block)
+
+	// 1. Syndromes S_i = r(a^i)
+	syndromes := make([]byte, ecCount)
+	clean := true
+	for i := range syndromes {
+		var s byte
+		for _, c := range block {
+			s = gfMul(s, gfPow(i)) ^ c
+		}
+		syndromes[i] = s
+		clean = clean && s == 0
+	}
+	if clean {
+		return nil
+	}
+
+	// 2. Error locator polynomial by Berlekamp-Massey, lowest degree first
+	locator, prev := []byte{1}, []byte{1}
+	errors, shift, prevDiscrepancy := 0, 1, byte(1)
+	for k := 0; k < ecCount; k++ {
+		discrepancy := syndromes[k]
+		for i := 1; i <= errors && i < len(locator); i++ {
+			discrepancy ^= gfMul(locator[i], syndromes[k-i])
+		}
+		if discrepancy == 0 {
+			shift++
+			continue
+		}
+
+		coef := gfDiv(discrepancy, prevDiscrepancy)
+		next := make([]byte, max(len(locator), len(prev)+shift))
+		copy(next, locator)
+		for i, c := range prev {
+			next[i+shift] ^= gfMul(coef, c)
+		}
+
+		if 2*errors <= k {
+			prev, locator = locator, next
+			errors = k + 1 - errors
+			prevDiscrepancy = discrepancy
+			shift = 1
+		} else {
+			locator = next
+			shift++
+		}
+	}
+	if errors*2 > ecCount {
+		return fmt.Errorf("too many errors")
+	}
+
+	// 3. Chien search: error at power p if locator(a^-p) == 0
+	evaluate := func(poly []byte, x byte) byte {
+		var y byte
+		for i := len(poly) - 1; i >= 0; i-- {
+			y = gfMul(y, x) ^ poly[i]
+		}
+		return y
+	}
+	var powers []int
+	for p := 0; p < n; p++ {
+		if evaluate(locator, gfPow(-p)) == 0 {
+			powers = append(powers, p)
+		}
+	}
+	if len(powers) != errors {
+		return fmt.Errorf("error locator has %d roots, want %d", len(powers), errors)
+	}
+
+	// 4. Forney: magnitude = X * omega(X^-1) / locator'(X^-1)
+	omega := make([]byte, ecCount)
+	for i := 0; i < ecCount; i++ {
+		for j := 0; j <= i && j < len(locator); j++ {
+			omega[i] ^= gfMul(locator[j], syndromes[i-j])
+		}
+	}
+	derivative := make([]byte, len(locator))
+	for i := 1; i < len(locator); i += 2 {
+		derivative[i-1] = locator[i]
+	}
+	for _, p := range powers {
+		xInv := gfPow(-p)
+		denominator := evaluate(derivative, xInv)
+		if denominator == 0 {
+			return fmt.Errorf("invalid error locator")
+		}
+		magnitude := gfMul(gfPow(p), gfDiv(evaluate(omega, xInv), denominator))
+		block[n-1-p] ^= magnitude
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package generator
+
+import (
+	"image"
+	"io"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Encoder writes images in a single file format. Encoders are registered
+// by name with RegisterEncoder, and the config's formats resolve against
+// them, so a fork or wrapper binary can add formats without touching core
+// code.
+type Encoder interface {
+	// Name returns the format name used in config and on the command line
+	Name() string
+	// Extensions returns the file extensions, the first being the default
+	Extensions() []string
+	// MimeType returns the MIME type of encoded files
+	MimeType() string
+	// Options returns the variant options the encoder honors
+	Options() []OptionSpec
+	// Encode writes img to w. img may be an *Animation or *PageSet; encoders
+	// without multi-frame support encode it as a still image.
+	Encode(w io.Writer, img image.Image, opts EncodeOptions) error
+}
+
+// OptionSpec describes a variant option an encoder honors. Name matches the
+// option key in the config, e.g. "lossless" or "frame_delay_ms".
+type OptionSpec struct {
+	Name        string
+	Type        string // bool, int, string or []string
+	Description string
+}
+
+// FuncEncoder adapts an encoding function and its format details to the
+// Encoder interface
+type FuncEncoder struct {
+	FormatName     string
+	FileExtensions []string
+	Mime           string
+	OptionSpecs    []OptionSpec
+	EncodeFunc     func(w io.Writer, img image.Image, opts EncodeOptions) error
+}
+
+// Name returns the format name
+func (e *FuncEncoder) Name() string { return e.FormatName }
+
+// Extensions returns the file extensions
+func (e *FuncEncoder) Extensions() []string { return e.FileExtensions }
+
+// MimeType returns the MIME type
+func (e *FuncEncoder) MimeType() string { return e.Mime }
+
+// Options returns the supported variant options
+func (e *FuncEncoder) Options() []OptionSpec { return e.OptionSpecs }
+
+// Encode calls EncodeFunc
+func (e *FuncEncoder) Encode(w io.Writer, img image.Image, opts EncodeOptions) error {
+	return e.EncodeFunc(w, img, opts)
+}
+
+var (
+	registryMu sync.RWMutex
+	encoders   = make(map[string]Encoder)
+	aliases    = make(map[string]string)
+)
+
+// RegisterEncoder makes an encoder available under its name and any
+// aliases. It panics if the encoder is nil, has no extensions or a name is
+// already registered, as registration happens at init time.
+func RegisterEncoder(enc Encoder, alias ...string) {
+	if enc == nil {
+		panic("generator: RegisterEncoder encoder is nil")
+	}
+	if len(enc.Extensions()) == 0 {
+		panic("generator: RegisterEncoder encoder " + enc.Name() + " has no extensions")
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := strings.ToLower(enc.Name())
+	for _, n := range append([]string{name}, alias...) {
+		n = strings.ToLower(n)
+		if _, dup := encoders[n]; dup {
+			panic("generator: RegisterEncoder called twice for " + n)
+		}
+		if _, dup := aliases[n]; dup {
+			panic("generator: RegisterEncoder called twice for " + n)
+		}
+	}
+
+	encoders[name] = enc
+	for _, a := range alias {
+		aliases[strings.ToLower(a)] = name
+	}
+}
+
+// LookupEncoder returns the encoder registered under a name or alias,
+// ignoring case
+func LookupEncoder(name string) (Encoder, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	name = strings.ToLower(name)
+	if target, ok := aliases[name]; ok {
+		name = target
+	}
+	enc, ok := encoders[name]
+	return enc, ok
+}
+
+// EncoderNames returns the sorted names of all registered encoders
+func EncoderNames() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(encoders))
+	for name := range encoders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SupportsOption checks if an encoder honors the named variant option
+func SupportsOption(enc Encoder, option string) bool {
+	for _, spec := range enc.Options() {
+		if spec.Name == option {
+			return true
+		}
+	}
+	return false
+}
+
+// encoderModules lists the third-party modules the built-in encoders and
+// decoders are implemented with
+var encoderModules = []string{
+	"github.com/chai2010/webp",
+	"golang.org/x/image",
+}
+
+// EncoderLibraries returns the versions of the encoder libraries compiled
+// into the binary, keyed by module path. It is empty when build
+// information is unavailable, e.g. in tests.
+func EncoderLibraries() map[string]string {
+	versions := make(map[string]string)
+
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return versions
+	}
+	for _, dep := range info.Deps {
+		version := dep.Version
+		if dep.Replace != nil {
+			version = dep.Replace.Version
+		}
+		for _, module := range encoderModules {
+			if dep.Path == module {
+				versions[module] = version
+			}
+		}
+	}
+	return versions
+}
@@ -0,0 +1,102 @@
+package generator
+
+import (
+	"image"
+	"image/color"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// OverlaySafeZones hatches the parts of target images that platforms cover
+// with UI, leaving the safe areas clear
+const OverlaySafeZones = "safe-zones"
+
+// SafeArea is a named region of an image that is not covered by platform UI
+type SafeArea struct {
+	Name string
+	Rect image.Rectangle
+}
+
+// safeZoneColors are cycled through in safe area order. Blended over any
+// category background they stay clear of the fiducial colors.
+var safeZoneColors = []color.RGBA{
+	{A: 255},                         // black
+	{R: 255, G: 255, B: 255, A: 255}, // white
+}
+
+// safeZoneAlpha is the opacity of the hatching, out of 255
+const safeZoneAlpha = 128
+
+// DrawSafeZones hatches everything outside each safe area of the spec with
+// translucent diagonal stripes, then outlines the area and labels it inside
+// the top-left corner. Areas alternate stripe direction and color so
+// overlapping zones stay distinguishable.
+func DrawSafeZones(img *image.RGBA, spec ImageSpec) {
+	grid := GetGridSize(spec.Width, spec.Height)
+	spacing := max(8, grid/5)
+	stripe := max(2, spacing/5)
+	thickness := max(2, grid/25)
+	face := basicfont.Face7x13
+	ascent := face.Metrics().Ascent.Ceil()
+	lineHeight := face.Metrics().Height.Ceil() + 2
+	bounds := img.Bounds()
+
+	for i, area := range spec.SafeAreas {
+		r := area.Rect.Intersect(bounds)
+		if r.Empty() || r == bounds {
+			continue
+		}
+		c := safeZoneColors[i%len(safeZoneColors)]
+		edge := safeZoneColors[(i+1)%len(safeZoneColors)]
+
+		// 1. Hatch the covered region, alternating stripe direction
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if image.Pt(x, y).In(r) {
+					continue
+				}
+				d := x + y
+				if i%2 == 1 {
+					d = x - y + spec.Height
+				}
+				if d%spacing >= stripe {
+					continue
+				}
+				blendPixel(img, x, y, c, safeZoneAlpha)
+			}
+		}
+
+		// 2. Outline the safe area, edged for contrast
+		sides := []image.Rectangle{
+			image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+thickness),
+			image.Rect(r.Min.X, r.Max.Y-thickness, r.Max.X, r.Max.Y),
+			image.Rect(r.Min.X, r.Min.Y, r.Min.X+thickness, r.Max.Y),
+			image.Rect(r.Max.X-thickness, r.Min.Y, r.Max.X, r.Max.Y),
+		}
+		for _, side := range sides {
+			fillRect(img, side.Inset(-1), edge)
+		}
+		for _, side := range sides {
+			fillRect(img, side, c)
+		}
+
+		// 3. Label, staggered so areas sharing an edge stay readable
+		x := r.Min.X + thickness + 3
+		y := r.Min.Y + thickness + ascent + 2 + i*lineHeight
+		drawTextWithOutline(img, area.Name, x, y, face, c, edge)
+	}
+}
+
+// blendPixel composites the opaque color c over the pixel at x, y with the
+// given opacity
+func blendPixel(img *image.RGBA, x, y int, c color.RGBA, alpha uint8) {
+	i := img.PixOffset(x, y)
+	a := uint32(alpha)
+	mix := func(dst, src uint8) uint8 {
+		return uint8((uint32(src)*a + uint32(dst)*(255-a)) / 255)
+	}
+	img.Pix[i] = mix(img.Pix[i], c.R)
+	img.Pix[i+1] = mix(img.Pix[i+1], c.G)
+	img.Pix[i+2] = mix(img.Pix[i+2], c.B)
+	img.Pix[i+3] = mix(img.Pix[i+3], 255)
+}
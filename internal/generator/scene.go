@@ -0,0 +1,276 @@
+package generator
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// Scene describes a synthetic scene of randomly placed objects. The same
+// seed always places the same objects, so their annotations can be
+// exported without looking at the pixels.
+type Scene struct {
+	Seed    int64
+	Objects int      // number of objects to place
+	Classes []string // object classes to pick from, all if empty
+}
+
+// SceneObject is an object placed in a scene. The polygon is the exact
+// outline that is filled, in pixel coordinates.
+type SceneObject struct {
+	Class   string
+	Polygon [][2]float64
+	Color   color.RGBA
+}
+
+// Bounds returns the bounding box of the object's polygon
+func (o SceneObject) Bounds() (x, y, width, height float64) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, p := range o.Polygon {
+		minX, maxX = math.Min(minX, p[0]), math.Max(maxX, p[0])
+		minY, maxY = math.Min(minY, p[1]), math.Max(maxY, p[1])
+	}
+	return minX, minY, maxX - minX, maxY - minY
+}
+
+// Area returns the area enclosed by the object's polygon
+func (o SceneObject) Area() float64 {
+	var sum float64
+	for i, p := range o.Polygon {
+		q := o.Polygon[(i+1)%len(o.Polygon)]
+		sum += p[0]*q[1] - q[0]*p[1]
+	}
+	return math.Abs(sum) / 2
+}
+
+// SceneClass is an object class with its outline in unit coordinates,
+// centered on the origin and at most 1 across
+type SceneClass struct {
+	Name          string
+	Supercategory string // shape or glyph
+	outline       func() [][2]float64
+}
+
+// sceneClasses lists the object classes in annotation category order
+var sceneClasses = []SceneClass{
+	{"circle", "shape", func() [][2]float64 { return regularPolygon(48, 0.5, 0) }},
+	{"square", "shape", func() [][2]float64 { return regularPolygon(4, 0.5, math.Pi/4) }},
+	{"triangle", "shape", func() [][2]float64 { return regularPolygon(3, 0.5, -math.Pi/2) }},
+	{"diamond", "shape", func() [][2]float64 { return [][2]float64{{0, -0.5}, {0.35, 0}, {0, 0.5}, {-0.35, 0}} }},
+	{"hexagon", "shape", func() [][2]float64 { return regularPolygon(6, 0.5, 0) }},
+	{"star", "shape", starOutline},
+	{"plus", "glyph", func() [][2]float64 {
+		return [][2]float64{
+			{-0.15, -0.5}, {0.15, -0.5}, {0.15, -0.15}, {0.5, -0.15}, {0.5, 0.15}, {0.15, 0.15},
+			{0.15, 0.5}, {-0.15, 0.5}, {-0.15, 0.15}, {-0.5, 0.15}, {-0.5, -0.15}, {-0.15, -0.15},
+		}
+	}},
+	{"arrow", "glyph", func() [][2]float64 {
+		return [][2]float64{{-0.5, -0.12}, {0.1, -0.12}, {0.1, -0.35}, {0.5, 0}, {0.1, 0.35}, {0.1, 0.12}, {-0.5, 0.12}}
+	}},
+	{"chevron", "glyph", func() [][2]float64 {
+		return [][2]float64{{-0.35, -0.5}, {-0.05, -0.5}, {0.35, 0}, {-0.05, 0.5}, {-0.35, 0.5}, {0.05, 0}}
+	}},
+	{"letter-l", "glyph", func() [][2]float64 {
+		return [][2]float64{{-0.3, -0.5}, {-0.05, -0.5}, {-0.05, 0.27}, {0.3, 0.27}, {0.3, 0.5}, {-0.3, 0.5}}
+	}},
+	{"letter-t", "glyph", func() [][2]float64 {
+		return [][2]float64{
+			{-0.4, -0.5}, {0.4, -0.5}, {0.4, -0.27}, {0.12, -0.27},
+			{0.12, 0.5}, {-0.12, 0.5}, {-0.12, -0.27}, {-0.4, -0.27},
+		}
+	}},
+}
+
+// SceneClasses returns the object classes in annotation category order
+func SceneClasses() []SceneClass {
+	return sceneClasses
+}
+
+// SceneClassNames returns the names of all object classes, sorted
+func SceneClassNames() []string {
+	names := make([]string, 0, len(sceneClasses))
+	for _, c := range sceneClasses {
+		names = append(names, c.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsValidSceneClass reports whether name is a known object class
+func IsValidSceneClass(name string) bool {
+	_, ok := lookupSceneClass(name)
+	return ok
+}
+
+func lookupSceneClass(name string) (SceneClass, bool) {
+	for _, c := range sceneClasses {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return SceneClass{}, false
+}
+
+// regularPolygon returns n points on a circle of the given radius, the
+// first at angle start
+func regularPolygon(n int, radius, start float64) [][2]float64 {
+	points := make([][2]float64, n)
+	for i := range points {
+		a := start + 2*math.Pi*float64(i)/float64(n)
+		points[i] = [2]float64{radius * math.Cos(a), radius * math.Sin(a)}
+	}
+	return points
+}
+
+// starOutline returns a five-pointed star pointing up
+func starOutline() [][2]float64 {
+	points := make([][2]float64, 10)
+	for i := range points {
+		radius := 0.5
+		if i%2 == 1 {
+			radius = 0.2
+		}
+		a := -math.Pi/2 + math.Pi*float64(i)/5
+		points[i] = [2]float64{radius * math.Cos(a), radius * math.Sin(a)}
+	}
+	return points
+}
+
+// sceneColors are the object fill colors. They differ from the category
+// backgrounds and the fiducial colors, so objects never read as markers.
+var sceneColors = []color.RGBA{
+	{R: 255, G: 255, B: 255, A: 255}, // white
+	{A: 255},                         // black
+	{R: 255, G: 220, A: 255},         // yellow
+	{R: 255, G: 105, B: 180, A: 255}, // pink
+	{R: 160, G: 82, B: 45, A: 255},   // brown
+	{R: 75, B: 130, A: 255},          // indigo
+	{G: 128, B: 128, A: 255},         // teal
+}
+
+// sceneEdgeClearance keeps objects clear of the border and corner markers
+const sceneEdgeClearance = 40
+
+// scenePlacementAttempts is how often an object is re-rolled before it is
+// left out because it does not fit between the others
+const scenePlacementAttempts = 200
+
+// SceneObjects places the objects of the spec's scene. Objects keep clear
+// of each other, the image edges, the fiducials and the label, so every
+// object is fully visible and its polygon is exact. Objects that do not
+// fit are left out.
+func SceneObjects(spec ImageSpec) ([]SceneObject, error) {
+	if spec.Scene == nil {
+		return nil, nil
+	}
+
+	// 1. Resolve the classes to pick from
+	classes := sceneClasses
+	if len(spec.Scene.Classes) > 0 {
+		classes = make([]SceneClass, 0, len(spec.Scene.Classes))
+		for _, name := range spec.Scene.Classes {
+			c, ok := lookupSceneClass(name)
+			if !ok {
+				return nil, fmt.Errorf("unknown scene class: %s", name)
+			}
+			classes = append(classes, c)
+		}
+	}
+
+	// 2. Reserve the label, the fiducials and the edges
+	label, err := labelBounds(spec)
+	if err != nil {
+		return nil, err
+	}
+	reserved := []image.Rectangle{label}
+	for _, f := range Fiducials(spec.Width, spec.Height) {
+		reserved = append(reserved, f.Rect.Inset(-f.Rect.Dx()/4))
+	}
+	area := image.Rect(0, 0, spec.Width, spec.Height).Inset(sceneEdgeClearance)
+	if area.Empty() {
+		return nil, nil
+	}
+
+	// 3. Roll class, size, rotation and position until each object fits
+	rng := rand.New(rand.NewSource(spec.Scene.Seed))
+	shorter := float64(min(spec.Width, spec.Height))
+	gap := max(4, int(shorter)/100)
+	var objects []SceneObject
+	for i := 0; i < spec.Scene.Objects; i++ {
+		for attempt := 0; attempt < scenePlacementAttempts; attempt++ {
+			class := classes[rng.Intn(len(classes))]
+			size := shorter * (0.08 + 0.14*rng.Float64())
+			angle := 2 * math.Pi * rng.Float64()
+			cx := float64(area.Min.X) + float64(area.Dx())*rng.Float64()
+			cy := float64(area.Min.Y) + float64(area.Dy())*rng.Float64()
+			c := sceneColors[rng.Intn(len(sceneColors))]
+
+			object := SceneObject{Class: class.Name, Color: c}
+			sin, cos := math.Sincos(angle)
+			for _, p := range class.outline() {
+				x, y := p[0]*size, p[1]*size
+				object.Polygon = append(object.Polygon, [2]float64{
+					math.Round((cx+x*cos-y*sin)*100) / 100,
+					math.Round((cy+x*sin+y*cos)*100) / 100,
+				})
+			}
+
+			bounds := objectRect(object)
+			if !bounds.In(area) || overlapsAny(bounds.Inset(-gap), reserved) {
+				continue
+			}
+			objects = append(objects, object)
+			reserved = append(reserved, bounds)
+			break
+		}
+	}
+	return objects, nil
+}
+
+// objectRect returns the pixels covering an object's bounding box
+func objectRect(o SceneObject) image.Rectangle {
+	x, y, w, h := o.Bounds()
+	return image.Rect(int(math.Floor(x)), int(math.Floor(y)), int(math.Ceil(x+w)), int(math.Ceil(y+h)))
+}
+
+func overlapsAny(r image.Rectangle, others []image.Rectangle) bool {
+	for _, o := range others {
+		if r.Overlaps(o) {
+			return true
+		}
+	}
+	return false
+}
+
+// DrawScene fills the polygon of every scene object. A pixel is filled when
+// its center lies inside the polygon.
+func DrawScene(img *image.RGBA, objects []SceneObject) {
+	for _, o := range objects {
+		r := objectRect(o).Intersect(img.Bounds())
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if insidePolygon(o.Polygon, float64(x)+0.5, float64(y)+0.5) {
+					img.SetRGBA(x, y, o.Color)
+				}
+			}
+		}
+	}
+}
+
+// insidePolygon reports whether x, y lies inside the polygon by the
+// even-odd rule
+func insidePolygon(polygon [][2]float64, x, y float64) bool {
+	inside := false
+	for i, j := 0, len(polygon)-1; i < len(polygon); j, i = i, i+1 {
+		a, b := polygon[i], polygon[j]
+		if (a[1] > y) != (b[1] > y) && x < (b[0]-a[0])*(y-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
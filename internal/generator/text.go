@@ -10,6 +10,13 @@ import (
 
 // DrawTextOverlay draws centered text overlay with metadata
 func DrawTextOverlay(img *image.RGBA, lines []string, width, height int) error {
+	drawTextBlock(img, lines, width, height/2)
+	return nil
+}
+
+// drawTextBlock draws horizontally centered lines around centerY
+func drawTextBlock(img *image.RGBA, lines []string, width, centerY int) {
+	height := img.Bounds().Dy()
 	white := color.RGBA{R: 255, G: 255, B: 255, A: 255}
 	black := color.RGBA{R: 0, G: 0, B: 0, A: 255}
 
@@ -24,7 +31,7 @@ func DrawTextOverlay(img *image.RGBA, lines []string, width, height int) error {
 	totalHeight := len(lines) * lineHeight
 
 	// Start Y position (centered vertically)
-	startY := (height - totalHeight) / 2
+	startY := centerY - totalHeight/2
 
 	// Draw each line centered
 	for i, line := range lines {
@@ -37,8 +44,6 @@ func DrawTextOverlay(img *image.RGBA, lines []string, width, height int) error {
 		// Draw text with outline
 		drawTextWithOutline(img, line, x, y, face, white, black)
 	}
-
-	return nil
 }
 
 // getFontFace returns appropriate font face based on size
@@ -0,0 +1,219 @@
+package generator
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"io"
+
+	"golang.org/x/image/tiff"
+)
+
+// TIFF compression schemes
+const (
+	TIFFCompressionNone    = "none"
+	TIFFCompressionDeflate = "deflate"
+)
+
+// IsValidTIFFCompression checks if compression is a supported TIFF scheme
+func IsValidTIFFCompression(compression string) bool {
+	switch compression {
+	case "", TIFFCompressionNone, TIFFCompressionDeflate:
+		return true
+	default:
+		return false
+	}
+}
+
+// TIFF tag numbers and field types used by the multi-page writer
+const (
+	tiffTagNewSubfileType  = 254
+	tiffTagImageWidth      = 256
+	tiffTagImageLength     = 257
+	tiffTagBitsPerSample   = 258
+	tiffTagCompression     = 259
+	tiffTagPhotometric     = 262
+	tiffTagStripOffsets    = 273
+	tiffTagSamplesPerPixel = 277
+	tiffTagRowsPerStrip    = 278
+	tiffTagStripByteCounts = 279
+	tiffTagXResolution     = 282
+	tiffTagYResolution     = 283
+	tiffTagPlanarConfig    = 284
+	tiffTagResolutionUnit  = 296
+	tiffTagPageNumber      = 297
+	tiffTagExtraSamples    = 338
+
+	tiffTypeShort    = 3
+	tiffTypeLong     = 4
+	tiffTypeRational = 5
+)
+
+// tiffEntry is a single IFD entry. Rational values are stored as
+// numerator/denominator pairs.
+type tiffEntry struct {
+	tag    uint16
+	typ    uint16
+	values []uint32
+}
+
+// encodeTIFF encodes image to TIFF format with the requested compression.
+// Multi-page images are written with one IFD per page.
+func encodeTIFF(file io.Writer, img image.Image, opts EncodeOptions) error {
+	if pages, ok := img.(*PageSet); ok {
+		if err := encodeMultiPageTIFF(file, pages.Pages, opts.Compression); err != nil {
+			return fmt.Errorf("failed to encode multi-page TIFF: %w", err)
+		}
+		return nil
+	}
+
+	tiffOpts := &tiff.Options{Compression: tiff.Uncompressed}
+	if opts.Compression == TIFFCompressionDeflate {
+		tiffOpts.Compression = tiff.Deflate
+	}
+
+	if err := tiff.Encode(file, img, tiffOpts); err != nil {
+		return fmt.Errorf("failed to encode TIFF: %w", err)
+	}
+	return nil
+}
+
+// encodeMultiPageTIFF writes every page as a single-strip RGB(A) image with
+// its own IFD, chained in page order. golang.org/x/image/tiff only writes a
+// single page, so the container is assembled here.
+func encodeMultiPageTIFF(w io.Writer, pages []*image.RGBA, compression string) error {
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	buf.Write(make([]byte, 4)) // First IFD offset, patched below
+	nextOffsetPos := 4
+
+	for i, page := range pages {
+		strip, samples, err := tiffStrip(page, compression)
+		if err != nil {
+			return fmt.Errorf("page %d: %w", i+1, err)
+		}
+
+		stripOffset := buf.Len()
+		buf.Write(strip)
+		if buf.Len()%2 == 1 {
+			buf.WriteByte(0) // IFDs must start on a word boundary
+		}
+
+		binary.LittleEndian.PutUint32(buf.Bytes()[nextOffsetPos:], uint32(buf.Len()))
+
+		compressionCode := uint32(1)
+		if compression == TIFFCompressionDeflate {
+			compressionCode = 8
+		}
+		bitsPerSample := make([]uint32, samples)
+		for s := range bitsPerSample {
+			bitsPerSample[s] = 8
+		}
+		bounds := page.Bounds()
+
+		// Entries must be sorted by tag
+		entries := []tiffEntry{
+			{tiffTagNewSubfileType, tiffTypeLong, []uint32{2}}, // Single page of a multi-page image
+			{tiffTagImageWidth, tiffTypeLong, []uint32{uint32(bounds.Dx())}},
+			{tiffTagImageLength, tiffTypeLong, []uint32{uint32(bounds.Dy())}},
+			{tiffTagBitsPerSample, tiffTypeShort, bitsPerSample},
+			{tiffTagCompression, tiffTypeShort, []uint32{compressionCode}},
+			{tiffTagPhotometric, tiffTypeShort, []uint32{2}}, // RGB
+			{tiffTagStripOffsets, tiffTypeLong, []uint32{uint32(stripOffset)}},
+			{tiffTagSamplesPerPixel, tiffTypeShort, []uint32{uint32(samples)}},
+			{tiffTagRowsPerStrip, tiffTypeLong, []uint32{uint32(bounds.Dy())}},
+			{tiffTagStripByteCounts, tiffTypeLong, []uint32{uint32(len(strip))}},
+			{tiffTagXResolution, tiffTypeRational, []uint32{72, 1}},
+			{tiffTagYResolution, tiffTypeRational, []uint32{72, 1}},
+			{tiffTagPlanarConfig, tiffTypeShort, []uint32{1}},   // Chunky
+			{tiffTagResolutionUnit, tiffTypeShort, []uint32{2}}, // Inch
+			{tiffTagPageNumber, tiffTypeShort, []uint32{uint32(i), uint32(len(pages))}},
+		}
+		if samples == 4 {
+			entries = append(entries, tiffEntry{tiffTagExtraSamples, tiffTypeShort, []uint32{2}}) // Unassociated alpha
+		}
+
+		nextOffsetPos = writeTIFFIFD(&buf, entries)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// tiffStrip returns the page pixels as a single strip, dropping the alpha
+// channel of opaque pages, and the number of samples per pixel
+func tiffStrip(page *image.RGBA, compression string) ([]byte, int, error) {
+	pix := toStraightRGBA(page).Pix
+
+	samples := 4
+	if page.Opaque() {
+		samples = 3
+		rgb := make([]byte, 0, len(pix)/4*3)
+		for i := 0; i < len(pix); i += 4 {
+			rgb = append(rgb, pix[i], pix[i+1], pix[i+2])
+		}
+		pix = rgb
+	}
+
+	if compression != TIFFCompressionDeflate {
+		return pix, samples, nil
+	}
+
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	if _, err := zw.Write(pix); err != nil {
+		return nil, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, err
+	}
+
+	return buf.Bytes(), samples, nil
+}
+
+// writeTIFFIFD appends an IFD followed by its out-of-line values and returns
+// the position of its next-IFD offset field
+func writeTIFFIFD(buf *bytes.Buffer, entries []tiffEntry) int {
+	ifdOffset := buf.Len()
+	valuesOffset := ifdOffset + 2 + len(entries)*12 + 4
+
+	var values bytes.Buffer
+	_ = binary.Write(buf, binary.LittleEndian, uint16(len(entries)))
+	for _, e := range entries {
+		var data bytes.Buffer
+		count := len(e.values)
+		for _, v := range e.values {
+			if e.typ == tiffTypeShort {
+				_ = binary.Write(&data, binary.LittleEndian, uint16(v))
+			} else {
+				_ = binary.Write(&data, binary.LittleEndian, v)
+			}
+		}
+		if e.typ == tiffTypeRational {
+			count /= 2
+		}
+
+		_ = binary.Write(buf, binary.LittleEndian, e.tag)
+		_ = binary.Write(buf, binary.LittleEndian, e.typ)
+		_ = binary.Write(buf, binary.LittleEndian, uint32(count))
+		if data.Len() <= 4 {
+			// Value fits inline in the offset field
+			inline := make([]byte, 4)
+			copy(inline, data.Bytes())
+			buf.Write(inline)
+			continue
+		}
+		_ = binary.Write(buf, binary.LittleEndian, uint32(valuesOffset+values.Len()))
+		values.Write(data.Bytes())
+	}
+
+	nextOffsetPos := buf.Len()
+	_ = binary.Write(buf, binary.LittleEndian, uint32(0)) // No next IFD yet
+	buf.Write(values.Bytes())
+
+	return nextOffsetPos
+}
@@ -0,0 +1,272 @@
+package generator
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+
+	"github.com/chai2010/webp"
+)
+
+// VP8X feature flags
+const (
+	vp8xFlagAnimation = 0x02
+	vp8xFlagXMP       = 0x04
+	vp8xFlagEXIF      = 0x08
+	vp8xFlagAlpha     = 0x10
+	vp8xFlagICC       = 0x20
+)
+
+// webpChunk is a single RIFF chunk of a WebP file
+type webpChunk struct {
+	fourCC string
+	data   []byte
+}
+
+// encodeWebP encodes image to WebP format. Plain options produce a simple
+// VP8/VP8L file; metadata or animation produce an extended VP8X container.
+func encodeWebP(file io.Writer, img image.Image, opts EncodeOptions) error {
+	var data []byte
+	var err error
+
+	if anim, ok := img.(*Animation); ok {
+		data, err = encodeAnimatedWebP(anim, opts)
+	} else {
+		data, err = encodeWebPFrame(img, opts)
+		if err == nil && len(opts.Metadata) > 0 {
+			data, err = extendWebP(data, img.Bounds(), opts)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to encode WebP: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		return fmt.Errorf("failed to write WebP: %w", err)
+	}
+
+	return nil
+}
+
+// encodeWebPFrame encodes a single image to a simple-format WebP bitstream
+func encodeWebPFrame(img image.Image, opts EncodeOptions) ([]byte, error) {
+	pix := toStraightRGBA(img)
+	if opts.NearLossless > 0 {
+		applyNearLossless(pix, opts.NearLossless)
+	}
+
+	// Near-lossless is a preprocessing step for the lossless encoder
+	webpOpts := &webp.Options{
+		Lossless: opts.Lossless || opts.NearLossless > 0,
+		Quality:  float32(opts.Quality),
+	}
+
+	var buf bytes.Buffer
+	if err := webp.Encode(&buf, pix, webpOpts); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeAnimatedWebP encodes every frame separately and wraps the bitstreams
+// in ANMF chunks of an animated VP8X container
+func encodeAnimatedWebP(anim *Animation, opts EncodeOptions) ([]byte, error) {
+	bounds := anim.Bounds()
+	flags := byte(vp8xFlagAnimation)
+
+	loop := make([]byte, 6) // Background color (BGRA) and loop count
+	binary.LittleEndian.PutUint16(loop[4:], uint16(anim.LoopCount))
+	frames := []webpChunk{{fourCC: "ANIM", data: loop}}
+
+	for i, frame := range anim.Frames {
+		data, err := encodeWebPFrame(frame, opts)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i+1, err)
+		}
+
+		chunks, err := parseWebPChunks(data)
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i+1, err)
+		}
+
+		var payload bytes.Buffer
+		header := make([]byte, 16)
+		putUint24(header[6:], uint32(frame.Bounds().Dx()-1))
+		putUint24(header[9:], uint32(frame.Bounds().Dy()-1))
+		putUint24(header[12:], uint32(anim.Delays[i]))
+		header[15] = 0x02 // Do not blend, keep disposal at none
+		payload.Write(header)
+
+		for _, chunk := range bitstreamChunks(chunks) {
+			if hasWebPAlpha(chunk) {
+				flags |= vp8xFlagAlpha
+			}
+			writeWebPChunk(&payload, chunk)
+		}
+
+		frames = append(frames, webpChunk{fourCC: "ANMF", data: payload.Bytes()})
+	}
+
+	return assembleVP8X(flags, bounds, frames, opts), nil
+}
+
+// extendWebP converts a simple-format WebP file into a VP8X container
+// carrying the requested metadata chunks
+func extendWebP(data []byte, bounds image.Rectangle, opts EncodeOptions) ([]byte, error) {
+	chunks, err := parseWebPChunks(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var flags byte
+	bitstream := bitstreamChunks(chunks)
+	for _, chunk := range bitstream {
+		if hasWebPAlpha(chunk) {
+			flags |= vp8xFlagAlpha
+		}
+	}
+
+	return assembleVP8X(flags, bounds, bitstream, opts), nil
+}
+
+// assembleVP8X writes a VP8X container with the image chunks surrounded by
+// the metadata chunks in the order required by the WebP container spec
+func assembleVP8X(flags byte, bounds image.Rectangle, bitstream []webpChunk, opts EncodeOptions) []byte {
+	var chunks []webpChunk
+
+	if opts.hasMetadata(MetadataICC) {
+		flags |= vp8xFlagICC
+		chunks = append(chunks, webpChunk{fourCC: "ICCP", data: BuildICCProfile()})
+	}
+	chunks = append(chunks, bitstream...)
+	if opts.hasMetadata(MetadataEXIF) {
+		flags |= vp8xFlagEXIF
+		chunks = append(chunks, webpChunk{fourCC: "EXIF", data: BuildEXIF(bounds.Dx(), bounds.Dy())})
+	}
+	if opts.hasMetadata(MetadataXMP) {
+		flags |= vp8xFlagXMP
+		chunks = append(chunks, webpChunk{fourCC: "XMP ", data: BuildXMP(bounds.Dx(), bounds.Dy())})
+	}
+
+	header := make([]byte, 10)
+	header[0] = flags
+	putUint24(header[4:], uint32(bounds.Dx()-1))
+	putUint24(header[7:], uint32(bounds.Dy()-1))
+
+	var body bytes.Buffer
+	body.WriteString("WEBP")
+	writeWebPChunk(&body, webpChunk{fourCC: "VP8X", data: header})
+	for _, chunk := range chunks {
+		writeWebPChunk(&body, chunk)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("RIFF")
+	_ = binary.Write(&out, binary.LittleEndian, uint32(body.Len()))
+	out.Write(body.Bytes())
+
+	return out.Bytes()
+}
+
+// parseWebPChunks splits a WebP file into its RIFF chunks
+func parseWebPChunks(data []byte) ([]webpChunk, error) {
+	if len(data) < 12 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return nil, fmt.Errorf("not a WebP file")
+	}
+
+	var chunks []webpChunk
+	for pos := 12; pos+8 <= len(data); {
+		fourCC := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		start := pos + 8
+		if start+size > len(data) {
+			return nil, fmt.Errorf("truncated %q chunk", fourCC)
+		}
+
+		chunks = append(chunks, webpChunk{fourCC: fourCC, data: data[start : start+size]})
+		pos = start + size + size%2
+	}
+
+	return chunks, nil
+}
+
+// writeWebPChunk writes a chunk header, payload and padding byte
+func writeWebPChunk(buf *bytes.Buffer, chunk webpChunk) {
+	buf.WriteString(chunk.fourCC)
+	_ = binary.Write(buf, binary.LittleEndian, uint32(len(chunk.data)))
+	buf.Write(chunk.data)
+	if len(chunk.data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+}
+
+// bitstreamChunks returns the ALPH, VP8 and VP8L chunks of a parsed file
+func bitstreamChunks(chunks []webpChunk) []webpChunk {
+	var result []webpChunk
+	for _, chunk := range chunks {
+		switch chunk.fourCC {
+		case "ALPH", "VP8 ", "VP8L":
+			result = append(result, chunk)
+		}
+	}
+	return result
+}
+
+// hasWebPAlpha reports whether a bitstream chunk carries alpha: either an
+// ALPH chunk or a VP8L bitstream with the alpha_is_used bit set
+func hasWebPAlpha(chunk webpChunk) bool {
+	switch chunk.fourCC {
+	case "ALPH":
+		return true
+	case "VP8L":
+		return len(chunk.data) >= 5 && binary.LittleEndian.Uint32(chunk.data[1:5])&(1<<28) != 0
+	default:
+		return false
+	}
+}
+
+// putUint24 writes a 24-bit little-endian value
+func putUint24(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+}
+
+// toStraightRGBA returns the image pixels as non-premultiplied RGBA bytes
+// wrapped in an image.RGBA, which is the layout libwebp expects
+func toStraightRGBA(img image.Image) *image.RGBA {
+	bounds := img.Bounds()
+	nrgba := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(nrgba, nrgba.Bounds(), img, bounds.Min, draw.Src)
+
+	return &image.RGBA{
+		Pix:    nrgba.Pix,
+		Stride: nrgba.Stride,
+		Rect:   nrgba.Rect,
+	}
+}
+
+// applyNearLossless approximates libwebp's near-lossless preprocessing by
+// rounding color channels to a coarser grid before lossless encoding. Lower
+// levels drop more bits, following libwebp's limit of 5 - level/20 bits.
+func applyNearLossless(img *image.RGBA, level int) {
+	bits := uint(5 - level/20)
+	if bits == 0 {
+		return
+	}
+	step := 1 << bits
+
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			v := (int(img.Pix[i+c]) + step/2) >> bits << bits
+			if v > 255 {
+				v = 255
+			}
+			img.Pix[i+c] = uint8(v)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package manifest
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Change describes a property of an image that differs between two manifests
+type Change struct {
+	Filename string `json:"filename"`
+	Field    string `json:"field"` // dimensions, format, pixels
+	Old      string `json:"old"`
+	New      string `json:"new"`
+}
+
+// SizeDelta describes a file size change of an image present in both
+// manifests
+type SizeDelta struct {
+	Filename     string  `json:"filename"`
+	OldBytes     int64   `json:"old_bytes"`
+	NewBytes     int64   `json:"new_bytes"`
+	DeltaBytes   int64   `json:"delta_bytes"`
+	DeltaPercent float64 `json:"delta_percent"`
+}
+
+// DiffReport is the result of comparing two manifests
+type DiffReport struct {
+	Added      []string    `json:"added"`
+	Removed    []string    `json:"removed"`
+	Changed    []Change    `json:"changed"`
+	SizeDeltas []SizeDelta `json:"size_deltas"`
+	OldBytes   int64       `json:"old_total_bytes"`
+	NewBytes   int64       `json:"new_total_bytes"`
+	Violations []string    `json:"violations"`
+}
+
+// DiffThresholds limits how much a fixture set may grow between runs.
+// Percentages are relative to the old size; nil disables a limit, while
+// zero fails on any growth.
+type DiffThresholds struct {
+	MaxSizeGrowth      *float64 // per image, in percent
+	MaxTotalSizeGrowth *float64 // whole set, in percent
+}
+
+// HasChanges returns true if any image was added, removed or changed
+func (r *DiffReport) HasChanges() bool {
+	return len(r.Added) > 0 || len(r.Removed) > 0 || len(r.Changed) > 0 || len(r.SizeDeltas) > 0
+}
+
+// TotalDeltaPercent returns the growth of the whole set in percent
+func (r *DiffReport) TotalDeltaPercent() float64 {
+	return percentChange(r.OldBytes, r.NewBytes)
+}
+
+// Diff compares two manifests by image filename
+func Diff(oldManifest, newManifest *Manifest) *DiffReport {
+	report := &DiffReport{
+		Added:      []string{},
+		Removed:    []string{},
+		Changed:    []Change{},
+		SizeDeltas: []SizeDelta{},
+		Violations: []string{},
+	}
+
+	oldImages := make(map[string]ImageRecord, len(oldManifest.Images))
+	for _, img := range oldManifest.Images {
+		oldImages[img.Filename] = img
+		report.OldBytes += img.FileSizeBytes
+	}
+
+	newImages := make(map[string]bool, len(newManifest.Images))
+	for _, img := range newManifest.Images {
+		newImages[img.Filename] = true
+		report.NewBytes += img.FileSizeBytes
+
+		old, ok := oldImages[img.Filename]
+		if !ok {
+			report.Added = append(report.Added, img.Filename)
+			continue
+		}
+		report.Changed = append(report.Changed, diffImage(old, img)...)
+
+		if old.FileSizeBytes != img.FileSizeBytes {
+			report.SizeDeltas = append(report.SizeDeltas, SizeDelta{
+				Filename:     img.Filename,
+				OldBytes:     old.FileSizeBytes,
+				NewBytes:     img.FileSizeBytes,
+				DeltaBytes:   img.FileSizeBytes - old.FileSizeBytes,
+				DeltaPercent: percentChange(old.FileSizeBytes, img.FileSizeBytes),
+			})
+		}
+	}
+
+	for _, img := range oldManifest.Images {
+		if !newImages[img.Filename] {
+			report.Removed = append(report.Removed, img.Filename)
+		}
+	}
+
+	sort.Strings(report.Added)
+	sort.Strings(report.Removed)
+	sort.Slice(report.Changed, func(i, j int) bool {
+		return report.Changed[i].Filename < report.Changed[j].Filename
+	})
+	sort.Slice(report.SizeDeltas, func(i, j int) bool {
+		return report.SizeDeltas[i].Filename < report.SizeDeltas[j].Filename
+	})
+
+	return report
+}
+
+// Check records a violation for every image and for the whole set
+// growing beyond the thresholds and returns the number of violations
+func (r *DiffReport) Check(thresholds DiffThresholds) int {
+	if limit := thresholds.MaxSizeGrowth; limit != nil {
+		for _, delta := range r.SizeDeltas {
+			if delta.DeltaPercent > *limit {
+				r.Violations = append(r.Violations, fmt.Sprintf("%s grew %.1f%% (%d -> %d bytes), limit %g%%",
+					delta.Filename, delta.DeltaPercent, delta.OldBytes, delta.NewBytes, *limit))
+			}
+		}
+	}
+
+	if limit := thresholds.MaxTotalSizeGrowth; limit != nil && r.TotalDeltaPercent() > *limit {
+		r.Violations = append(r.Violations, fmt.Sprintf("total size grew %.1f%% (%d -> %d bytes), limit %g%%",
+			r.TotalDeltaPercent(), r.OldBytes, r.NewBytes, *limit))
+	}
+
+	return len(r.Violations)
+}
+
+// diffImage compares the properties of two records of the same file
+func diffImage(old, img ImageRecord) []Change {
+	var changes []Change
+
+	if old.Width != img.Width || old.Height != img.Height {
+		changes = append(changes, Change{
+			Filename: img.Filename,
+			Field:    "dimensions",
+			Old:      fmt.Sprintf("%dx%d", old.Width, old.Height),
+			New:      fmt.Sprintf("%dx%d", img.Width, img.Height),
+		})
+	}
+	if old.Format != img.Format {
+		changes = append(changes, Change{Filename: img.Filename, Field: "format", Old: old.Format, New: img.Format})
+	}
+	if old.PixelSHA256 != "" && img.PixelSHA256 != "" && old.PixelSHA256 != img.PixelSHA256 {
+		changes = append(changes, Change{Filename: img.Filename, Field: "pixels", Old: old.PixelSHA256, New: img.PixelSHA256})
+	}
+
+	return changes
+}
+
+// percentChange returns the change from oldBytes to newBytes in percent.
+// Growth from zero counts as 100%.
+func percentChange(oldBytes, newBytes int64) float64 {
+	if oldBytes == 0 {
+		if newBytes == 0 {
+			return 0
+		}
+		return 100
+	}
+	return float64(newBytes-oldBytes) / float64(oldBytes) * 100
+}
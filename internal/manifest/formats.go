@@ -0,0 +1,352 @@
+package manifest
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Manifest output formats
+const (
+	FormatJSON   = "json"
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+	FormatSQL    = "sql"
+)
+
+// OutputFormats lists the supported manifest output formats
+var OutputFormats = []string{FormatJSON, FormatCSV, FormatNDJSON, FormatSQL}
+
+// IsValidOutputFormat checks if a manifest output format is supported
+func IsValidOutputFormat(format string) bool {
+	for _, f := range OutputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// FileName returns the manifest file name for an output format, e.g.
+// "manifest.csv"
+func FileName(format string) string {
+	return "manifest." + format
+}
+
+// columnKind determines how a column value is rendered in SQL
+type columnKind int
+
+const (
+	kindText columnKind = iota
+	kindInt
+	kindFloat
+	kindBool
+)
+
+// column is a flat field of an image record. Optional columns are
+// written as NULL in SQL when empty.
+type column struct {
+	name     string
+	kind     columnKind
+	optional bool
+	value    func(img ImageRecord) string
+}
+
+func animationValue(img ImageRecord, value func(a *AnimationInfo) string) string {
+	if img.Animation == nil {
+		return ""
+	}
+	return value(img.Animation)
+}
+
+// safeAreasValue flattens safe areas to "name:x,y,width,height" joined
+// with ";"
+func safeAreasValue(img ImageRecord) string {
+	values := make([]string, len(img.SafeAreas))
+	for i, a := range img.SafeAreas {
+		values[i] = fmt.Sprintf("%s:%d,%d,%d,%d", a.Name, a.X, a.Y, a.Width, a.Height)
+	}
+	return strings.Join(values, ";")
+}
+
+// sceneValue flattens a scene to the classes of its objects joined with ";"
+func sceneValue(img ImageRecord) string {
+	if img.Scene == nil {
+		return ""
+	}
+	classes := make([]string, len(img.Scene.Objects))
+	for i, o := range img.Scene.Objects {
+		classes[i] = o.Class
+	}
+	return strings.Join(classes, ";")
+}
+
+func optionalInt(v int) string {
+	if v == 0 {
+		return ""
+	}
+	return strconv.Itoa(v)
+}
+
+// columns lists the image record fields in manifest order, with nested
+// animation settings flattened
+var columns = []column{
+	{"filename", kindText, false, func(img ImageRecord) string { return img.Filename }},
+	{"category", kindText, false, func(img ImageRecord) string { return img.Category }},
+	{"subcategory", kindText, false, func(img ImageRecord) string { return img.Subcategory }},
+	{"width", kindInt, false, func(img ImageRecord) string { return strconv.Itoa(img.Width) }},
+	{"height", kindInt, false, func(img ImageRecord) string { return strconv.Itoa(img.Height) }},
+	{"ratio", kindText, false, func(img ImageRecord) string { return img.Ratio }},
+	{"ratio_decimal", kindFloat, false, func(img ImageRecord) string { return strconv.FormatFloat(img.RatioDecimal, 'f', -1, 64) }},
+	{"format", kindText, false, func(img ImageRecord) string { return img.Format }},
+	{"quality", kindInt, false, func(img ImageRecord) string { return strconv.Itoa(img.Quality) }},
+	{"file_size_bytes", kindInt, false, func(img ImageRecord) string { return strconv.FormatInt(img.FileSizeBytes, 10) }},
+	{"mime_type", kindText, true, func(img ImageRecord) string { return img.MimeType }},
+	{"sha256", kindText, true, func(img ImageRecord) string { return img.SHA256 }},
+	{"pixel_sha256", kindText, true, func(img ImageRecord) string { return img.PixelSHA256 }},
+	{"dhash", kindText, true, func(img ImageRecord) string { return img.DHash }},
+	{"phash", kindText, true, func(img ImageRecord) string { return img.PHash }},
+	{"spec_sha256", kindText, true, func(img ImageRecord) string { return img.SpecSHA256 }},
+	{"size_category", kindText, false, func(img ImageRecord) string { return img.SizeCategory }},
+	{"variant", kindText, true, func(img ImageRecord) string { return img.Variant }},
+	{"lossless", kindBool, false, func(img ImageRecord) string { return strconv.FormatBool(img.Lossless) }},
+	{"near_lossless", kindInt, true, func(img ImageRecord) string { return optionalInt(img.NearLossless) }},
+	{"has_alpha", kindBool, false, func(img ImageRecord) string { return strconv.FormatBool(img.HasAlpha) }},
+	{"interlaced", kindBool, false, func(img ImageRecord) string { return strconv.FormatBool(img.Interlaced) }},
+	{"metadata", kindText, true, func(img ImageRecord) string { return strings.Join(img.Metadata, ";") }},
+	{"compression", kindText, true, func(img ImageRecord) string { return img.Compression }},
+	{"pages", kindInt, true, func(img ImageRecord) string { return optionalInt(img.Pages) }},
+	{"overlays", kindText, true, func(img ImageRecord) string { return strings.Join(img.Overlays, ";") }},
+	{"safe_areas", kindText, true, safeAreasValue},
+	{"focal_point", kindText, true, func(img ImageRecord) string {
+		if img.FocalPoint == nil {
+			return ""
+		}
+		return fmt.Sprintf("%d,%d", img.FocalPoint.PixelX, img.FocalPoint.PixelY)
+	}},
+	{"scene", kindText, true, sceneValue},
+	{"frames", kindInt, true, func(img ImageRecord) string {
+		return animationValue(img, func(a *AnimationInfo) string { return strconv.Itoa(a.Frames) })
+	}},
+	{"frame_delay_ms", kindInt, true, func(img ImageRecord) string {
+		return animationValue(img, func(a *AnimationInfo) string { return strconv.Itoa(a.FrameDelayMs) })
+	}},
+	{"loop_count", kindInt, true, func(img ImageRecord) string {
+		return animationValue(img, func(a *AnimationInfo) string { return strconv.Itoa(a.LoopCount) })
+	}},
+	{"dispose_op", kindText, true, func(img ImageRecord) string {
+		return animationValue(img, func(a *AnimationInfo) string { return a.DisposeOp })
+	}},
+	{"blend_op", kindText, true, func(img ImageRecord) string {
+		return animationValue(img, func(a *AnimationInfo) string { return a.BlendOp })
+	}},
+	{"separate_default_image", kindBool, true, func(img ImageRecord) string {
+		return animationValue(img, func(a *AnimationInfo) string { return strconv.FormatBool(a.SeparateDefaultImage) })
+	}},
+}
+
+// lookupColumn finds a flat field by name
+func lookupColumn(name string) (column, bool) {
+	for _, c := range columns {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return column{}, false
+}
+
+// ColumnNames returns the names of the flat image fields usable in CSV
+// and SQL output
+func ColumnNames() []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// SQLColumn maps a flat image field to a database column
+type SQLColumn struct {
+	Column string
+	Field  string
+}
+
+// SQLLayout describes the table SQL INSERT statements are written for
+type SQLLayout struct {
+	Table   string
+	Columns []SQLColumn
+}
+
+// DefaultSQLLayout returns a layout inserting every field into a
+// same-named column of the test_images table
+func DefaultSQLLayout() SQLLayout {
+	layout := SQLLayout{Table: "test_images"}
+	for _, c := range columns {
+		layout.Columns = append(layout.Columns, SQLColumn{Column: c.name, Field: c.name})
+	}
+	return layout
+}
+
+// ParseSQLColumns parses a column mapping such as
+// "path=filename,width,height", where a bare name maps a field to a
+// same-named column
+func ParseSQLColumns(spec []string) ([]SQLColumn, error) {
+	var result []SQLColumn
+	for _, entry := range spec {
+		col, field, found := strings.Cut(strings.TrimSpace(entry), "=")
+		if !found {
+			field = col
+		}
+		col, field = strings.TrimSpace(col), strings.TrimSpace(field)
+
+		if col == "" || field == "" {
+			return nil, fmt.Errorf("invalid column mapping: %q", entry)
+		}
+		if _, ok := lookupColumn(field); !ok {
+			return nil, fmt.Errorf("unknown field %q (available: %s)", field, strings.Join(ColumnNames(), ", "))
+		}
+		result = append(result, SQLColumn{Column: col, Field: field})
+	}
+	return result, nil
+}
+
+// Encode writes the manifest in the given output format. The layout is
+// used only for SQL output.
+func (m *Manifest) Encode(w io.Writer, format string, layout SQLLayout) error {
+	switch format {
+	case FormatJSON:
+		data, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal manifest: %w", err)
+		}
+		_, err = w.Write(data)
+		return err
+	case FormatCSV:
+		return m.writeCSV(w)
+	case FormatNDJSON:
+		return m.writeNDJSON(w)
+	case FormatSQL:
+		return m.writeSQL(w, layout)
+	default:
+		return fmt.Errorf("unsupported manifest format: %s (supported: %s)", format, strings.Join(OutputFormats, ", "))
+	}
+}
+
+// WriteFormat writes the manifest to a file in the given output format
+func (m *Manifest) WriteFormat(outputPath, format string, layout SQLLayout) (err error) {
+	if format == FormatJSON {
+		return m.Write(outputPath)
+	}
+
+	file, err := os.Create(outputPath)
+	if err != nil {
+		return fmt.Errorf("failed to create manifest file: %w", err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close manifest file: %w", closeErr)
+		}
+	}()
+
+	if err := m.Encode(file, format, layout); err != nil {
+		return fmt.Errorf("failed to write %s manifest: %w", format, err)
+	}
+	return nil
+}
+
+// writeCSV writes a header row and one row per image
+func (m *Manifest) writeCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(ColumnNames()); err != nil {
+		return err
+	}
+
+	row := make([]string, len(columns))
+	for _, img := range m.Images {
+		for i, c := range columns {
+			row[i] = c.value(img)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// writeNDJSON writes one compact JSON image record per line
+func (m *Manifest) writeNDJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, img := range m.Images {
+		if err := encoder.Encode(img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSQL writes one INSERT statement per image
+func (m *Manifest) writeSQL(w io.Writer, layout SQLLayout) error {
+	if layout.Table == "" || len(layout.Columns) == 0 {
+		return fmt.Errorf("SQL layout needs a table and at least one column")
+	}
+
+	names := make([]string, len(layout.Columns))
+	fields := make([]column, len(layout.Columns))
+	for i, mapping := range layout.Columns {
+		c, ok := lookupColumn(mapping.Field)
+		if !ok {
+			return fmt.Errorf("unknown field: %s", mapping.Field)
+		}
+		names[i] = quoteIdentifier(mapping.Column)
+		fields[i] = c
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", quoteIdentifier(layout.Table), strings.Join(names, ", "))
+
+	values := make([]string, len(fields))
+	for _, img := range m.Images {
+		for i, c := range fields {
+			values[i] = sqlLiteral(c, c.value(img))
+		}
+		if _, err := fmt.Fprintf(w, "%s%s);\n", prefix, strings.Join(values, ", ")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quoteIdentifier quotes a table or column name, keeping dotted
+// schema-qualified names intact
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = `"` + strings.ReplaceAll(part, `"`, `""`) + `"`
+	}
+	return strings.Join(parts, ".")
+}
+
+// sqlLiteral renders a column value as a SQL literal
+func sqlLiteral(c column, value string) string {
+	if value == "" {
+		if c.optional {
+			return "NULL"
+		}
+		if c.kind == kindText {
+			return "''"
+		}
+	}
+
+	switch c.kind {
+	case kindInt, kindFloat:
+		return value
+	case kindBool:
+		return strings.ToUpper(value)
+	default:
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	}
+}
@@ -0,0 +1,141 @@
+package manifest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// SchemaVersion is the manifest schema version written by this tool. It is
+// bumped on incompatible changes; new optional fields keep the version.
+const SchemaVersion = 2
+
+// legacySchemaVersion is assumed for manifests without a schema_version
+const legacySchemaVersion = 1
+
+// migration upgrades a raw manifest from one schema version to the next
+type migration func(raw map[string]interface{}) error
+
+// migrations maps a schema version to the migration that upgrades it to
+// the next version
+var migrations = map[int]migration{
+	1: migrateV1ToV2,
+}
+
+// Load reads a manifest file, migrating older schema versions to the
+// current one, and validates it
+func Load(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	m, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load manifest %s: %w", path, err)
+	}
+
+	return m, nil
+}
+
+// Parse decodes manifest JSON, migrating older schema versions to the
+// current one, and validates it
+func Parse(data []byte) (*Manifest, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid manifest JSON: %w", err)
+	}
+	if raw == nil {
+		return nil, fmt.Errorf("invalid manifest JSON: top level must be an object")
+	}
+
+	// 1. Detect the schema version
+	version := legacySchemaVersion
+	if v, ok := raw["schema_version"]; ok {
+		number, ok := v.(float64)
+		if !ok || number != float64(int(number)) || number < 1 {
+			return nil, fmt.Errorf("invalid schema_version %v", v)
+		}
+		version = int(number)
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("manifest schema version %d is newer than supported version %d", version, SchemaVersion)
+	}
+
+	// 2. Apply migrations up to the current version
+	for ; version < SchemaVersion; version++ {
+		migrate, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from schema version %d", version)
+		}
+		if err := migrate(raw); err != nil {
+			return nil, fmt.Errorf("failed to migrate from schema version %d: %w", version, err)
+		}
+		raw["schema_version"] = version + 1
+	}
+
+	// 3. Decode into the current structure
+	migrated, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("failed to re-encode manifest: %w", err)
+	}
+	var m Manifest
+	if err := json.Unmarshal(migrated, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+
+	// 4. Validate
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// Validate checks that the manifest is well-formed
+func (m *Manifest) Validate() error {
+	if m.SchemaVersion != SchemaVersion {
+		return fmt.Errorf("unsupported schema version %d", m.SchemaVersion)
+	}
+	if m.TotalImages != len(m.Images) {
+		return fmt.Errorf("total_images is %d but manifest lists %d images", m.TotalImages, len(m.Images))
+	}
+
+	seen := make(map[string]bool, len(m.Images))
+	for i, img := range m.Images {
+		if img.Filename == "" {
+			return fmt.Errorf("image %d has no filename", i)
+		}
+		if seen[img.Filename] {
+			return fmt.Errorf("image %s is listed twice", img.Filename)
+		}
+		seen[img.Filename] = true
+
+		if img.Width <= 0 || img.Height <= 0 {
+			return fmt.Errorf("image %s has invalid dimensions %dx%d", img.Filename, img.Width, img.Height)
+		}
+		if img.Format == "" {
+			return fmt.Errorf("image %s has no format", img.Filename)
+		}
+	}
+
+	return nil
+}
+
+// migrateV1ToV2 upgrades manifests written before schema versioning, which
+// may lack total_images
+func migrateV1ToV2(raw map[string]interface{}) error {
+	images, ok := raw["images"]
+	if !ok || images == nil {
+		raw["images"] = []interface{}{}
+		images = raw["images"]
+	}
+
+	list, ok := images.([]interface{})
+	if !ok {
+		return fmt.Errorf("images must be an array")
+	}
+	raw["total_images"] = len(list)
+
+	return nil
+}
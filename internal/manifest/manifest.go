@@ -3,8 +3,10 @@ package manifest
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,31 +15,114 @@ import (
 
 // Manifest represents the complete metadata for all generated images
 type Manifest struct {
+	SchemaVersion int           `json:"schema_version"`
 	GeneratedAt   string        `json:"generated_at"`
 	ToolVersion   string        `json:"tool_version"`
 	ConfigVersion string        `json:"config_version"`
 	TotalImages   int           `json:"total_images"`
+	Provenance    *Provenance   `json:"provenance,omitempty"`
 	Images        []ImageRecord `json:"images"`
 }
 
 // ImageRecord represents metadata for a single generated image
 type ImageRecord struct {
-	Filename      string  `json:"filename"`
-	Category      string  `json:"category"`
-	Subcategory   string  `json:"subcategory"`
-	Width         int     `json:"width"`
-	Height        int     `json:"height"`
-	Ratio         string  `json:"ratio"`
-	RatioDecimal  float64 `json:"ratio_decimal"`
-	Format        string  `json:"format"`
-	Quality       int     `json:"quality"`
-	FileSizeBytes int64   `json:"file_size_bytes"`
-	SizeCategory  string  `json:"size_category"`
+	Filename      string         `json:"filename"`
+	Category      string         `json:"category"`
+	Subcategory   string         `json:"subcategory"`
+	Width         int            `json:"width"`
+	Height        int            `json:"height"`
+	Ratio         string         `json:"ratio"`
+	RatioDecimal  float64        `json:"ratio_decimal"`
+	Format        string         `json:"format"`
+	Quality       int            `json:"quality"`
+	FileSizeBytes int64          `json:"file_size_bytes"`
+	MimeType      string         `json:"mime_type,omitempty"`
+	SHA256        string         `json:"sha256,omitempty"`
+	PixelSHA256   string         `json:"pixel_sha256,omitempty"`
+	DHash         string         `json:"dhash,omitempty"`
+	PHash         string         `json:"phash,omitempty"`
+	SpecSHA256    string         `json:"spec_sha256,omitempty"`
+	SizeCategory  string         `json:"size_category"`
+	Variant       string         `json:"variant,omitempty"`
+	Lossless      bool           `json:"lossless,omitempty"`
+	NearLossless  int            `json:"near_lossless,omitempty"`
+	HasAlpha      bool           `json:"has_alpha,omitempty"`
+	Interlaced    bool           `json:"interlaced,omitempty"`
+	Metadata      []string       `json:"metadata,omitempty"`
+	Compression   string         `json:"compression,omitempty"`
+	Pages         int            `json:"pages,omitempty"`
+	Overlays      []string       `json:"overlays,omitempty"`
+	SafeAreas     []SafeArea     `json:"safe_areas,omitempty"`
+	FocalPoint    *FocalPoint    `json:"focal_point,omitempty"`
+	Scene         *Scene         `json:"scene,omitempty"`
+	Animation     *AnimationInfo `json:"animation,omitempty"`
+}
+
+// SafeArea is a named region of a target image that is not covered by
+// platform UI, in pixel coordinates
+type SafeArea struct {
+	Name   string `json:"name"`
+	X      int    `json:"x"`
+	Y      int    `json:"y"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+// FocalPoint is the position of the subject drawn on a focal point image,
+// both normalized and in pixels
+type FocalPoint struct {
+	Name   string  `json:"name,omitempty"` // named position, e.g. top-left-third
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	PixelX int     `json:"pixel_x"`
+	PixelY int     `json:"pixel_y"`
+	Radius int     `json:"radius"` // subject radius in pixels
+}
+
+// Scene is the seed and ground truth of a synthetic scene image
+type Scene struct {
+	Seed    int64         `json:"seed"`
+	Objects []SceneObject `json:"objects"`
+}
+
+// SceneObject is an annotated object of a scene in pixel coordinates
+type SceneObject struct {
+	Class   string     `json:"class"`
+	BBox    [4]float64 `json:"bbox"`    // x, y, width, height
+	Polygon []float64  `json:"polygon"` // x1, y1, x2, y2, ...
+	Area    float64    `json:"area"`    // area enclosed by the polygon
+}
+
+// SpecIDLength is the number of hex digits of the spec fingerprint used as
+// a short, stable image ID
+const SpecIDLength = generator.SpecIDLength
+
+// SpecID returns the short ID of the image's generation spec, or an empty
+// string if the manifest predates spec fingerprints
+func (r ImageRecord) SpecID() string {
+	if len(r.SpecSHA256) < SpecIDLength {
+		return ""
+	}
+	return r.SpecSHA256[:SpecIDLength]
+}
+
+// AnimationInfo represents the animation settings of a multi-frame image
+type AnimationInfo struct {
+	Frames       int    `json:"frames"`
+	FrameDelayMs int    `json:"frame_delay_ms"`
+	LoopCount    int    `json:"loop_count"`
+	DisposeOp    string `json:"dispose_op,omitempty"`
+	BlendOp      string `json:"blend_op,omitempty"`
+
+	// SeparateDefaultImage is true when the file carries a still default
+	// image that differs from frame 1
+	SeparateDefaultImage bool `json:"separate_default_image,omitempty"`
 }
 
 // NewManifest creates a new Manifest
 func NewManifest(toolVersion, configVersion string) *Manifest {
 	return &Manifest{
+		SchemaVersion: SchemaVersion,
 		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
 		ToolVersion:   toolVersion,
 		ConfigVersion: configVersion,
@@ -46,9 +131,13 @@ func NewManifest(toolVersion, configVersion string) *Manifest {
 }
 
 // AddImage adds an image record to the manifest
-func (m *Manifest) AddImage(spec generator.ImageSpec, fileSize int64) {
+func (m *Manifest) AddImage(spec generator.ImageSpec, fileSize int64) error {
 	// Determine category and subcategory from output path
 	category, subcategory := extractCategoryFromPath(spec.OutputPath)
+	specSHA256, err := spec.Fingerprint()
+	if err != nil {
+		return fmt.Errorf("failed to add %s: %w", spec.Filename, err)
+	}
 
 	record := ImageRecord{
 		Filename:      getRelativePath(spec.OutputPath),
@@ -61,16 +150,101 @@ func (m *Manifest) AddImage(spec generator.ImageSpec, fileSize int64) {
 		Format:        strings.ToLower(spec.Format),
 		Quality:       spec.Quality,
 		FileSizeBytes: fileSize,
+		MimeType:      spec.MimeType,
+		SpecSHA256:    specSHA256,
 		SizeCategory:  strings.ToLower(spec.SizeCategory),
+		Variant:       spec.Variant,
+		Lossless:      spec.Options.Lossless,
+		NearLossless:  spec.Options.NearLossless,
+		HasAlpha:      spec.Options.Alpha,
+		Interlaced:    spec.Options.Interlaced,
+		Metadata:      spec.Options.Metadata,
+		Compression:   spec.Options.Compression,
+		Overlays:      spec.Overlays,
+	}
+
+	for _, area := range spec.SafeAreas {
+		record.SafeAreas = append(record.SafeAreas, SafeArea{
+			Name:   area.Name,
+			X:      area.Rect.Min.X,
+			Y:      area.Rect.Min.Y,
+			Width:  area.Rect.Dx(),
+			Height: area.Rect.Dy(),
+		})
+	}
+
+	if spec.FocalPoint != nil {
+		pixel := spec.FocalPoint.Pixel(spec.Width, spec.Height)
+		record.FocalPoint = &FocalPoint{
+			Name:   spec.FocalPoint.Name,
+			X:      spec.FocalPoint.X,
+			Y:      spec.FocalPoint.Y,
+			PixelX: pixel.X,
+			PixelY: pixel.Y,
+			Radius: generator.FocalSubjectRadius(spec.Width, spec.Height),
+		}
+	}
+
+	if spec.Scene != nil {
+		objects, err := generator.SceneObjects(spec)
+		if err != nil {
+			return fmt.Errorf("failed to add %s: %w", spec.Filename, err)
+		}
+		record.Scene = &Scene{Seed: spec.Scene.Seed, Objects: make([]SceneObject, 0, len(objects))}
+		for _, o := range objects {
+			x, y, w, h := o.Bounds()
+			object := SceneObject{
+				Class: o.Class,
+				BBox:  [4]float64{round2(x), round2(y), round2(w), round2(h)},
+				Area:  round2(o.Area()),
+			}
+			for _, p := range o.Polygon {
+				object.Polygon = append(object.Polygon, p[0], p[1])
+			}
+			record.Scene.Objects = append(record.Scene.Objects, object)
+		}
+	}
+
+	if spec.Options.IsMultiPage() {
+		record.Pages = spec.Options.Pages
+	}
+
+	if spec.Options.IsAnimated() {
+		record.Animation = &AnimationInfo{
+			Frames:       spec.Options.Frames,
+			FrameDelayMs: spec.Options.FrameDelayMs(),
+			LoopCount:    spec.Options.LoopCount,
+			DisposeOp:    spec.Options.DisposeOp,
+			BlendOp:      spec.Options.BlendOp,
+
+			SeparateDefaultImage: spec.Options.SeparateDefault,
+		}
 	}
 
 	m.Images = append(m.Images, record)
 	m.TotalImages = len(m.Images)
+	return nil
+}
+
+// AddResult adds the image of a successful generation result, including
+// its checksum and pixel hashes
+func (m *Manifest) AddResult(result generator.GenerationResult) error {
+	if err := m.AddImage(result.Spec, result.FileSize); err != nil {
+		return err
+	}
+
+	record := &m.Images[len(m.Images)-1]
+	record.SHA256 = result.Fingerprint.SHA256
+	record.PixelSHA256 = result.Fingerprint.PixelSHA256
+	record.DHash = result.Fingerprint.DHash
+	record.PHash = result.Fingerprint.PHash
+	return nil
 }
 
 // Write writes the manifest to a JSON file
 func (m *Manifest) Write(outputPath string) error {
 	// Ensure the manifest is up to date
+	m.SchemaVersion = SchemaVersion
 	m.TotalImages = len(m.Images)
 
 	// Marshal to JSON with pretty printing
@@ -87,6 +261,14 @@ func (m *Manifest) Write(outputPath string) error {
 	return nil
 }
 
+// categoryDirs are the top-level output categories
+var categoryDirs = []string{"ratios", "targets", "edge-cases", "focal-points", "scenes", "variants"}
+
+// isCategoryDir checks if a path component is a top-level output category
+func isCategoryDir(part string) bool {
+	return slices.Contains(categoryDirs, part)
+}
+
 // extractCategoryFromPath extracts category and subcategory from file path
 // e.g., "/path/to/ratios/2-3/file.jpg" -> ("ratios", "2-3")
 func extractCategoryFromPath(path string) (category, subcategory string) {
@@ -94,10 +276,10 @@ func extractCategoryFromPath(path string) (category, subcategory string) {
 	dir := filepath.Dir(path)
 	parts := strings.Split(filepath.ToSlash(dir), "/")
 
-	// Find the category (ratios, targets, edge-cases)
+	// Find the category (ratios, targets, edge-cases, focal-points, scenes, variants)
 	for i := len(parts) - 1; i >= 0; i-- {
 		part := parts[i]
-		if part == "ratios" || part == "targets" || part == "edge-cases" {
+		if isCategoryDir(part) {
 			category = part
 			// Subcategory is the next part if it exists
 			if i+1 < len(parts) {
@@ -110,6 +292,11 @@ func extractCategoryFromPath(path string) (category, subcategory string) {
 	return category, subcategory
 }
 
+// round2 rounds v to two decimals, the precision of scene polygons
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
 // getRelativePath returns the relative path from the base directory
 // e.g., "/tmp/output/ratios/2-3/file.jpg" -> "ratios/2-3/file.jpg"
 func getRelativePath(path string) string {
@@ -117,7 +304,7 @@ func getRelativePath(path string) string {
 	parts := strings.Split(filepath.ToSlash(path), "/")
 
 	for i, part := range parts {
-		if part == "ratios" || part == "targets" || part == "edge-cases" {
+		if isCategoryDir(part) {
 			// Return from this point onwards
 			return strings.Join(parts[i:], "/")
 		}
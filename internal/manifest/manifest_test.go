@@ -2,11 +2,18 @@ package manifest
 
 import (
 	"encoding/json"
+	"image"
+	"math"
 	"os"
 	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
 	"github.com/gruz0/futuage-test-image-generator/internal/generator"
 )
 
@@ -50,7 +57,9 @@ func TestManifest_AddImage(t *testing.T) {
 		Filename:     "test.jpg",
 	}
 
-	m.AddImage(spec, 12345)
+	if err := m.AddImage(spec, 12345); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
 
 	if m.TotalImages != 1 {
 		t.Errorf("After AddImage(), TotalImages = %d, want 1", m.TotalImages)
@@ -87,12 +96,227 @@ func TestManifest_AddImage(t *testing.T) {
 	}
 }
 
+func TestManifest_AddImage_Variant(t *testing.T) {
+	m := NewManifest("1.0.0", "1.0.0")
+
+	if err := m.AddImage(generator.ImageSpec{
+		Width:      500,
+		Height:     500,
+		Format:     "WEBP",
+		OutputPath: "/tmp/test/variants/webp/animated_500x500_webp_q82.webp",
+		Variant:    "animated",
+		Options:    generator.EncodeOptions{Frames: 10, LoopCount: 2, Metadata: []string{"exif"}},
+	}, 4096); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+
+	img := m.Images[0]
+
+	if img.Category != "variants" || img.Subcategory != "webp" {
+		t.Errorf("Image category = %q/%q, want %q/%q", img.Category, img.Subcategory, "variants", "webp")
+	}
+
+	if img.Filename != "variants/webp/animated_500x500_webp_q82.webp" {
+		t.Errorf("Image.Filename = %q", img.Filename)
+	}
+
+	if img.Variant != "animated" {
+		t.Errorf("Image.Variant = %q, want %q", img.Variant, "animated")
+	}
+
+	if img.Animation == nil {
+		t.Fatal("Image.Animation is nil for animated spec")
+	}
+
+	want := AnimationInfo{Frames: 10, FrameDelayMs: generator.DefaultFrameDelay, LoopCount: 2}
+	if *img.Animation != want {
+		t.Errorf("Image.Animation = %+v, want %+v", *img.Animation, want)
+	}
+}
+
+func TestManifest_AddImage_SafeAreas(t *testing.T) {
+	m := NewManifest("1.0.0", "1.0.0")
+	if err := m.AddImage(generator.ImageSpec{
+		Width:      1080,
+		Height:     1920,
+		Format:     "JPEG",
+		OutputPath: "/tmp/test/targets/IG_STORY_1080x1920_jpeg_q85.jpg",
+		SafeAreas: []generator.SafeArea{
+			{Name: "ui", Rect: image.Rect(0, 250, 1080, 1670)},
+			{Name: "caption", Rect: image.Rect(60, 130, 940, 1440)},
+		},
+	}, 4096); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+
+	img := m.Images[0]
+	want := []SafeArea{
+		{Name: "ui", X: 0, Y: 250, Width: 1080, Height: 1420},
+		{Name: "caption", X: 60, Y: 130, Width: 880, Height: 1310},
+	}
+	if !reflect.DeepEqual(img.SafeAreas, want) {
+		t.Errorf("Image.SafeAreas = %+v, want %+v", img.SafeAreas, want)
+	}
+	if got := safeAreasValue(img); got != "ui:0,250,1080,1420;caption:60,130,880,1310" {
+		t.Errorf("safeAreasValue() = %q", got)
+	}
+}
+
+func TestManifest_AddImage_FocalPoint(t *testing.T) {
+	m := NewManifest("1.0.0", "1.0.0")
+	if err := m.AddImage(generator.ImageSpec{
+		Width:      1600,
+		Height:     900,
+		Format:     "JPEG",
+		OutputPath: "/tmp/test/focal-points/wide-top-left_1600x900_jpeg_q82.jpg",
+		FocalPoint: &generator.FocalPoint{Name: "top-left-third", X: 1.0 / 3, Y: 1.0 / 3},
+	}, 4096); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+
+	img := m.Images[0]
+	if img.Category != "focal-points" {
+		t.Errorf("Image.Category = %q, want %q", img.Category, "focal-points")
+	}
+	want := FocalPoint{Name: "top-left-third", X: 1.0 / 3, Y: 1.0 / 3, PixelX: 533, PixelY: 300, Radius: 90}
+	if img.FocalPoint == nil || *img.FocalPoint != want {
+		t.Errorf("Image.FocalPoint = %+v, want %+v", img.FocalPoint, want)
+	}
+}
+
+func TestManifest_AddImage_Scene(t *testing.T) {
+	spec := generator.ImageSpec{
+		Width:      1600,
+		Height:     900,
+		Ratio:      "16:9",
+		Format:     "PNG",
+		Quality:    95,
+		OutputPath: "/tmp/test/scenes/scene-wide_1600x900_png_q95.png",
+		Scene:      &generator.Scene{Seed: 1, Objects: 8},
+	}
+	m := NewManifest("1.0.0", "1.0.0")
+	if err := m.AddImage(spec, 4096); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+
+	img := m.Images[0]
+	if img.Category != "scenes" {
+		t.Errorf("Image.Category = %q, want %q", img.Category, "scenes")
+	}
+	if !slices.Contains(schemaCategories(t), img.Category) {
+		t.Errorf("Schema category enum is missing %q", img.Category)
+	}
+	objects, err := generator.SceneObjects(spec)
+	if err != nil {
+		t.Fatalf("SceneObjects() error = %v", err)
+	}
+	if img.Scene == nil || img.Scene.Seed != 1 || len(img.Scene.Objects) != len(objects) {
+		t.Fatalf("Image.Scene = %+v, want seed 1 and %d objects", img.Scene, len(objects))
+	}
+	for i, o := range img.Scene.Objects {
+		if o.Class != objects[i].Class || len(o.Polygon) != 2*len(objects[i].Polygon) {
+			t.Errorf("object %d = %s with %d coordinates, want %s with %d", i, o.Class, len(o.Polygon), objects[i].Class, 2*len(objects[i].Polygon))
+		}
+		if o.BBox[2] <= 0 || o.BBox[3] <= 0 || o.Area <= 0 {
+			t.Errorf("object %d bbox = %v, area = %v", i, o.BBox, o.Area)
+		}
+	}
+}
+
+func TestManifest_AddImage_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		spec generator.ImageSpec
+	}{
+		{"non-finite ratio", generator.ImageSpec{Width: 100, Height: 100, Format: "PNG", RatioDecimal: math.NaN()}},
+		{"unknown scene class", generator.ImageSpec{Width: 400, Height: 400, Format: "PNG", Scene: &generator.Scene{Seed: 1, Objects: 1, Classes: []string{"cloud"}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewManifest("1.0.0", "1.0.0")
+			if err := m.AddImage(tt.spec, 100); err == nil {
+				t.Error("AddImage() error = nil, want error")
+			}
+			if len(m.Images) != 0 {
+				t.Errorf("len(Images) = %d, want no record for a failed image", len(m.Images))
+			}
+		})
+	}
+}
+
+func TestManifest_AddResult(t *testing.T) {
+	m := NewManifest("1.0.0", "1.0.0")
+	if err := m.AddResult(generator.GenerationResult{
+		Spec: generator.ImageSpec{
+			Width:      100,
+			Height:     100,
+			Format:     "JPEG",
+			MimeType:   "image/jpeg",
+			OutputPath: "/tmp/ratios/1-1/a.jpg",
+		},
+		FileSize: 1000,
+		Fingerprint: fingerprint.Fingerprint{
+			SHA256:      strings.Repeat("a", 64),
+			PixelSHA256: strings.Repeat("b", 64),
+			DHash:       "0123456789abcdef",
+			PHash:       "fedcba9876543210",
+		},
+	}); err != nil {
+		t.Fatalf("AddResult() error = %v", err)
+	}
+
+	img := m.Images[0]
+	if img.MimeType != "image/jpeg" || img.FileSizeBytes != 1000 {
+		t.Errorf("Image = %s %d bytes, want image/jpeg 1000 bytes", img.MimeType, img.FileSizeBytes)
+	}
+	if img.SHA256 != strings.Repeat("a", 64) || img.PixelSHA256 != strings.Repeat("b", 64) {
+		t.Errorf("Image checksums = %s / %s", img.SHA256, img.PixelSHA256)
+	}
+	if img.DHash != "0123456789abcdef" || img.PHash != "fedcba9876543210" {
+		t.Errorf("Image perceptual hashes = %s / %s", img.DHash, img.PHash)
+	}
+	if len(img.SpecSHA256) != 64 {
+		t.Errorf("Image.SpecSHA256 = %q, want a SHA-256", img.SpecSHA256)
+	}
+}
+
+func TestNewProvenance(t *testing.T) {
+	start := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := NewProvenance(nil, start, start.Add(1500*time.Millisecond))
+
+	if p.Args == nil || p.Filters.Ratios == nil {
+		t.Error("NewProvenance() left lists nil, want empty")
+	}
+	if p.GoVersion != runtime.Version() || p.OS != runtime.GOOS || p.Arch != runtime.GOARCH {
+		t.Errorf("NewProvenance() build = %s %s/%s", p.GoVersion, p.OS, p.Arch)
+	}
+	if p.StartedAt != "2025-01-02T03:04:05Z" || p.FinishedAt != "2025-01-02T03:04:06.5Z" || p.DurationSeconds != 1.5 {
+		t.Errorf("NewProvenance() timing = %s - %s (%gs)", p.StartedAt, p.FinishedAt, p.DurationSeconds)
+	}
+
+	// Provenance survives a write/load round trip
+	m := NewManifest("1.0.0", "1.0.0")
+	m.Provenance = p
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	if err := m.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if !reflect.DeepEqual(loaded.Provenance, p) {
+		t.Errorf("Provenance = %+v, want %+v", loaded.Provenance, p)
+	}
+}
+
 func TestManifest_Write(t *testing.T) {
 	tmpDir := t.TempDir()
 	outputPath := filepath.Join(tmpDir, "manifest.json")
 
 	m := NewManifest("1.0.0", "1.0.0")
-	m.AddImage(generator.ImageSpec{
+	if err := m.AddImage(generator.ImageSpec{
 		Width:        100,
 		Height:       100,
 		Ratio:        "1:1",
@@ -103,7 +327,9 @@ func TestManifest_Write(t *testing.T) {
 		Category:     "test",
 		OutputPath:   "/tmp/targets/test.png",
 		Filename:     "test.png",
-	}, 5000)
+	}, 5000); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
 
 	err := m.Write(outputPath)
 	if err != nil {
@@ -215,21 +441,27 @@ func TestManifest_Summary(t *testing.T) {
 	}
 
 	// Add some images
-	m.AddImage(generator.ImageSpec{
+	if err := m.AddImage(generator.ImageSpec{
 		Width:      100,
 		Height:     100,
 		OutputPath: "/tmp/ratios/1-1/a.jpg",
-	}, 1000)
-	m.AddImage(generator.ImageSpec{
+	}, 1000); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+	if err := m.AddImage(generator.ImageSpec{
 		Width:      200,
 		Height:     200,
 		OutputPath: "/tmp/ratios/1-1/b.jpg",
-	}, 2000)
-	m.AddImage(generator.ImageSpec{
+	}, 2000); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+	if err := m.AddImage(generator.ImageSpec{
 		Width:      300,
 		Height:     300,
 		OutputPath: "/tmp/targets/c.jpg",
-	}, 3000)
+	}, 3000); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
 
 	summary = m.Summary()
 
@@ -246,3 +478,473 @@ func TestManifest_Summary(t *testing.T) {
 	}
 }
 
+func TestLoad_RoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+
+	m := NewManifest("1.0.0", "1.0.0")
+	if err := m.AddImage(generator.ImageSpec{
+		Width:        500,
+		Height:       500,
+		Ratio:        "1:1",
+		RatioDecimal: 1.0,
+		Format:       "GIF",
+		Quality:      100,
+		SizeCategory: "Small",
+		OutputPath:   "/tmp/out/variants/gif/animated_500x500_gif_q100.gif",
+		Variant:      "animated",
+		Options:      generator.EncodeOptions{Frames: 5, LoopCount: 1},
+	}, 4096); err != nil {
+		t.Fatalf("AddImage() error = %v", err)
+	}
+	if err := m.Write(path); err != nil {
+		t.Fatalf("Manifest.Write() error = %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if loaded.SchemaVersion != SchemaVersion {
+		t.Errorf("Load() SchemaVersion = %d, want %d", loaded.SchemaVersion, SchemaVersion)
+	}
+	if len(loaded.Images) != 1 || loaded.Images[0].Animation == nil || loaded.Images[0].Animation.Frames != 5 {
+		t.Errorf("Load() Images = %+v, want the animated record", loaded.Images)
+	}
+}
+
+func TestParse_MigratesLegacyManifest(t *testing.T) {
+	// Manifests written before schema versioning have no schema_version
+	legacy := `{
+		"generated_at": "2025-01-01T00:00:00Z",
+		"tool_version": "1.0.0",
+		"config_version": "1.0.0",
+		"images": [
+			{"filename": "ratios/1-1/a.jpg", "category": "ratios", "subcategory": "1-1",
+			 "width": 100, "height": 100, "ratio": "1:1", "ratio_decimal": 1,
+			 "format": "jpeg", "quality": 82, "file_size_bytes": 1000, "size_category": "tiny"}
+		]
+	}`
+
+	m, err := Parse([]byte(legacy))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	if m.SchemaVersion != SchemaVersion {
+		t.Errorf("Parse() SchemaVersion = %d, want %d", m.SchemaVersion, SchemaVersion)
+	}
+	if m.TotalImages != 1 {
+		t.Errorf("Parse() TotalImages = %d, want 1", m.TotalImages)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	image := `{"filename": "a.jpg", "width": 10, "height": 10, "format": "jpeg"}`
+
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"invalid JSON", `{`},
+		{"null", `null`},
+		{"array", `[]`},
+		{"string", `"x"`},
+		{"newer schema version", `{"schema_version": 99, "total_images": 0, "images": []}`},
+		{"invalid schema version", `{"schema_version": "2", "total_images": 0, "images": []}`},
+		{"total mismatch", `{"schema_version": 2, "total_images": 2, "images": [` + image + `]}`},
+		{"duplicate filename", `{"schema_version": 2, "total_images": 2, "images": [` + image + `,` + image + `]}`},
+		{"missing dimensions", `{"schema_version": 2, "total_images": 1, "images": [{"filename": "a.jpg", "format": "jpeg"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.data)); err == nil {
+				t.Error("Parse() error = nil, want error")
+			}
+		})
+	}
+}
+
+// schemaCategories returns the category enum of the image schema
+func schemaCategories(t *testing.T) []string {
+	t.Helper()
+	var schema struct {
+		Defs map[string]struct {
+			Properties struct {
+				Category struct {
+					Enum []string `json:"enum"`
+				} `json:"category"`
+			} `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema(), &schema); err != nil {
+		t.Fatalf("Schema() is not valid JSON: %v", err)
+	}
+	return schema.Defs["image"].Properties.Category.Enum
+}
+
+func TestSchema_CoversFields(t *testing.T) {
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	if err := json.Unmarshal(Schema(), &schema); err != nil {
+		t.Fatalf("Schema() is not valid JSON: %v", err)
+	}
+
+	// Every JSON field written by the tool must be described by the schema
+	types := []struct {
+		value      interface{}
+		properties map[string]json.RawMessage
+	}{
+		{Manifest{}, schema.Properties},
+		{ImageRecord{}, schema.Defs["image"].Properties},
+		{AnimationInfo{}, schema.Defs["animation"].Properties},
+		{SafeArea{}, schema.Defs["safe_area"].Properties},
+		{FocalPoint{}, schema.Defs["focal_point"].Properties},
+		{Scene{}, schema.Defs["scene"].Properties},
+		{SceneObject{}, schema.Defs["scene_object"].Properties},
+		{Provenance{}, schema.Defs["provenance"].Properties},
+		{FilterSet{}, schema.Defs["filters"].Properties},
+	}
+	for _, tt := range types {
+		typ := reflect.TypeOf(tt.value)
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			if _, ok := tt.properties[name]; !ok {
+				t.Errorf("Schema is missing %s.%s", typ.Name(), name)
+			}
+		}
+	}
+
+	// Every category the tool writes must be allowed by the schema
+	categories := schemaCategories(t)
+	for _, dir := range categoryDirs {
+		if !slices.Contains(categories, dir) {
+			t.Errorf("Schema category enum is missing %q", dir)
+		}
+	}
+}
+
+func TestManifest_Verify(t *testing.T) {
+	dir := t.TempDir()
+	m := NewManifest("1.0.0", "1.0.0")
+
+	for _, name := range []string{"a.png", "b.png", "c.png", "d.png"} {
+		spec := generator.ImageSpec{
+			Width:        40,
+			Height:       30,
+			Ratio:        "4:3",
+			SizeCategory: "tiny",
+			Category:     "common",
+			Format:       "png",
+			Quality:      95,
+			OutputPath:   filepath.Join(dir, "ratios", "4-3", name),
+		}
+		if err := os.MkdirAll(filepath.Dir(spec.OutputPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := generator.Generate(spec); err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		data, err := os.ReadFile(spec.OutputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := m.AddResult(generator.GenerationResult{Spec: spec, FileSize: int64(len(data)), Fingerprint: fingerprint.Compute(data)}); err != nil {
+			t.Fatalf("AddResult() error = %v", err)
+		}
+	}
+
+	report, err := m.Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+	if !report.OK() || report.Checked != 4 {
+		t.Fatalf("Verify() on intact output = %+v, want OK with 4 checked", report)
+	}
+
+	// Break the output in every way the report distinguishes
+	if err := os.Remove(filepath.Join(dir, "ratios", "4-3", "a.png")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ratios", "4-3", "b.png"), []byte("not a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "ratios", "4-3", "extra.png"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m.Images[2].Width = 41
+	m.Images[3].Format = "JPEG"
+
+	report, err = m.Verify(dir)
+	if err != nil {
+		t.Fatalf("Verify() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(report.Missing, []string{"ratios/4-3/a.png"}) {
+		t.Errorf("Missing = %v", report.Missing)
+	}
+	if !reflect.DeepEqual(report.Extra, []string{"ratios/4-3/extra.png"}) {
+		t.Errorf("Extra = %v", report.Extra)
+	}
+
+	fields := make(map[string][]string)
+	for _, mm := range report.Mismatched {
+		fields[mm.Filename] = append(fields[mm.Filename], mm.Field)
+	}
+	want := map[string][]string{
+		"ratios/4-3/b.png": {"size", "sha256", "decode"},
+		"ratios/4-3/c.png": {"dimensions"},
+		"ratios/4-3/d.png": {"format"},
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Errorf("Mismatched fields = %v, want %v", fields, want)
+	}
+	if report.OK() || report.Problems() != 7 {
+		t.Errorf("Problems() = %d, want 7", report.Problems())
+	}
+}
+
+func TestDiff(t *testing.T) {
+	oldManifest := &Manifest{Images: []ImageRecord{
+		{Filename: "a.jpg", Width: 100, Height: 100, Format: "JPEG", FileSizeBytes: 1000, PixelSHA256: "p1"},
+		{Filename: "b.jpg", Width: 100, Height: 100, Format: "JPEG", FileSizeBytes: 1000},
+		{Filename: "c.png", Width: 100, Height: 100, Format: "PNG", FileSizeBytes: 1000},
+	}}
+	newManifest := &Manifest{Images: []ImageRecord{
+		{Filename: "a.jpg", Width: 100, Height: 100, Format: "JPEG", FileSizeBytes: 1050, PixelSHA256: "p2"},
+		{Filename: "c.png", Width: 120, Height: 100, Format: "WEBP", FileSizeBytes: 1500},
+		{Filename: "d.gif", Width: 10, Height: 10, Format: "GIF", FileSizeBytes: 450},
+	}}
+
+	report := Diff(oldManifest, newManifest)
+
+	if !reflect.DeepEqual(report.Added, []string{"d.gif"}) {
+		t.Errorf("Added = %v", report.Added)
+	}
+	if !reflect.DeepEqual(report.Removed, []string{"b.jpg"}) {
+		t.Errorf("Removed = %v", report.Removed)
+	}
+
+	wantChanges := []Change{
+		{Filename: "a.jpg", Field: "pixels", Old: "p1", New: "p2"},
+		{Filename: "c.png", Field: "dimensions", Old: "100x100", New: "120x100"},
+		{Filename: "c.png", Field: "format", Old: "PNG", New: "WEBP"},
+	}
+	if !reflect.DeepEqual(report.Changed, wantChanges) {
+		t.Errorf("Changed = %+v, want %+v", report.Changed, wantChanges)
+	}
+
+	if len(report.SizeDeltas) != 2 || report.SizeDeltas[0].DeltaPercent != 5 || report.SizeDeltas[1].DeltaBytes != 500 {
+		t.Errorf("SizeDeltas = %+v", report.SizeDeltas)
+	}
+	if report.OldBytes != 3000 || report.NewBytes != 3000 || report.TotalDeltaPercent() != 0 {
+		t.Errorf("Totals = %d -> %d", report.OldBytes, report.NewBytes)
+	}
+	if !report.HasChanges() {
+		t.Error("HasChanges() = false, want true")
+	}
+}
+
+func TestDiffReport_Check(t *testing.T) {
+	oldManifest := &Manifest{Images: []ImageRecord{
+		{Filename: "a.jpg", FileSizeBytes: 1000},
+		{Filename: "b.jpg", FileSizeBytes: 1000},
+	}}
+	newManifest := &Manifest{Images: []ImageRecord{
+		{Filename: "a.jpg", FileSizeBytes: 1050},
+		{Filename: "b.jpg", FileSizeBytes: 1200},
+	}}
+
+	percent := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name       string
+		thresholds DiffThresholds
+		want       int
+	}{
+		{"no limits", DiffThresholds{}, 0},
+		{"per image 10%", DiffThresholds{MaxSizeGrowth: percent(10)}, 1},
+		{"per image 1%", DiffThresholds{MaxSizeGrowth: percent(1)}, 2},
+		{"per image 0%", DiffThresholds{MaxSizeGrowth: percent(0)}, 2},
+		{"total 10%", DiffThresholds{MaxTotalSizeGrowth: percent(10)}, 1},
+		{"total 20%", DiffThresholds{MaxTotalSizeGrowth: percent(20)}, 0},
+		{"total 0%", DiffThresholds{MaxTotalSizeGrowth: percent(0)}, 1},
+		{"both", DiffThresholds{MaxSizeGrowth: percent(10), MaxTotalSizeGrowth: percent(10)}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := Diff(oldManifest, newManifest)
+			if got := report.Check(tt.thresholds); got != tt.want {
+				t.Errorf("Check() = %d, want %d (%v)", got, tt.want, report.Violations)
+			}
+		})
+	}
+
+	same := Diff(oldManifest, oldManifest)
+	if got := same.Check(DiffThresholds{MaxSizeGrowth: percent(0), MaxTotalSizeGrowth: percent(0)}); got != 0 {
+		t.Errorf("Check() of identical manifests with 0%% limits = %d, want 0 (%v)", got, same.Violations)
+	}
+	if same.HasChanges() {
+		t.Errorf("Diff() of identical manifests has changes: %+v", same)
+	}
+}
+
+func TestColumns_CoverFields(t *testing.T) {
+	names := make(map[string]bool)
+	for _, name := range ColumnNames() {
+		names[name] = true
+	}
+
+	for _, typ := range []reflect.Type{reflect.TypeOf(ImageRecord{}), reflect.TypeOf(AnimationInfo{})} {
+		for i := 0; i < typ.NumField(); i++ {
+			name := strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]
+			if name != "animation" && !names[name] {
+				t.Errorf("field %s.%s has no flat column", typ.Name(), name)
+			}
+		}
+	}
+}
+
+func TestManifest_Encode(t *testing.T) {
+	m := NewManifest("1.0.0", "1.0.0")
+	m.Images = []ImageRecord{
+		{Filename: "ratios/1-1/a.jpg", Width: 100, Height: 100, Ratio: "1:1", RatioDecimal: 1, Format: "jpeg", Quality: 82, FileSizeBytes: 1000, SizeCategory: "tiny"},
+		{Filename: "variants/gif/it's.gif", Width: 10, Height: 20, Format: "gif", Metadata: []string{"exif", "xmp"}, HasAlpha: true,
+			Animation: &AnimationInfo{Frames: 5, FrameDelayMs: 100}},
+	}
+
+	t.Run("csv", func(t *testing.T) {
+		var buf strings.Builder
+		if err := m.Encode(&buf, FormatCSV, SQLLayout{}); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 3 || !strings.HasPrefix(lines[0], "filename,category,subcategory,width,height") {
+			t.Fatalf("CSV = %q", buf.String())
+		}
+		if !strings.Contains(lines[2], "exif;xmp") || !strings.HasSuffix(lines[2], ",5,100,0,,,false") {
+			t.Errorf("CSV row = %q", lines[2])
+		}
+	})
+
+	t.Run("ndjson", func(t *testing.T) {
+		var buf strings.Builder
+		if err := m.Encode(&buf, FormatNDJSON, SQLLayout{}); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("NDJSON has %d lines, want 2", len(lines))
+		}
+		var img ImageRecord
+		if err := json.Unmarshal([]byte(lines[1]), &img); err != nil {
+			t.Fatalf("NDJSON line is not JSON: %v", err)
+		}
+		if img.Animation == nil || img.Animation.Frames != 5 {
+			t.Errorf("NDJSON record = %+v", img)
+		}
+	})
+
+	t.Run("sql", func(t *testing.T) {
+		cols, err := ParseSQLColumns([]string{"path=filename", "width", "alpha=has_alpha", "frames", "ratio_decimal"})
+		if err != nil {
+			t.Fatalf("ParseSQLColumns() error = %v", err)
+		}
+
+		var buf strings.Builder
+		if err := m.Encode(&buf, FormatSQL, SQLLayout{Table: "fixtures.assets", Columns: cols}); err != nil {
+			t.Fatalf("Encode() error = %v", err)
+		}
+		want := `INSERT INTO "fixtures"."assets" ("path", "width", "alpha", "frames", "ratio_decimal") VALUES ('ratios/1-1/a.jpg', 100, FALSE, NULL, 1);
+INSERT INTO "fixtures"."assets" ("path", "width", "alpha", "frames", "ratio_decimal") VALUES ('variants/gif/it''s.gif', 10, TRUE, 5, 0);
+`
+		if buf.String() != want {
+			t.Errorf("SQL =\n%s\nwant\n%s", buf.String(), want)
+		}
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		if err := m.Encode(&strings.Builder{}, "xml", SQLLayout{}); err == nil {
+			t.Error("Encode() expected error for unknown format")
+		}
+	})
+}
+
+func TestParseSQLColumns_Errors(t *testing.T) {
+	for _, spec := range [][]string{{"nope"}, {"path="}, {"=filename"}} {
+		if _, err := ParseSQLColumns(spec); err == nil {
+			t.Errorf("ParseSQLColumns(%v) expected error", spec)
+		}
+	}
+}
+
+func TestSignFile(t *testing.T) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "signing.key")
+	if err := GenerateKey(keyPath); err != nil {
+		t.Fatalf("GenerateKey() error = %v", err)
+	}
+	private, err := LoadPrivateKey(keyPath)
+	if err != nil {
+		t.Fatalf("LoadPrivateKey() error = %v", err)
+	}
+	public, err := LoadPublicKey(keyPath + ".pub")
+	if err != nil {
+		t.Fatalf("LoadPublicKey() error = %v", err)
+	}
+	if _, err := LoadPublicKey(keyPath); err == nil {
+		t.Error("LoadPublicKey() accepted a private key")
+	}
+
+	m := NewManifest("1.0.0", "1.0.0")
+	m.Images = []ImageRecord{{Filename: "ratios/1-1/a.jpg", Width: 1, Height: 1, Format: "jpeg", SHA256: strings.Repeat("a", 64)}}
+	path := filepath.Join(dir, "manifest.json")
+	if err := m.Write(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SignFile(path, private); err != nil {
+		t.Fatalf("SignFile() error = %v", err)
+	}
+	if err := VerifyFileSignature(path, public); err != nil {
+		t.Errorf("VerifyFileSignature() error = %v", err)
+	}
+
+	// A different key does not verify
+	otherPath := filepath.Join(dir, "other.key")
+	if err := GenerateKey(otherPath); err != nil {
+		t.Fatal(err)
+	}
+	other, _ := LoadPublicKey(otherPath + ".pub")
+	if err := VerifyFileSignature(path, other); err == nil {
+		t.Error("VerifyFileSignature() accepted the wrong key")
+	}
+
+	// Tampering with a checksum breaks the signature
+	data, _ := os.ReadFile(path)
+	tampered := strings.Replace(string(data), strings.Repeat("a", 64), strings.Repeat("b", 64), 1)
+	if err := os.WriteFile(path, []byte(tampered), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := VerifyFileSignature(path, public); err == nil {
+		t.Error("VerifyFileSignature() accepted a tampered manifest")
+	}
+
+	// Manifests without checksums cannot be signed
+	m.Images[0].SHA256 = ""
+	if err := m.Write(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := SignFile(path, private); err == nil {
+		t.Error("SignFile() signed a manifest without checksums")
+	}
+}
@@ -0,0 +1,54 @@
+package manifest
+
+import (
+	"runtime"
+	"time"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+)
+
+// Provenance records how a fixture set was produced, so a set found in a
+// cache can be traced back to the exact configuration, invocation and
+// build
+type Provenance struct {
+	ConfigSource     string            `json:"config_source"` // config file path, or "default"
+	ConfigSHA256     string            `json:"config_sha256"`
+	Args             []string          `json:"args"`
+	Filters          FilterSet         `json:"filters"`
+	GoVersion        string            `json:"go_version"`
+	EncoderLibraries map[string]string `json:"encoder_libraries"`
+	OS               string            `json:"os"`
+	Arch             string            `json:"arch"`
+	StartedAt        string            `json:"started_at"`
+	FinishedAt       string            `json:"finished_at"`
+	DurationSeconds  float64           `json:"duration_seconds"`
+}
+
+// FilterSet records the ratio, size and format filters of a run; empty
+// lists mean no filter
+type FilterSet struct {
+	Ratios  []string `json:"ratios"`
+	Sizes   []string `json:"sizes"`
+	Formats []string `json:"formats"`
+}
+
+// NewProvenance returns the provenance of a run of this binary with the
+// given arguments, filled in with the Go version, encoder library
+// versions and platform. Config and filter details are set by the caller.
+func NewProvenance(args []string, startedAt, finishedAt time.Time) *Provenance {
+	if args == nil {
+		args = []string{}
+	}
+
+	return &Provenance{
+		Args:             args,
+		Filters:          FilterSet{Ratios: []string{}, Sizes: []string{}, Formats: []string{}},
+		GoVersion:        runtime.Version(),
+		EncoderLibraries: generator.EncoderLibraries(),
+		OS:               runtime.GOOS,
+		Arch:             runtime.GOARCH,
+		StartedAt:        startedAt.UTC().Format(time.RFC3339Nano),
+		FinishedAt:       finishedAt.UTC().Format(time.RFC3339Nano),
+		DurationSeconds:  finishedAt.Sub(startedAt).Seconds(),
+	}
+}
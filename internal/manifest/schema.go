@@ -0,0 +1,16 @@
+package manifest
+
+import (
+	_ "embed"
+)
+
+// schemaJSON is the JSON Schema describing manifest.json
+//
+//go:embed manifest.schema.json
+var schemaJSON []byte
+
+// Schema returns the JSON Schema (draft 2020-12) of the current manifest
+// schema version
+func Schema() []byte {
+	return append([]byte(nil), schemaJSON...)
+}
@@ -0,0 +1,151 @@
+package manifest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// SignatureSuffix is appended to a manifest path to name its detached
+// signature file, e.g. "manifest.json.sig"
+const SignatureSuffix = ".sig"
+
+// GenerateKey creates an ed25519 key pair and writes the private key to
+// path (mode 0600) and the public key to path + ".pub", both PEM encoded
+// like `openssl genpkey -algorithm ed25519`
+func GenerateKey(path string) error {
+	public, private, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return fmt.Errorf("failed to generate key: %w", err)
+	}
+
+	privateDER, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return fmt.Errorf("failed to encode private key: %w", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return fmt.Errorf("failed to encode public key: %w", err)
+	}
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600); err != nil {
+		return fmt.Errorf("failed to write private key: %w", err)
+	}
+	if err := os.WriteFile(path+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0644); err != nil {
+		return fmt.Errorf("failed to write public key: %w", err)
+	}
+	return nil
+}
+
+// LoadPrivateKey reads a PEM encoded PKCS #8 ed25519 private key
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	der, err := readPEM(path, "PRIVATE KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key %s: %w", path, err)
+	}
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("private key %s is not an ed25519 key", path)
+	}
+	return private, nil
+}
+
+// LoadPublicKey reads a PEM encoded PKIX ed25519 public key
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	der, err := readPEM(path, "PUBLIC KEY")
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := x509.ParsePKIXPublicKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key %s: %w", path, err)
+	}
+	public, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an ed25519 key", path)
+	}
+	return public, nil
+}
+
+// readPEM returns the DER bytes of the first PEM block of the given type
+func readPEM(path, blockType string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		return nil, fmt.Errorf("%s does not contain a PEM %q block", path, blockType)
+	}
+	return block.Bytes, nil
+}
+
+// SignFile signs the exact bytes of a manifest file and writes the
+// base64 signature next to it. Every image must have a checksum, so the
+// signature covers the content of every file.
+func SignFile(manifestPath string, key ed25519.PrivateKey) error {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	m, err := Parse(data)
+	if err != nil {
+		return err
+	}
+	if missing := m.MissingChecksums(); len(missing) > 0 {
+		return fmt.Errorf("cannot sign manifest: %d image(s) have no checksum, e.g. %s", len(missing), missing[0])
+	}
+
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(key, data))
+	if err := os.WriteFile(manifestPath+SignatureSuffix, []byte(signature+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write signature: %w", err)
+	}
+	return nil
+}
+
+// VerifyFileSignature checks the detached signature of a manifest file
+func VerifyFileSignature(manifestPath string, key ed25519.PublicKey) error {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return fmt.Errorf("failed to read manifest file: %w", err)
+	}
+
+	encoded, err := os.ReadFile(manifestPath + SignatureSuffix)
+	if err != nil {
+		return fmt.Errorf("failed to read signature: %w", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encoded)))
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return fmt.Errorf("malformed signature %s", manifestPath+SignatureSuffix)
+	}
+
+	if !ed25519.Verify(key, data, signature) {
+		return fmt.Errorf("signature of %s does not match the public key", manifestPath)
+	}
+	return nil
+}
+
+// MissingChecksums returns the images without a SHA-256, whose content a
+// signature cannot vouch for
+func (m *Manifest) MissingChecksums() []string {
+	var missing []string
+	for _, img := range m.Images {
+		if img.SHA256 == "" {
+			missing = append(missing, img.Filename)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,152 @@
+package manifest
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+)
+
+// Mismatch describes a file whose properties differ from its record
+type Mismatch struct {
+	Filename string `json:"filename"`
+	Field    string `json:"field"` // size, sha256, decode, format, dimensions
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// VerifyReport is the result of checking an output directory against a
+// manifest
+type VerifyReport struct {
+	Checked    int        `json:"checked"`
+	Missing    []string   `json:"missing"`
+	Extra      []string   `json:"extra"`
+	Mismatched []Mismatch `json:"mismatched"`
+}
+
+// OK returns true if every file is present and intact and there are no
+// extra files
+func (r *VerifyReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Extra) == 0 && len(r.Mismatched) == 0
+}
+
+// Problems returns the total number of missing, extra and mismatched files
+func (r *VerifyReport) Problems() int {
+	return len(r.Missing) + len(r.Extra) + len(r.Mismatched)
+}
+
+// Verify checks that every image in the manifest exists in dir with the
+// recorded size and checksum, decodes with the recorded format and has the
+// recorded dimensions, and that the category directories hold no other
+// files
+func (m *Manifest) Verify(dir string) (*VerifyReport, error) {
+	report := &VerifyReport{
+		Missing:    []string{},
+		Extra:      []string{},
+		Mismatched: []Mismatch{},
+	}
+
+	// 1. Check every recorded image
+	recorded := make(map[string]bool, len(m.Images))
+	for _, img := range m.Images {
+		recorded[img.Filename] = true
+
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(img.Filename)))
+		if os.IsNotExist(err) {
+			report.Missing = append(report.Missing, img.Filename)
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", img.Filename, err)
+		}
+
+		report.Checked++
+		report.Mismatched = append(report.Mismatched, verifyImage(img, data)...)
+	}
+
+	// 2. Look for files the manifest does not know about
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		// Only image category directories are checked, so reports and
+		// manifests next to them are not extra
+		if top := strings.SplitN(rel, "/", 2)[0]; isCategoryDir(top) && !recorded[rel] {
+			report.Extra = append(report.Extra, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	sort.Strings(report.Missing)
+	sort.Strings(report.Extra)
+
+	return report, nil
+}
+
+// verifyImage compares a file's contents with its record. Checks for
+// fields the record lacks, such as checksums in older manifests, are
+// skipped.
+func verifyImage(img ImageRecord, data []byte) []Mismatch {
+	var mismatches []Mismatch
+	mismatch := func(field, expected, actual string) {
+		mismatches = append(mismatches, Mismatch{Filename: img.Filename, Field: field, Expected: expected, Actual: actual})
+	}
+
+	if int64(len(data)) != img.FileSizeBytes {
+		mismatch("size", fmt.Sprintf("%d bytes", img.FileSizeBytes), fmt.Sprintf("%d bytes", len(data)))
+	}
+
+	fp := fingerprint.Compute(data)
+	if img.SHA256 != "" && fp.SHA256 != img.SHA256 {
+		mismatch("sha256", img.SHA256, fp.SHA256)
+	}
+
+	// Formats without a decoder have no pixel hash in the manifest
+	if fp.Format == "" {
+		if img.PixelSHA256 != "" || img.SHA256 == "" {
+			mismatch("decode", img.Format, "undecodable")
+		}
+		return mismatches
+	}
+
+	if want := decoderFormat(img.Format); fp.Format != want {
+		mismatch("format", want, fp.Format)
+	}
+	if fp.Width != img.Width || fp.Height != img.Height {
+		mismatch("dimensions", fmt.Sprintf("%dx%d", img.Width, img.Height), fmt.Sprintf("%dx%d", fp.Width, fp.Height))
+	}
+
+	return mismatches
+}
+
+// decoderFormat maps a manifest format to the name the image decoders
+// report for it
+func decoderFormat(format string) string {
+	switch strings.ToLower(format) {
+	case "jpg":
+		return "jpeg"
+	case "apng":
+		return "png"
+	case "tif":
+		return "tiff"
+	default:
+		return strings.ToLower(format)
+	}
+}
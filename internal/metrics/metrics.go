@@ -0,0 +1,147 @@
+// Package metrics measures the quality of processed images against the
+// generated originals they were produced from.
+package metrics
+
+import (
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/config"
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+	"github.com/gruz0/futuage-test-image-generator/internal/oracle"
+	"golang.org/x/image/draw"
+)
+
+// MaxPSNR is reported for identical images, whose PSNR is infinite
+const MaxPSNR = 100.0
+
+// SSIM window size and stride in pixels
+const (
+	ssimWindow = 8
+	ssimStride = 4
+)
+
+// Scores holds the quality metrics of one processed image
+type Scores struct {
+	PSNR     float64 `json:"psnr"`     // dB over RGB, higher is better
+	SSIM     float64 `json:"ssim"`     // mean structural similarity of luma, 1 is identical
+	Distance int     `json:"distance"` // pHash Hamming distance in bits, 0 is identical
+}
+
+// Prepare center-crops and resizes an original to the dimensions of a
+// processed image, the way the pipeline is expected to, so both can be
+// compared pixel by pixel
+func Prepare(original image.Image, width, height int) *image.RGBA {
+	bounds := original.Bounds()
+	p := oracle.Predict(bounds.Dx(), bounds.Dy(), "", config.Target{Dimensions: []int{width, height}})
+
+	crop := image.Rect(p.Crop.X, p.Crop.Y, p.Crop.X+p.Crop.Width, p.Crop.Y+p.Crop.Height).Add(bounds.Min)
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), original, crop, draw.Src, nil)
+	return dst
+}
+
+// Measure compares a processed image with its original, rescaling the
+// original as needed
+func Measure(original, processed image.Image) (Scores, error) {
+	bounds := processed.Bounds()
+	if bounds.Empty() {
+		return Scores{}, fmt.Errorf("processed image is empty")
+	}
+
+	reference := Prepare(original, bounds.Dx(), bounds.Dy())
+	actual := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(actual, actual.Bounds(), processed, bounds.Min, draw.Src)
+
+	_, referenceHash := fingerprint.PerceptualHashes(reference)
+	_, actualHash := fingerprint.PerceptualHashes(actual)
+
+	return Scores{
+		PSNR:     PSNR(reference, actual),
+		SSIM:     SSIM(reference, actual),
+		Distance: fingerprint.Distance(referenceHash, actualHash),
+	}, nil
+}
+
+// PSNR returns the peak signal-to-noise ratio of two equally sized images
+// over their RGB channels, capped at MaxPSNR
+func PSNR(a, b *image.RGBA) float64 {
+	var sum float64
+	var n int
+	for y := 0; y < a.Rect.Dy(); y++ {
+		rowA := a.Pix[y*a.Stride:]
+		rowB := b.Pix[y*b.Stride:]
+		for x := 0; x < a.Rect.Dx()*4; x += 4 {
+			for c := 0; c < 3; c++ {
+				d := float64(rowA[x+c]) - float64(rowB[x+c])
+				sum += d * d
+			}
+			n += 3
+		}
+	}
+
+	if n == 0 || sum == 0 {
+		return MaxPSNR
+	}
+	return math.Min(MaxPSNR, 10*math.Log10(255*255/(sum/float64(n))))
+}
+
+// SSIM returns the mean structural similarity of the luma of two equally
+// sized images over 8x8 windows. Images smaller than a window are
+// compared as a single window.
+func SSIM(a, b *image.RGBA) float64 {
+	const (
+		c1 = (0.01 * 255) * (0.01 * 255)
+		c2 = (0.03 * 255) * (0.03 * 255)
+	)
+
+	w, h := a.Rect.Dx(), a.Rect.Dy()
+	la, lb := luma(a), luma(b)
+	win := min(ssimWindow, w, h)
+
+	var total float64
+	var windows int
+	for y0 := 0; y0+win <= h; y0 += ssimStride {
+		for x0 := 0; x0+win <= w; x0 += ssimStride {
+			var sa, sb, saa, sbb, sab float64
+			for y := y0; y < y0+win; y++ {
+				for x := x0; x < x0+win; x++ {
+					va, vb := la[y*w+x], lb[y*w+x]
+					sa += va
+					sb += vb
+					saa += va * va
+					sbb += vb * vb
+					sab += va * vb
+				}
+			}
+
+			n := float64(win * win)
+			ma, mb := sa/n, sb/n
+			varA, varB := saa/n-ma*ma, sbb/n-mb*mb
+			cov := sab/n - ma*mb
+
+			total += ((2*ma*mb + c1) * (2*cov + c2)) / ((ma*ma + mb*mb + c1) * (varA + varB + c2))
+			windows++
+		}
+	}
+
+	if windows == 0 {
+		return 1
+	}
+	return total / float64(windows)
+}
+
+// luma returns the BT.601 luma of every pixel
+func luma(img *image.RGBA) []float64 {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	out := make([]float64, w*h)
+	for y := 0; y < h; y++ {
+		row := img.Pix[y*img.Stride:]
+		for x := 0; x < w; x++ {
+			p := row[x*4:]
+			out[y*w+x] = 0.299*float64(p[0]) + 0.587*float64(p[1]) + 0.114*float64(p[2])
+		}
+	}
+	return out
+}
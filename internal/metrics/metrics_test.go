@@ -0,0 +1,152 @@
+package metrics
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+	"github.com/gruz0/futuage-test-image-generator/pkg/testimages"
+)
+
+func uniform(w, h int, v uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = v
+	}
+	return img
+}
+
+func TestPSNR(t *testing.T) {
+	if got := PSNR(uniform(8, 8, 100), uniform(8, 8, 100)); got != MaxPSNR {
+		t.Errorf("PSNR(identical) = %f, want %f", got, MaxPSNR)
+	}
+
+	want := 10 * math.Log10(255*255/100.0)
+	if got := PSNR(uniform(8, 8, 100), uniform(8, 8, 110)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("PSNR(off by 10) = %f, want %f", got, want)
+	}
+}
+
+func TestSSIM(t *testing.T) {
+	a := testimage(t, 64, 64)
+	if got := SSIM(a, a); math.Abs(got-1) > 1e-9 {
+		t.Errorf("SSIM(identical) = %f, want 1", got)
+	}
+	if got := SSIM(a, uniform(64, 64, 128)); got > 0.5 {
+		t.Errorf("SSIM(image, flat) = %f, want < 0.5", got)
+	}
+	if got := SSIM(uniform(4, 4, 10), uniform(4, 4, 10)); math.Abs(got-1) > 1e-9 {
+		t.Errorf("SSIM(smaller than a window) = %f, want 1", got)
+	}
+}
+
+// testimage decodes a generated test image into RGBA
+func testimage(t *testing.T, w, h int) *image.RGBA {
+	t.Helper()
+	img, _, err := fingerprint.Decode(testimages.Bytes(t, "png", w, h))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return Prepare(img, w, h)
+}
+
+func TestMeasure(t *testing.T) {
+	original := testimage(t, 400, 300)
+
+	// A faithful crop and resize scores high
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, Prepare(original, 150, 150), &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	processed, _, err := fingerprint.Decode(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	good, err := Measure(original, processed)
+	if err != nil {
+		t.Fatalf("Measure() error = %v", err)
+	}
+	if good.PSNR < 25 || good.SSIM < 0.8 || good.Distance > 10 {
+		t.Errorf("Measure(faithful) = %+v, want high quality", good)
+	}
+
+	// A flat gray output scores low
+	bad, err := Measure(original, uniform(150, 150, 128))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bad.PSNR >= good.PSNR || bad.SSIM >= good.SSIM {
+		t.Errorf("Measure(flat) = %+v, want worse than %+v", bad, good)
+	}
+}
+
+func TestRun(t *testing.T) {
+	inputDir, outputDir := t.TempDir(), t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(inputDir, "ratios", "4-3"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(inputDir, "ratios", "4-3", "a.png"), testimages.Bytes(t, "png", 400, 300), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, Prepare(testimage(t, 400, 300), 100, 100), &jpeg.Options{Quality: 90}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(outputDir, "SQUARE"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, "SQUARE", "a.jpg"), buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := manifest.NewManifest("1.0.0", "1.0.0")
+	m.Images = []manifest.ImageRecord{
+		{Filename: "ratios/4-3/a.png", Width: 400, Height: 300, Format: "png"},
+		{Filename: "ratios/4-3/unprocessed.png", Width: 400, Height: 300, Format: "png"},
+	}
+	cfg := &Config{
+		OutputPattern: "{target}/{name}.*",
+		Thresholds:    Thresholds{MinPSNR: 20, MinSSIM: 0.5},
+		Formats:       map[string]Thresholds{"jpeg": {MinPSNR: 99}},
+		Targets:       map[string]Thresholds{"SQUARE": {}, "WIDE": {}},
+	}
+
+	report, err := Run(outputDir, inputDir, m, cfg)
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	if len(report.Pairs) != 1 || report.Pairs[0].Format != "jpeg" {
+		t.Fatalf("Pairs = %+v, want one jpeg pair", report.Pairs)
+	}
+	if report.OK() || len(report.Pairs[0].Violations) != 1 {
+		t.Errorf("Violations = %v, want the jpeg PSNR override to fail", report.Pairs[0].Violations)
+	}
+	if len(report.Aggregates) != 2 || report.Aggregates[0].Group != "format:jpeg" || report.Aggregates[1].Group != "target:SQUARE" {
+		t.Errorf("Aggregates = %+v", report.Aggregates)
+	}
+
+	// Target thresholds override format thresholds
+	cfg.Targets["SQUARE"] = Thresholds{MinPSNR: 20}
+	if report, err = Run(outputDir, inputDir, m, cfg); err != nil || !report.OK() {
+		t.Errorf("Run() with target override = %+v, %v; want OK", report, err)
+	}
+}
+
+func TestThresholds_Merge(t *testing.T) {
+	base := Thresholds{MinPSNR: 30, MinSSIM: 0.9, MaxDistance: 8}
+	got := base.merge(Thresholds{MinSSIM: 0.95})
+	want := Thresholds{MinPSNR: 30, MinSSIM: 0.95, MaxDistance: 8}
+	if got != want {
+		t.Errorf("merge() = %+v, want %+v", got, want)
+	}
+}
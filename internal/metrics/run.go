@@ -0,0 +1,246 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/compare"
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+)
+
+// Thresholds are the limits a processed image must stay within. Zero
+// values disable a limit.
+type Thresholds struct {
+	MinPSNR     float64 `json:"min_psnr,omitempty"`
+	MinSSIM     float64 `json:"min_ssim,omitempty"`
+	MaxDistance int     `json:"max_distance,omitempty"`
+}
+
+// merge returns t with the limits set in override replacing its own
+func (t Thresholds) merge(override Thresholds) Thresholds {
+	if override.MinPSNR != 0 {
+		t.MinPSNR = override.MinPSNR
+	}
+	if override.MinSSIM != 0 {
+		t.MinSSIM = override.MinSSIM
+	}
+	if override.MaxDistance != 0 {
+		t.MaxDistance = override.MaxDistance
+	}
+	return t
+}
+
+// Config selects the outputs to measure and their thresholds. Format
+// thresholds override the defaults; target thresholds override both.
+type Config struct {
+	OutputPattern string                `json:"output_pattern"` // see compare.Rules
+	Thresholds    Thresholds            `json:"thresholds"`
+	Formats       map[string]Thresholds `json:"formats"` // keyed by decoded output format
+	Targets       map[string]Thresholds `json:"targets"` // the targets to measure
+}
+
+// LoadConfig reads a metrics config file
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics config: %w", err)
+	}
+
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse metrics config: %w", err)
+	}
+	if cfg.OutputPattern == "" {
+		cfg.OutputPattern = compare.DefaultOutputPattern
+	}
+	if len(cfg.Targets) == 0 {
+		return nil, fmt.Errorf("invalid metrics config: at least one target is required")
+	}
+	return &cfg, nil
+}
+
+// thresholdsFor returns the effective thresholds of an output
+func (c *Config) thresholdsFor(target, format string) Thresholds {
+	return c.Thresholds.merge(c.Formats[format]).merge(c.Targets[target])
+}
+
+// Pair is the measurement of one processed output
+type Pair struct {
+	Target     string   `json:"target"`
+	Input      string   `json:"input"`
+	Output     string   `json:"output"`
+	Format     string   `json:"format"`
+	Scores     Scores   `json:"scores"`
+	Violations []string `json:"violations,omitempty"`
+}
+
+// Aggregate summarizes the scores of a group of pairs
+type Aggregate struct {
+	Group       string  `json:"group"` // "target:<name>" or "format:<name>"
+	Count       int     `json:"count"`
+	MeanPSNR    float64 `json:"mean_psnr"`
+	MinPSNR     float64 `json:"min_psnr"`
+	MeanSSIM    float64 `json:"mean_ssim"`
+	MinSSIM     float64 `json:"min_ssim"`
+	MaxDistance int     `json:"max_distance"`
+}
+
+// Report is the outcome of a metrics run. Inputs without an output are
+// not measured.
+type Report struct {
+	Pairs      []Pair      `json:"pairs"`
+	Aggregates []Aggregate `json:"aggregates"`
+	Violations int         `json:"violations"`
+}
+
+// OK returns true if no pair violates its thresholds
+func (r *Report) OK() bool {
+	return r.Violations == 0
+}
+
+// Run measures every output in dir of the images in a manifest stored in
+// inputDir, for every target in the config
+func Run(dir, inputDir string, m *manifest.Manifest, cfg *Config) (*Report, error) {
+	targets := make([]string, 0, len(cfg.Targets))
+	for name := range cfg.Targets {
+		targets = append(targets, name)
+	}
+	sort.Strings(targets)
+
+	report := &Report{Pairs: []Pair{}, Aggregates: []Aggregate{}}
+	for _, img := range m.Images {
+		// Originals are decoded once, on first use
+		var original image.Image
+
+		for _, target := range targets {
+			matches, err := compare.Locate(dir, cfg.OutputPattern, target, img)
+			if err != nil {
+				return nil, err
+			}
+			if len(matches) != 1 {
+				continue
+			}
+
+			if original == nil {
+				if original, err = decodeFile(filepath.Join(inputDir, filepath.FromSlash(img.Filename))); err != nil {
+					return nil, err
+				}
+			}
+			processed, format, err := decodeFormat(matches[0])
+			if err != nil {
+				return nil, err
+			}
+
+			scores, err := Measure(original, processed)
+			if err != nil {
+				return nil, fmt.Errorf("failed to measure %s: %w", matches[0], err)
+			}
+
+			rel, err := filepath.Rel(dir, matches[0])
+			if err != nil {
+				return nil, err
+			}
+			pair := Pair{Target: target, Input: img.Filename, Output: filepath.ToSlash(rel), Format: format, Scores: scores}
+			pair.Violations = check(scores, cfg.thresholdsFor(target, format))
+			if len(pair.Violations) > 0 {
+				report.Violations++
+			}
+			report.Pairs = append(report.Pairs, pair)
+		}
+	}
+
+	report.Aggregates = aggregate(report.Pairs)
+	return report, nil
+}
+
+// check lists the thresholds scores violate
+func check(s Scores, t Thresholds) []string {
+	var violations []string
+	if t.MinPSNR > 0 && s.PSNR < t.MinPSNR {
+		violations = append(violations, fmt.Sprintf("PSNR %.2f dB below %g", s.PSNR, t.MinPSNR))
+	}
+	if t.MinSSIM > 0 && s.SSIM < t.MinSSIM {
+		violations = append(violations, fmt.Sprintf("SSIM %.4f below %g", s.SSIM, t.MinSSIM))
+	}
+	if t.MaxDistance > 0 && s.Distance > t.MaxDistance {
+		violations = append(violations, fmt.Sprintf("perceptual distance %d above %d", s.Distance, t.MaxDistance))
+	}
+	return violations
+}
+
+// aggregate summarizes pairs per target and per output format
+func aggregate(pairs []Pair) []Aggregate {
+	groups := make(map[string]*Aggregate)
+	add := func(group string, s Scores) {
+		a, ok := groups[group]
+		if !ok {
+			a = &Aggregate{Group: group, MinPSNR: math.MaxFloat64, MinSSIM: math.MaxFloat64}
+			groups[group] = a
+		}
+		a.Count++
+		a.MeanPSNR += s.PSNR
+		a.MeanSSIM += s.SSIM
+		a.MinPSNR = math.Min(a.MinPSNR, s.PSNR)
+		a.MinSSIM = math.Min(a.MinSSIM, s.SSIM)
+		a.MaxDistance = max(a.MaxDistance, s.Distance)
+	}
+	for _, p := range pairs {
+		add("target:"+p.Target, p.Scores)
+		add("format:"+p.Format, p.Scores)
+	}
+
+	result := make([]Aggregate, 0, len(groups))
+	for _, a := range groups {
+		a.MeanPSNR /= float64(a.Count)
+		a.MeanSSIM /= float64(a.Count)
+		result = append(result, *a)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Group < result[j].Group })
+	return result
+}
+
+// WriteText prints violations and the aggregates
+func (r *Report) WriteText(w io.Writer) error {
+	var b strings.Builder
+	for _, p := range r.Pairs {
+		if len(p.Violations) > 0 {
+			fmt.Fprintf(&b, "  ✗ %s %s -> %s: %s\n", p.Target, p.Input, p.Output, strings.Join(p.Violations, "; "))
+		}
+	}
+
+	fmt.Fprintf(&b, "%-28s %6s %10s %10s %8s %8s %8s\n", "Group", "Count", "Mean PSNR", "Min PSNR", "Mean SSIM", "Min SSIM", "Max dist")
+	for _, a := range r.Aggregates {
+		fmt.Fprintf(&b, "%-28s %6d %10.2f %10.2f %8.4f %8.4f %8d\n", a.Group, a.Count, a.MeanPSNR, a.MinPSNR, a.MeanSSIM, a.MinSSIM, a.MaxDistance)
+	}
+	fmt.Fprintf(&b, "%d pairs measured, %d with violations\n", len(r.Pairs), r.Violations)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// decodeFile decodes an image file
+func decodeFile(path string) (image.Image, error) {
+	img, _, err := decodeFormat(path)
+	return img, err
+}
+
+// decodeFormat decodes an image file and returns its format
+func decodeFormat(path string) (image.Image, string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	img, format, err := fingerprint.Decode(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, format, nil
+}
@@ -0,0 +1,153 @@
+// Package oracle predicts how a pipeline that center-crops and resizes
+// uploads to each platform target transforms a generated image, so test
+// harnesses can assert their output against it. Images with a focal point
+// also get the window a subject-aware crop is expected to keep.
+package oracle
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/config"
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+)
+
+// FileName is the name of the expectations file written next to
+// manifest.json
+const FileName = "expectations.json"
+
+// SchemaVersion is the expectations file schema version
+const SchemaVersion = 1
+
+// Rect is a rectangle in source image pixels
+type Rect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// Prediction is the expected result of fitting an image to one target
+type Prediction struct {
+	Target    string  `json:"target"`
+	Platform  string  `json:"platform"`
+	Ratio     string  `json:"ratio"`
+	Crop      Rect    `json:"crop"`                 // largest centered window with the target's aspect ratio
+	SmartCrop *Rect   `json:"smart_crop,omitempty"` // crop window centered on the focal point, if any
+	Scale     float64 `json:"scale"`                // resize factor applied to the crop, > 1 enlarges
+	Width     int     `json:"width"`                // final width, the target width
+	Height    int     `json:"height"`               // final height, the target height
+	Upscale   bool    `json:"upscale"`
+}
+
+// ImageExpectations holds the predictions for one generated image
+type ImageExpectations struct {
+	Filename string       `json:"filename"`
+	Width    int          `json:"width"`
+	Height   int          `json:"height"`
+	Targets  []Prediction `json:"targets"`
+}
+
+// Expectations is the content of expectations.json
+type Expectations struct {
+	SchemaVersion int                 `json:"schema_version"`
+	GeneratedAt   string              `json:"generated_at"`
+	ConfigVersion string              `json:"config_version"`
+	Images        []ImageExpectations `json:"images"`
+}
+
+// Predict computes the center crop and resize of a width x height image
+// to a target. The crop keeps the full extent of the constraining side;
+// the other side is rounded to the nearest pixel and centered, with odd
+// leftover pixels going to the right or bottom.
+func Predict(width, height int, name string, target config.Target) Prediction {
+	tw, th := target.Dimensions[0], target.Dimensions[1]
+
+	// 1. Largest centered window with the target ratio
+	window := generator.CenterCrop(width, height, tw, th)
+	crop := Rect{X: window.Min.X, Y: window.Min.Y, Width: window.Dx(), Height: window.Dy()}
+
+	// 2. Resize the window to the target dimensions
+	scale := max(float64(tw)/float64(crop.Width), float64(th)/float64(crop.Height))
+
+	return Prediction{
+		Target:   name,
+		Platform: target.Platform,
+		Ratio:    target.Ratio,
+		Crop:     crop,
+		Scale:    scale,
+		Width:    tw,
+		Height:   th,
+		Upscale:  crop.Width < tw || crop.Height < th,
+	}
+}
+
+// PredictSmartCrop computes the window a subject-aware crop to a target
+// keeps: the center crop moved to be centered on the focal pixel as far as
+// the image bounds allow. Scale and final size match the center crop, since
+// the window has the same size.
+func PredictSmartCrop(width, height, focusX, focusY int, target config.Target) Rect {
+	window := generator.SmartCrop(width, height, image.Pt(focusX, focusY), target.Dimensions[0], target.Dimensions[1])
+	return Rect{X: window.Min.X, Y: window.Min.Y, Width: window.Dx(), Height: window.Dy()}
+}
+
+// PredictAll computes the predictions for every target, sorted by target
+// name
+func PredictAll(width, height int, targets map[string]config.Target) []Prediction {
+	names := make([]string, 0, len(targets))
+	for name := range targets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	predictions := make([]Prediction, 0, len(names))
+	for _, name := range names {
+		predictions = append(predictions, Predict(width, height, name, targets[name]))
+	}
+	return predictions
+}
+
+// Build computes the expectations of every image in a manifest
+func Build(m *manifest.Manifest, cfg *config.Config) *Expectations {
+	e := &Expectations{
+		SchemaVersion: SchemaVersion,
+		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
+		ConfigVersion: cfg.Version,
+		Images:        make([]ImageExpectations, 0, len(m.Images)),
+	}
+
+	for _, img := range m.Images {
+		predictions := PredictAll(img.Width, img.Height, cfg.Targets)
+		if fp := img.FocalPoint; fp != nil {
+			for i := range predictions {
+				window := PredictSmartCrop(img.Width, img.Height, fp.PixelX, fp.PixelY, cfg.Targets[predictions[i].Target])
+				predictions[i].SmartCrop = &window
+			}
+		}
+		e.Images = append(e.Images, ImageExpectations{
+			Filename: img.Filename,
+			Width:    img.Width,
+			Height:   img.Height,
+			Targets:  predictions,
+		})
+	}
+	return e
+}
+
+// Write writes the expectations to a JSON file
+func (e *Expectations) Write(outputPath string) error {
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal expectations: %w", err)
+	}
+
+	if err := os.WriteFile(outputPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write expectations file: %w", err)
+	}
+	return nil
+}
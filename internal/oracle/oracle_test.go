@@ -0,0 +1,149 @@
+package oracle
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/config"
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+)
+
+func TestPredict(t *testing.T) {
+	pinterest := config.Target{Platform: "Pinterest", Dimensions: []int{1000, 1500}, Ratio: "2:3"}
+	square := config.Target{Platform: "Instagram", Dimensions: []int{1080, 1080}, Ratio: "1:1"}
+	linkedin := config.Target{Platform: "LinkedIn", Dimensions: []int{1200, 628}, Ratio: "1.91:1"}
+
+	tests := []struct {
+		name          string
+		width, height int
+		target        config.Target
+		wantCrop      Rect
+		wantScale     float64
+		wantUpscale   bool
+	}{
+		{"exact match", 1000, 1500, pinterest, Rect{0, 0, 1000, 1500}, 1, false},
+		{"same ratio larger", 2000, 3000, pinterest, Rect{0, 0, 2000, 3000}, 0.5, false},
+		{"same ratio smaller", 500, 750, pinterest, Rect{0, 0, 500, 750}, 2, true},
+		{"wide to square", 1600, 900, square, Rect{350, 0, 900, 900}, 1.2, true},
+		{"tall to square", 1080, 1920, square, Rect{0, 420, 1080, 1080}, 1, false},
+		{"odd leftover", 101, 100, square, Rect{0, 0, 100, 100}, 10.8, true},
+		{"square to wide", 2000, 2000, linkedin, Rect{0, 476, 2000, 1047}, 0.6, false},
+		{"wide rounding", 1000, 1000, pinterest, Rect{166, 0, 667, 1000}, 1.5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Predict(tt.width, tt.height, "T", tt.target)
+			if got.Crop != tt.wantCrop {
+				t.Errorf("Crop = %+v, want %+v", got.Crop, tt.wantCrop)
+			}
+			if diff := got.Scale - tt.wantScale; diff > 0.001 || diff < -0.001 {
+				t.Errorf("Scale = %f, want %f", got.Scale, tt.wantScale)
+			}
+			if got.Upscale != tt.wantUpscale {
+				t.Errorf("Upscale = %v, want %v", got.Upscale, tt.wantUpscale)
+			}
+			if got.Width != tt.target.Dimensions[0] || got.Height != tt.target.Dimensions[1] {
+				t.Errorf("Final = %dx%d, want target dimensions", got.Width, got.Height)
+			}
+		})
+	}
+}
+
+func TestPredictSmartCrop(t *testing.T) {
+	square := config.Target{Platform: "Instagram", Dimensions: []int{1080, 1080}, Ratio: "1:1"}
+	story := config.Target{Platform: "Instagram", Dimensions: []int{1080, 1920}, Ratio: "9:16"}
+
+	tests := []struct {
+		name           string
+		width, height  int
+		focusX, focusY int
+		target         config.Target
+		want           Rect
+	}{
+		{"follows subject", 1600, 900, 533, 300, square, Rect{83, 0, 900, 900}},
+		{"clamped to edge", 1600, 900, 1500, 600, square, Rect{700, 0, 900, 900}},
+		{"portrait window", 1600, 900, 1067, 600, story, Rect{814, 0, 506, 900}},
+		{"center subject", 1600, 900, 800, 450, square, Rect{350, 0, 900, 900}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := PredictSmartCrop(tt.width, tt.height, tt.focusX, tt.focusY, tt.target)
+			if got != tt.want {
+				t.Errorf("PredictSmartCrop() = %+v, want %+v", got, tt.want)
+			}
+			center := Predict(tt.width, tt.height, "T", tt.target).Crop
+			if got.Width != center.Width || got.Height != center.Height {
+				t.Errorf("smart crop %dx%d differs in size from center crop %dx%d",
+					got.Width, got.Height, center.Width, center.Height)
+			}
+		})
+	}
+}
+
+func TestBuild_SmartCrop(t *testing.T) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := manifest.NewManifest("1.0.0", cfg.Version)
+	m.Images = []manifest.ImageRecord{
+		{Filename: "ratios/16-9/a.jpg", Width: 1600, Height: 900, Format: "jpeg"},
+		{Filename: "focal-points/b.jpg", Width: 1600, Height: 900, Format: "jpeg",
+			FocalPoint: &manifest.FocalPoint{X: 1.0 / 3, Y: 1.0 / 3, PixelX: 533, PixelY: 300, Radius: 90}},
+	}
+
+	e := Build(m, cfg)
+	for _, p := range e.Images[0].Targets {
+		if p.SmartCrop != nil {
+			t.Errorf("%s: image without focal point has a smart crop", p.Target)
+		}
+	}
+	for _, p := range e.Images[1].Targets {
+		if p.SmartCrop == nil {
+			t.Fatalf("%s: focal point image has no smart crop", p.Target)
+		}
+		if p.Target == "IG_FEED_1_1" && *p.SmartCrop != (Rect{83, 0, 900, 900}) {
+			t.Errorf("IG_FEED_1_1 smart crop = %+v", *p.SmartCrop)
+		}
+	}
+}
+
+func TestBuild_Write(t *testing.T) {
+	cfg, err := config.LoadConfig("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := manifest.NewManifest("1.0.0", cfg.Version)
+	m.Images = []manifest.ImageRecord{{Filename: "ratios/1-1/a.jpg", Width: 500, Height: 500, Format: "jpeg"}}
+
+	e := Build(m, cfg)
+	if len(e.Images) != 1 || len(e.Images[0].Targets) != len(cfg.Targets) {
+		t.Fatalf("Build() = %+v, want one image with %d targets", e, len(cfg.Targets))
+	}
+	for i := 1; i < len(e.Images[0].Targets); i++ {
+		if e.Images[0].Targets[i-1].Target >= e.Images[0].Targets[i].Target {
+			t.Errorf("Targets not sorted by name: %s before %s", e.Images[0].Targets[i-1].Target, e.Images[0].Targets[i].Target)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), FileName)
+	if err := e.Write(path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded Expectations
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		t.Fatalf("expectations.json is not valid JSON: %v", err)
+	}
+	if loaded.SchemaVersion != SchemaVersion || loaded.Images[0].Filename != "ratios/1-1/a.jpg" {
+		t.Errorf("loaded = %+v", loaded)
+	}
+}
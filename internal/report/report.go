@@ -0,0 +1,222 @@
+// Package report renders an offline HTML contact sheet of a generation
+// run next to its manifest.
+package report
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+)
+
+// FileName is the name of the report written to the output directory
+const FileName = "index.html"
+
+// detailsDir holds one detail page per image
+const detailsDir = "details"
+
+//go:embed index.html.tmpl
+var indexTemplate string
+
+//go:embed detail.html.tmpl
+var detailTemplate string
+
+var templates = template.Must(template.New("report").Funcs(template.FuncMap{
+	"humanSize": humanSize,
+}).Parse(`{{define "index"}}` + indexTemplate + `{{end}}{{define "detail"}}` + detailTemplate + `{{end}}`))
+
+// Failure describes an image that could not be generated
+type Failure struct {
+	Filename string
+	Format   string
+	Width    int
+	Height   int
+	Error    string
+}
+
+// Entry is an image shown in the report
+type Entry struct {
+	manifest.ImageRecord
+
+	Thumbnail   template.URL // data: URI, empty if the file could not be decoded
+	DetailPage  string
+	SizePercent float64
+	Missing     bool
+	Error       string
+}
+
+// Failed returns true if the image was not generated or is missing
+func (e Entry) Failed() bool {
+	return e.Missing || e.Error != ""
+}
+
+// BrowserNative returns true if browsers can display the original file;
+// other formats are previewed by their thumbnail
+func (e Entry) BrowserNative() bool {
+	switch e.Format {
+	case "jpeg", "png", "apng", "gif", "webp", "bmp":
+		return true
+	default:
+		return false
+	}
+}
+
+// Page is the data the index template is rendered with
+type Page struct {
+	Manifest   *manifest.Manifest
+	Entries    []Entry
+	Failures   []Entry
+	TotalBytes int64
+}
+
+// Write renders index.html and the per-image detail pages into dir, the
+// output directory the manifest describes. Thumbnails are embedded as
+// data URIs so the report needs no network or image decoders.
+func Write(dir string, m *manifest.Manifest, failures []Failure) error {
+	page := buildPage(dir, m, failures)
+
+	// 1. Detail pages
+	if err := os.MkdirAll(filepath.Join(dir, detailsDir), 0755); err != nil {
+		return fmt.Errorf("failed to create details directory: %w", err)
+	}
+	for _, entry := range page.Entries {
+		if err := renderFile(filepath.Join(dir, filepath.FromSlash(entry.DetailPage)), "detail", entry); err != nil {
+			return err
+		}
+	}
+
+	// 2. Index
+	return renderFile(filepath.Join(dir, FileName), "index", page)
+}
+
+// buildPage collects the entries of the report, sorted by category,
+// subcategory and filename, with failures first
+func buildPage(dir string, m *manifest.Manifest, failures []Failure) *Page {
+	page := &Page{Manifest: m}
+
+	var maxSize int64
+	for _, img := range m.Images {
+		page.TotalBytes += img.FileSizeBytes
+		if img.FileSizeBytes > maxSize {
+			maxSize = img.FileSizeBytes
+		}
+	}
+
+	entries := make([]Entry, len(m.Images))
+	thumbnails(dir, m.Images, entries)
+
+	for i, img := range m.Images {
+		entries[i].ImageRecord = img
+		entries[i].DetailPage = detailPath(img.Filename)
+		if maxSize > 0 {
+			entries[i].SizePercent = float64(img.FileSizeBytes) / float64(maxSize) * 100
+		}
+	}
+
+	for _, f := range failures {
+		entries = append(entries, Entry{
+			ImageRecord: manifest.ImageRecord{
+				Filename: f.Filename,
+				Format:   strings.ToLower(f.Format),
+				Width:    f.Width,
+				Height:   f.Height,
+			},
+			DetailPage: detailPath(f.Filename),
+			Error:      f.Error,
+		})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := entries[i], entries[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		if a.Subcategory != b.Subcategory {
+			return a.Subcategory < b.Subcategory
+		}
+		return a.Filename < b.Filename
+	})
+
+	page.Entries = entries
+	for _, entry := range entries {
+		if entry.Failed() {
+			page.Failures = append(page.Failures, entry)
+		}
+	}
+	return page
+}
+
+// thumbnails fills in the thumbnail of every image in parallel, marking
+// files that no longer exist as missing
+func thumbnails(dir string, images []manifest.ImageRecord, entries []Entry) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				path := filepath.Join(dir, filepath.FromSlash(images[i].Filename))
+				data, err := os.ReadFile(path)
+				if err != nil {
+					entries[i].Missing = os.IsNotExist(err)
+					continue
+				}
+				entries[i].Thumbnail = Thumbnail(data)
+			}
+		}()
+	}
+
+	for i := range images {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// detailPath returns the detail page of an image relative to the output
+// directory
+func detailPath(filename string) string {
+	name := strings.NewReplacer("/", "_", "\\", "_").Replace(filename)
+	return detailsDir + "/" + name + ".html"
+}
+
+// renderFile executes a template into a file
+func renderFile(path, name string, data interface{}) (err error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer func() {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = fmt.Errorf("failed to close %s: %w", path, closeErr)
+		}
+	}()
+
+	if err := templates.ExecuteTemplate(file, name, data); err != nil {
+		return fmt.Errorf("failed to render %s: %w", path, err)
+	}
+	return nil
+}
+
+// humanSize formats a byte count, e.g. "1.5 MB"
+func humanSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGT"[exp])
+}
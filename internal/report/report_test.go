@@ -0,0 +1,116 @@
+package report
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/manifest"
+	"github.com/gruz0/futuage-test-image-generator/pkg/testimages"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "ratios", "1-1"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{"a.png", "b.tiff"} {
+		format := strings.TrimPrefix(filepath.Ext(name), ".")
+		data := testimages.Bytes(t, format, 300, 200)
+		if err := os.WriteFile(filepath.Join(dir, "ratios", "1-1", name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	m := manifest.NewManifest("1.0.0", "1.0.0")
+	m.Images = []manifest.ImageRecord{
+		{Filename: "ratios/1-1/a.png", Category: "ratios", Subcategory: "1-1", Width: 300, Height: 200, Format: "png", FileSizeBytes: 2000},
+		{Filename: "ratios/1-1/b.tiff", Category: "ratios", Subcategory: "1-1", Width: 300, Height: 200, Format: "tiff", FileSizeBytes: 1000},
+		{Filename: "ratios/1-1/gone.jpg", Category: "ratios", Subcategory: "1-1", Width: 10, Height: 10, Format: "jpeg", FileSizeBytes: 10},
+	}
+	failures := []Failure{{Filename: "targets/x.webp", Format: "WEBP", Width: 5, Height: 5, Error: "encoder exploded"}}
+
+	if err := Write(dir, m, failures); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+
+	index, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	html := string(index)
+
+	for _, want := range []string{
+		"details/ratios_1-1_a.png.html",
+		"data:image/jpeg;base64,",
+		"2 failed",
+		"encoder exploded",
+		"file missing",
+		`style="width: 50.0%"`,
+	} {
+		if !strings.Contains(html, want) {
+			t.Errorf("index.html does not contain %q", want)
+		}
+	}
+	for _, banned := range []string{"http://", "https://"} {
+		if strings.Contains(html, banned) {
+			t.Errorf("index.html references a network asset (%s)", banned)
+		}
+	}
+
+	// TIFF is previewed by its thumbnail, PNG by the original file
+	detail, err := os.ReadFile(filepath.Join(dir, "details", "ratios_1-1_b.tiff.html"))
+	if err != nil {
+		t.Fatalf("detail page not written: %v", err)
+	}
+	if !strings.Contains(string(detail), "data:image/jpeg;base64,") {
+		t.Error("TIFF detail page has no thumbnail preview")
+	}
+	detail, err = os.ReadFile(filepath.Join(dir, "details", "ratios_1-1_a.png.html"))
+	if err != nil {
+		t.Fatalf("detail page not written: %v", err)
+	}
+	if !strings.Contains(string(detail), `src="../ratios/1-1/a.png"`) {
+		t.Error("PNG detail page does not show the original file")
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"opaque", testimages.Bytes(t, "jpeg", 400, 100), "data:image/jpeg;base64,"},
+		{"alpha", testimages.Bytes(t, "png", 100, 100, testimages.Alpha()), "data:image/png;base64,"},
+		{"undecodable", []byte("nope"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(Thumbnail(tt.data))
+			if !strings.HasPrefix(got, tt.want) || (tt.want == "") != (got == "") {
+				t.Errorf("Thumbnail() = %.40q, want prefix %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHumanSize(t *testing.T) {
+	tests := []struct {
+		bytes int64
+		want  string
+	}{
+		{512, "512 B"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+	}
+
+	for _, tt := range tests {
+		if got := humanSize(tt.bytes); got != tt.want {
+			t.Errorf("humanSize(%d) = %s, want %s", tt.bytes, got, tt.want)
+		}
+	}
+}
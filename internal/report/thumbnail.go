@@ -0,0 +1,56 @@
+package report
+
+import (
+	"bytes"
+	"encoding/base64"
+	"html/template"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/fingerprint"
+	"golang.org/x/image/draw"
+)
+
+// ThumbnailSize is the maximum width and height of a thumbnail
+const ThumbnailSize = 160
+
+// Thumbnail decodes an image file and returns a scaled-down copy as a
+// data URI, or an empty URI if the file cannot be decoded. Opaque images
+// are embedded as JPEG, images with transparency as PNG.
+func Thumbnail(data []byte) template.URL {
+	img, _, err := fingerprint.Decode(data)
+	if err != nil {
+		return ""
+	}
+
+	// 1. Fit into the thumbnail box, never upscaling
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w == 0 || h == 0 {
+		return ""
+	}
+	scale := float64(ThumbnailSize) / float64(max(w, h))
+	if scale > 1 {
+		scale = 1
+	}
+	tw, th := max(1, int(float64(w)*scale)), max(1, int(float64(h)*scale))
+
+	thumb := image.NewRGBA(image.Rect(0, 0, tw, th))
+	draw.ApproxBiLinear.Scale(thumb, thumb.Bounds(), img, bounds, draw.Src, nil)
+
+	// 2. Encode and wrap in a data URI
+	var buf bytes.Buffer
+	mime := "image/jpeg"
+	if thumb.Opaque() {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 75})
+	} else {
+		mime = "image/png"
+		err = png.Encode(&buf, thumb)
+	}
+	if err != nil {
+		return ""
+	}
+
+	return template.URL("data:" + mime + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes()))
+}
@@ -0,0 +1,406 @@
+// Package testimages generates image fixtures for Go tests. Fixtures carry
+// the same grid, border, text overlay and corner markers as the images the
+// CLI generates, so pipeline tests can check dimensions, crops and formats.
+//
+//	path := testimages.JPEG(t, 1080, 1350, testimages.Quality(82))
+//	data := testimages.Bytes(t, "webp", 500, 500, testimages.Lossless(), testimages.Alpha())
+//
+// Encoded fixtures are cached in memory for the whole test binary, up to
+// 64 MiB, and files are written once per test into t.TempDir().
+//
+// The exported API of this package follows semantic versioning: it only
+// changes incompatibly in a new major version of the module.
+package testimages
+
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gruz0/futuage-test-image-generator/internal/config"
+	"github.com/gruz0/futuage-test-image-generator/internal/generator"
+)
+
+// Option configures a fixture
+type Option func(*settings)
+
+// settings holds the fixture options as a config variant
+type settings struct {
+	variant    config.FormatVariant
+	qualitySet bool
+}
+
+// Quality sets the encoder quality (1-100). Defaults to the first quality
+// of the format in the default config.
+func Quality(quality int) Option {
+	return func(s *settings) {
+		s.variant.Quality = quality
+		s.qualitySet = true
+	}
+}
+
+// Alpha renders the fixture with transparent and translucent grid cells
+func Alpha() Option {
+	return func(s *settings) { s.variant.Alpha = true }
+}
+
+// Lossless uses lossless compression (WebP)
+func Lossless() Option {
+	return func(s *settings) { s.variant.Lossless = true }
+}
+
+// NearLossless applies near-lossless preprocessing, 1-99 with lower
+// values being stronger (WebP)
+func NearLossless(level int) Option {
+	return func(s *settings) { s.variant.NearLossless = level }
+}
+
+// Interlaced writes interlaced rows (GIF)
+func Interlaced() Option {
+	return func(s *settings) { s.variant.Interlaced = true }
+}
+
+// Metadata embeds metadata of the given kinds: exif, xmp, icc (WebP)
+func Metadata(kinds ...string) Option {
+	kinds = slices.Clone(kinds)
+	return func(s *settings) { s.variant.Metadata = kinds }
+}
+
+// Animated renders an animation with the given frame count and delay in
+// milliseconds (WebP, GIF, APNG)
+func Animated(frames, delayMs int) Option {
+	return func(s *settings) {
+		s.variant.Frames = frames
+		s.variant.FrameDelayMs = delayMs
+	}
+}
+
+// LoopCount sets the number of animation plays, 0 loops forever
+func LoopCount(loops int) Option {
+	return func(s *settings) { s.variant.LoopCount = loops }
+}
+
+// DisposeOp sets the frame disposal: none, background or previous (GIF, APNG)
+func DisposeOp(op string) Option {
+	return func(s *settings) { s.variant.DisposeOp = op }
+}
+
+// BlendOp sets the frame blending: source or over (APNG)
+func BlendOp(op string) Option {
+	return func(s *settings) { s.variant.BlendOp = op }
+}
+
+// SeparateDefaultImage adds a still default image that differs from
+// frame 1 (APNG)
+func SeparateDefaultImage() Option {
+	return func(s *settings) { s.variant.SeparateDefaultImage = true }
+}
+
+// Compression sets the compression scheme: none or deflate (TIFF)
+func Compression(compression string) Option {
+	return func(s *settings) { s.variant.Compression = compression }
+}
+
+// Pages renders a multi-page document (TIFF)
+func Pages(pages int) Option {
+	return func(s *settings) { s.variant.Pages = pages }
+}
+
+// JPEG returns the path of a JPEG fixture
+func JPEG(t testing.TB, width, height int, opts ...Option) string {
+	t.Helper()
+	return File(t, "jpeg", width, height, opts...)
+}
+
+// PNG returns the path of a PNG fixture
+func PNG(t testing.TB, width, height int, opts ...Option) string {
+	t.Helper()
+	return File(t, "png", width, height, opts...)
+}
+
+// WebP returns the path of a WebP fixture
+func WebP(t testing.TB, width, height int, opts ...Option) string {
+	t.Helper()
+	return File(t, "webp", width, height, opts...)
+}
+
+// GIF returns the path of a GIF fixture
+func GIF(t testing.TB, width, height int, opts ...Option) string {
+	t.Helper()
+	return File(t, "gif", width, height, opts...)
+}
+
+// APNG returns the path of an animated PNG fixture
+func APNG(t testing.TB, width, height int, opts ...Option) string {
+	t.Helper()
+	return File(t, "apng", width, height, opts...)
+}
+
+// BMP returns the path of a BMP fixture
+func BMP(t testing.TB, width, height int, opts ...Option) string {
+	t.Helper()
+	return File(t, "bmp", width, height, opts...)
+}
+
+// TIFF returns the path of a TIFF fixture
+func TIFF(t testing.TB, width, height int, opts ...Option) string {
+	t.Helper()
+	return File(t, "tiff", width, height, opts...)
+}
+
+// File returns the path of a fixture in any registered format, written
+// into the test's temporary directory. Repeated calls with the same
+// arguments in one test return the same path.
+func File(t testing.TB, format string, width, height int, opts ...Option) string {
+	t.Helper()
+
+	f, err := newFixture(format, width, height, opts)
+	if err != nil {
+		t.Fatalf("testimages: %v", err)
+	}
+
+	files := testFiles(t)
+	files.mu.Lock()
+	defer files.mu.Unlock()
+
+	if path, ok := files.paths[f.key]; ok {
+		return path
+	}
+
+	data, err := f.encode()
+	if err != nil {
+		t.Fatalf("testimages: %v", err)
+	}
+
+	path := filepath.Join(files.dir, f.filename())
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("testimages: failed to write fixture: %v", err)
+	}
+	files.paths[f.key] = path
+
+	return path
+}
+
+// Bytes returns the encoded bytes of a fixture in any registered format
+func Bytes(t testing.TB, format string, width, height int, opts ...Option) []byte {
+	t.Helper()
+
+	f, err := newFixture(format, width, height, opts)
+	if err != nil {
+		t.Fatalf("testimages: %v", err)
+	}
+
+	data, err := f.encode()
+	if err != nil {
+		t.Fatalf("testimages: %v", err)
+	}
+
+	return data
+}
+
+// Encode writes a fixture in any registered format to w, for use outside
+// of tests
+func Encode(w io.Writer, format string, width, height int, opts ...Option) error {
+	f, err := newFixture(format, width, height, opts)
+	if err != nil {
+		return fmt.Errorf("testimages: %w", err)
+	}
+
+	data, err := f.encode()
+	if err != nil {
+		return fmt.Errorf("testimages: %w", err)
+	}
+
+	_, err = w.Write(data)
+	return err
+}
+
+// Formats returns the names of the formats fixtures can be generated in
+func Formats() []string {
+	return generator.EncoderNames()
+}
+
+// fixture is a validated spec and the cache key derived from it
+type fixture struct {
+	spec generator.ImageSpec
+	key  string
+}
+
+var (
+	defaultConfig    *config.Config
+	defaultConfigErr error
+	loadConfigOnce   sync.Once
+
+	cache = newFixtureCache(maxCacheBytes)
+)
+
+// maxCacheBytes bounds the memory held by cached fixtures
+const maxCacheBytes = 64 << 20
+
+// newFixture builds the spec of a fixture through the config spec builder,
+// so it is validated and labeled like the images the CLI generates
+func newFixture(format string, width, height int, opts []Option) (*fixture, error) {
+	loadConfigOnce.Do(func() {
+		defaultConfig, defaultConfigErr = config.LoadConfig("")
+	})
+	if defaultConfigErr != nil {
+		return nil, defaultConfigErr
+	}
+
+	s := settings{variant: config.FormatVariant{Name: "fixture", Dimensions: []int{width, height}}}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	variant := s.variant
+
+	// A zero quality would silently fall back to the format default
+	if s.qualitySet && variant.Quality == 0 {
+		return nil, fmt.Errorf("quality must be between 1 and 100")
+	}
+
+	builder := config.NewSpecBuilder(defaultConfig, config.NewFilters(nil, nil, nil), "")
+	spec, err := builder.BuildVariantSpec(strings.ToLower(format), variant)
+	if err != nil {
+		return nil, err
+	}
+	spec.Variant = "" // Keep the overlay free of the internal variant name
+
+	key, err := spec.Fingerprint()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fixture{spec: spec, key: key}, nil
+}
+
+// encode renders and encodes the fixture, or returns a copy of the cached
+// bytes
+func (f *fixture) encode() ([]byte, error) {
+	if data, ok := cache.get(f.key); ok {
+		return data, nil
+	}
+
+	img, err := generator.Render(f.spec)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := f.spec.Options
+	opts.Quality = f.spec.Quality
+
+	var buf bytes.Buffer
+	if err := generator.EncodeTo(&buf, img, f.spec.Format, opts); err != nil {
+		return nil, err
+	}
+
+	cache.put(f.key, buf.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// fixtureCache holds encoded fixtures by key, evicting the oldest entries
+// once their total size exceeds the limit
+type fixtureCache struct {
+	mu      sync.Mutex
+	limit   int
+	size    int
+	entries map[string][]byte
+	order   []string
+}
+
+// newFixtureCache returns an empty cache holding up to limit bytes
+func newFixtureCache(limit int) *fixtureCache {
+	return &fixtureCache{limit: limit, entries: make(map[string][]byte)}
+}
+
+// get returns a copy of the cached bytes for key
+func (c *fixtureCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	return slices.Clone(data), true
+}
+
+// put stores a copy of data under key. Data larger than the limit is not
+// cached.
+func (c *fixtureCache) put(key string, data []byte) {
+	if len(data) > c.limit {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	// 1. Evict the oldest entries until the data fits
+	for c.size+len(data) > c.limit {
+		oldest := c.order[0]
+		c.order = c.order[1:]
+		c.size -= len(c.entries[oldest])
+		delete(c.entries, oldest)
+	}
+
+	// 2. Store a copy, so callers may modify their bytes
+	c.entries[key] = slices.Clone(data)
+	c.order = append(c.order, key)
+	c.size += len(data)
+}
+
+// filename returns a file name unique to the fixture settings
+func (f *fixture) filename() string {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(f.key))
+
+	ext := filepath.Ext(f.spec.Filename)
+	return fmt.Sprintf("%s_%dx%d_q%d_%08x%s",
+		strings.ToLower(f.spec.Format), f.spec.Width, f.spec.Height, f.spec.Quality, h.Sum32(), ext)
+}
+
+// fixtureFiles tracks the fixture files written for a single test
+type fixtureFiles struct {
+	mu    sync.Mutex
+	dir   string
+	paths map[string]string
+}
+
+var (
+	testFilesMu sync.Mutex
+	filesByTest = make(map[testing.TB]*fixtureFiles)
+)
+
+// testFiles returns the fixture files of a test, creating its temporary
+// directory on first use
+func testFiles(t testing.TB) *fixtureFiles {
+	t.Helper()
+
+	testFilesMu.Lock()
+	defer testFilesMu.Unlock()
+
+	if files, ok := filesByTest[t]; ok {
+		return files
+	}
+
+	files := &fixtureFiles{dir: t.TempDir(), paths: make(map[string]string)}
+	filesByTest[t] = files
+	t.Cleanup(func() {
+		testFilesMu.Lock()
+		delete(filesByTest, t)
+		testFilesMu.Unlock()
+	})
+
+	return files
+}
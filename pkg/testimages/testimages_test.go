@@ -0,0 +1,163 @@
+package testimages
+
+import (
+	"bytes"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "golang.org/x/image/webp"
+)
+
+func TestJPEG(t *testing.T) {
+	path := JPEG(t, 1080, 1350, Quality(82))
+
+	if !strings.HasSuffix(path, ".jpg") {
+		t.Errorf("JPEG() path = %q, want .jpg extension", path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	img, err := jpeg.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode fixture: %v", err)
+	}
+	if b := img.Bounds(); b.Dx() != 1080 || b.Dy() != 1350 {
+		t.Errorf("Fixture size = %dx%d, want 1080x1350", b.Dx(), b.Dy())
+	}
+
+	// Same arguments in the same test return the same file
+	if again := JPEG(t, 1080, 1350, Quality(82)); again != path {
+		t.Errorf("JPEG() second call = %q, want cached %q", again, path)
+	}
+	if other := JPEG(t, 1080, 1350, Quality(60)); other == path || filepath.Dir(other) != filepath.Dir(path) {
+		t.Errorf("JPEG() with other quality = %q, want a new file next to %q", other, path)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		opts       []Option
+		wantFormat string
+	}{
+		{"png", "png", nil, "png"},
+		{"webp lossless alpha", "webp", []Option{Lossless(), Alpha()}, "webp"},
+		{"animated gif", "gif", []Option{Animated(3, 100)}, "gif"},
+		{"tiff alias", "TIF", []Option{Compression("deflate")}, "tiff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := Bytes(t, tt.format, 120, 80, tt.opts...)
+
+			if tt.wantFormat == "gif" {
+				anim, err := gif.DecodeAll(bytes.NewReader(data))
+				if err != nil {
+					t.Fatalf("Failed to decode GIF: %v", err)
+				}
+				if len(anim.Image) != 3 {
+					t.Errorf("GIF frames = %d, want 3", len(anim.Image))
+				}
+				return
+			}
+
+			if tt.wantFormat == "tiff" {
+				if !bytes.HasPrefix(data, []byte("II*\x00")) {
+					t.Error("Fixture is not a little-endian TIFF")
+				}
+				return
+			}
+
+			cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Failed to decode fixture: %v", err)
+			}
+			if format != tt.wantFormat || cfg.Width != 120 || cfg.Height != 80 {
+				t.Errorf("Fixture = %s %dx%d, want %s 120x80", format, cfg.Width, cfg.Height, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestEncode_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		width  int
+		opts   []Option
+	}{
+		{"unknown format", "heic", 100, nil},
+		{"unsupported option", "jpeg", 100, []Option{Lossless()}},
+		{"invalid quality", "jpeg", 100, []Option{Quality(101)}},
+		{"zero quality", "jpeg", 100, []Option{Quality(0)}},
+		{"invalid dimensions", "png", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Encode(&buf, tt.format, tt.width, 100, tt.opts...); err == nil {
+				t.Error("Encode() error = nil, want error")
+			}
+		})
+	}
+}
+
+func TestMetadata_CopiesKinds(t *testing.T) {
+	kinds := []string{"exif"}
+	opt := Metadata(kinds...)
+	kinds[0] = "unknown"
+
+	var buf bytes.Buffer
+	if err := Encode(&buf, "webp", 100, 100, opt); err != nil {
+		t.Errorf("Encode() error = %v, want the kinds passed to Metadata", err)
+	}
+}
+
+func TestFixtureCache(t *testing.T) {
+	c := newFixtureCache(10)
+
+	c.put("a", []byte("aaaa"))
+	c.put("b", []byte("bbbb"))
+	c.put("huge", []byte("hugehugehuge"))
+
+	data, ok := c.get("a")
+	if !ok || string(data) != "aaaa" {
+		t.Fatalf("get(a) = %q, %v, want aaaa", data, ok)
+	}
+	data[0] = 'x'
+	if again, _ := c.get("a"); string(again) != "aaaa" {
+		t.Errorf("get(a) after modifying a copy = %q, want aaaa", again)
+	}
+	if _, ok := c.get("huge"); ok {
+		t.Error("get(huge) found an entry larger than the limit")
+	}
+
+	// Adding c evicts the oldest entry
+	c.put("c", []byte("cccc"))
+	if _, ok := c.get("a"); ok {
+		t.Error("get(a) found the evicted entry")
+	}
+	if _, ok := c.get("c"); !ok || c.size != 8 {
+		t.Errorf("get(c) = %v with size %d, want cached with size 8", ok, c.size)
+	}
+}
+
+func TestFormats(t *testing.T) {
+	formats := strings.Join(Formats(), ",")
+	for _, want := range []string{"jpeg", "png", "webp", "gif", "apng", "bmp", "tiff"} {
+		if !strings.Contains(formats, want) {
+			t.Errorf("Formats() = %s, missing %s", formats, want)
+		}
+	}
+}